@@ -8,7 +8,10 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"slices"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib" // load the PostgreSQL driver for pgx
 
@@ -29,11 +32,14 @@ var (
 	apiVersion = "v1"
 	apiPrefix  = fmt.Sprintf("api/%s", apiVersion)
 
-	LogConfig     = config.NewLogConfig()
+	LogConfig     = config.NewLogConfig(appName)
 	HTTPSrvConfig = config.NewHTTPServerConfig()
 	DBConfig      = config.NewDatabaseConfig()
 	OTConfig      = config.NewOpenTelemetryConfig(appName, version.Version)
 
+	// startup logs the startup phases with their durations
+	startup = o11y.NewLifecycle("startup")
+
 	logHandler        slog.Handler
 	logHandlerOptions *slog.HandlerOptions
 	logger            *slog.Logger
@@ -41,34 +47,74 @@ var (
 	showVersion     bool
 	showLongVersion bool
 	showHelp        bool
+	showConfigDump  bool
 	debug           bool
 )
 
 func init() {
+	// the config load ends once the configuration is validated
+	configLoadDone := startup.Phase("config load")
+
 	// Version flag
 	flag.BoolVar(&showVersion, "version", false, "Show the version information")
 	flag.BoolVar(&showLongVersion, "version.long", false, "Show the long version information")
 	flag.BoolVar(&debug, "debug", false, "Enable debug mode. This is a shorthand for -log.level=debug")
 	flag.BoolVar(&showHelp, "help", false, "Show this help message")
+	flag.BoolVar(&showConfigDump, "config-dump", false, "Show the configuration values and where they came from, with the secrets redacted")
 
 	// Log configuration values
 	flag.StringVar(&LogConfig.Level.Value, LogConfig.Level.FlagName, config.DefaultLogLevel, LogConfig.Level.FlagDescription)
 	flag.StringVar(&LogConfig.Format.Value, LogConfig.Format.FlagName, config.DefaultLogFormat, LogConfig.Format.FlagDescription)
 	flag.Var(&LogConfig.Output.Value, LogConfig.Output.FlagName, LogConfig.Output.FlagDescription)
+	flag.Float64Var(&LogConfig.SampleRate.Value, LogConfig.SampleRate.FlagName, config.DefaultLogSampleRate, LogConfig.SampleRate.FlagDescription)
+	flag.StringVar(&LogConfig.ServiceName.Value, LogConfig.ServiceName.FlagName, appName, LogConfig.ServiceName.FlagDescription)
+	flag.StringVar(&LogConfig.Environment.Value, LogConfig.Environment.FlagName, config.DefaultLogEnvironment, LogConfig.Environment.FlagDescription)
 
 	// HTTP Server configuration values
 	flag.StringVar(&HTTPSrvConfig.Address.Value, HTTPSrvConfig.Address.FlagName, config.DefaultHTTPServerAddress, HTTPSrvConfig.Address.FlagDescription)
 	flag.IntVar(&HTTPSrvConfig.Port.Value, HTTPSrvConfig.Port.FlagName, config.DefaultHTTPServerPort, HTTPSrvConfig.Port.FlagDescription)
 	flag.DurationVar(&HTTPSrvConfig.ShutdownTimeout.Value, HTTPSrvConfig.ShutdownTimeout.FlagName, config.DefaultHTTPServerShutdownTimeout, HTTPSrvConfig.ShutdownTimeout.FlagDescription)
+	flag.DurationVar(&HTTPSrvConfig.StartupTimeout.Value, HTTPSrvConfig.StartupTimeout.FlagName, config.DefaultHTTPServerStartupTimeout, HTTPSrvConfig.StartupTimeout.FlagDescription)
+	flag.DurationVar(&HTTPSrvConfig.WriteTimeout.Value, HTTPSrvConfig.WriteTimeout.FlagName, config.DefaultHTTPServerWriteTimeout, HTTPSrvConfig.WriteTimeout.FlagDescription)
+	flag.DurationVar(&HTTPSrvConfig.RequestTimeout.Value, HTTPSrvConfig.RequestTimeout.FlagName, config.DefaultHTTPServerRequestTimeout, HTTPSrvConfig.RequestTimeout.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.RequestTimeoutSkip.Value, HTTPSrvConfig.RequestTimeoutSkip.FlagName, config.DefaultHTTPServerRequestTimeoutSkipRoutes, HTTPSrvConfig.RequestTimeoutSkip.FlagDescription)
+	flag.DurationVar(&HTTPSrvConfig.SlowRequestThreshold.Value, HTTPSrvConfig.SlowRequestThreshold.FlagName, config.DefaultHTTPServerSlowRequestThreshold, HTTPSrvConfig.SlowRequestThreshold.FlagDescription)
+	flag.IntVar(&HTTPSrvConfig.MaxHeaderBytes.Value, HTTPSrvConfig.MaxHeaderBytes.FlagName, config.DefaultHTTPServerMaxHeaderBytes, HTTPSrvConfig.MaxHeaderBytes.FlagDescription)
 	flag.Var(&HTTPSrvConfig.PrivateKeyFile.Value, HTTPSrvConfig.PrivateKeyFile.FlagName, HTTPSrvConfig.PrivateKeyFile.FlagDescription)
 	flag.Var(&HTTPSrvConfig.CertificateFile.Value, HTTPSrvConfig.CertificateFile.FlagName, HTTPSrvConfig.CertificateFile.FlagDescription)
 	flag.BoolVar(&HTTPSrvConfig.TLSEnabled.Value, HTTPSrvConfig.TLSEnabled.FlagName, config.DefaultHTTPServerTLSEnabled, HTTPSrvConfig.TLSEnabled.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.TLSProfile.Value, HTTPSrvConfig.TLSProfile.FlagName, config.DefaultHTTPServerTLSProfile, HTTPSrvConfig.TLSProfile.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.TLSMinVersion.Value, HTTPSrvConfig.TLSMinVersion.FlagName, config.DefaultHTTPServerTLSMinVersion, HTTPSrvConfig.TLSMinVersion.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.TLSMaxVersion.Value, HTTPSrvConfig.TLSMaxVersion.FlagName, config.DefaultHTTPServerTLSMaxVersion, HTTPSrvConfig.TLSMaxVersion.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.TLSOCSPStapling.Value, HTTPSrvConfig.TLSOCSPStapling.FlagName, config.DefaultHTTPServerTLSOCSPStaplingEnabled, HTTPSrvConfig.TLSOCSPStapling.FlagDescription)
 	flag.BoolVar(&HTTPSrvConfig.PprofEnabled.Value, HTTPSrvConfig.PprofEnabled.FlagName, config.DefaultHTTPServerPprofEnabled, HTTPSrvConfig.PprofEnabled.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.PrettyJSONEnabled.Value, HTTPSrvConfig.PrettyJSONEnabled.FlagName, config.DefaultHTTPServerPrettyJSONEnabled, HTTPSrvConfig.PrettyJSONEnabled.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.RecoverEnabled.Value, HTTPSrvConfig.RecoverEnabled.FlagName, config.DefaultHTTPServerRecoverEnabled, HTTPSrvConfig.RecoverEnabled.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.ReadOnly.Value, HTTPSrvConfig.ReadOnly.FlagName, config.DefaultHTTPServerReadOnly, HTTPSrvConfig.ReadOnly.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.JSONKeyCasing.Value, HTTPSrvConfig.JSONKeyCasing.FlagName, config.DefaultHTTPServerJSONKeyCasing, HTTPSrvConfig.JSONKeyCasing.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.JSONKeyNegotiation.Value, HTTPSrvConfig.JSONKeyNegotiation.FlagName, config.DefaultHTTPServerJSONKeyCasingNegotiation, HTTPSrvConfig.JSONKeyNegotiation.FlagDescription)
+	flag.IntVar(&HTTPSrvConfig.JSONKeyMaxBodyBytes.Value, HTTPSrvConfig.JSONKeyMaxBodyBytes.FlagName, config.DefaultHTTPServerJSONKeyCasingMaxBodyBytes, HTTPSrvConfig.JSONKeyMaxBodyBytes.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.APIVersionHeader.Value, HTTPSrvConfig.APIVersionHeader.FlagName, config.DefaultHTTPServerAPIVersionHeader, HTTPSrvConfig.APIVersionHeader.FlagDescription)
+	flag.DurationVar(&HTTPSrvConfig.ReadyTimeout.Value, HTTPSrvConfig.ReadyTimeout.FlagName, config.DefaultHTTPServerReadyTimeout, HTTPSrvConfig.ReadyTimeout.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.ReadyOptionalDeps.Value, HTTPSrvConfig.ReadyOptionalDeps.FlagName, config.DefaultHTTPServerReadyOptionalDependencies, HTTPSrvConfig.ReadyOptionalDeps.FlagDescription)
+	flag.DurationVar(&HTTPSrvConfig.SwaggerCacheMaxAge.Value, HTTPSrvConfig.SwaggerCacheMaxAge.FlagName, config.DefaultHTTPServerSwaggerCacheMaxAge, HTTPSrvConfig.SwaggerCacheMaxAge.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.FilterRejectWildcard.Value, HTTPSrvConfig.FilterRejectWildcard.FlagName, config.DefaultHTTPServerFilterRejectLeadingWildcard, HTTPSrvConfig.FilterRejectWildcard.FlagDescription)
+	flag.IntVar(&HTTPSrvConfig.FilterMaxPredicates.Value, HTTPSrvConfig.FilterMaxPredicates.FlagName, config.DefaultHTTPServerFilterMaxPredicates, HTTPSrvConfig.FilterMaxPredicates.FlagDescription)
+	flag.IntVar(&HTTPSrvConfig.TokenMaxLength.Value, HTTPSrvConfig.TokenMaxLength.FlagName, config.DefaultHTTPServerTokenMaxLength, HTTPSrvConfig.TokenMaxLength.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.SortDefaultDirection.Value, HTTPSrvConfig.SortDefaultDirection.FlagName, config.DefaultHTTPServerSortDefaultDirection, HTTPSrvConfig.SortDefaultDirection.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.SortDefaultNulls.Value, HTTPSrvConfig.SortDefaultNulls.FlagName, config.DefaultHTTPServerSortDefaultNulls, HTTPSrvConfig.SortDefaultNulls.FlagDescription)
+	flag.BoolVar(&HTTPSrvConfig.BodyLoggingEnabled.Value, HTTPSrvConfig.BodyLoggingEnabled.FlagName, config.DefaultHTTPServerBodyLoggingEnabled, HTTPSrvConfig.BodyLoggingEnabled.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.BodyLoggingRoutes.Value, HTTPSrvConfig.BodyLoggingRoutes.FlagName, config.DefaultHTTPServerBodyLoggingRoutes, HTTPSrvConfig.BodyLoggingRoutes.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.BodyLoggingRedactKeys.Value, HTTPSrvConfig.BodyLoggingRedactKeys.FlagName, config.DefaultHTTPServerBodyLoggingRedactKeys, HTTPSrvConfig.BodyLoggingRedactKeys.FlagDescription)
+	flag.IntVar(&HTTPSrvConfig.BodyLoggingMaxSize.Value, HTTPSrvConfig.BodyLoggingMaxSize.FlagName, config.DefaultHTTPServerBodyLoggingMaxSize, HTTPSrvConfig.BodyLoggingMaxSize.FlagDescription)
 	flag.BoolVar(&HTTPSrvConfig.CorsEnabled.Value, HTTPSrvConfig.CorsEnabled.FlagName, config.DefaultHTTPServerCorsEnabled, HTTPSrvConfig.CorsEnabled.FlagDescription)
 	flag.BoolVar(&HTTPSrvConfig.CorsAllowCredentials.Value, HTTPSrvConfig.CorsAllowCredentials.FlagName, config.DefaultHTTPServerCorsAllowCredentials, HTTPSrvConfig.CorsAllowCredentials.FlagDescription)
 	flag.StringVar(&HTTPSrvConfig.CorsAllowedOrigins.Value, HTTPSrvConfig.CorsAllowedOrigins.FlagName, config.DefaultHTTPServerCorsAllowedOrigins, HTTPSrvConfig.CorsAllowedOrigins.FlagDescription)
 	flag.StringVar(&HTTPSrvConfig.CorsAllowedMethods.Value, HTTPSrvConfig.CorsAllowedMethods.FlagName, config.DefaultHTTPServerCorsAllowedMethods, HTTPSrvConfig.CorsAllowedMethods.FlagDescription)
 	flag.StringVar(&HTTPSrvConfig.CorsAllowedHeaders.Value, HTTPSrvConfig.CorsAllowedHeaders.FlagName, config.DefaultHTTPServerCorsAllowedHeaders, HTTPSrvConfig.CorsAllowedHeaders.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.CorsRouteGroups.Value, HTTPSrvConfig.CorsRouteGroups.FlagName, config.DefaultHTTPServerCorsRouteGroups, HTTPSrvConfig.CorsRouteGroups.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.ContentTypes.Value, HTTPSrvConfig.ContentTypes.FlagName, config.DefaultHTTPServerContentTypes, HTTPSrvConfig.ContentTypes.FlagDescription)
+	flag.StringVar(&HTTPSrvConfig.ContentTypeGroups.Value, HTTPSrvConfig.ContentTypeGroups.FlagName, config.DefaultHTTPServerContentTypeRouteGroups, HTTPSrvConfig.ContentTypeGroups.FlagDescription)
 
 	// Database configuration values
 	flag.StringVar(&DBConfig.Kind.Value, DBConfig.Kind.FlagName, config.DefaultDatabaseKind, DBConfig.Kind.FlagDescription)
@@ -76,15 +122,26 @@ func init() {
 	flag.IntVar(&DBConfig.Port.Value, DBConfig.Port.FlagName, config.DefaultDatabasePort, DBConfig.Port.FlagDescription)
 	flag.StringVar(&DBConfig.Username.Value, DBConfig.Username.FlagName, config.DefaultDatabaseUsername, DBConfig.Username.FlagDescription)
 	flag.StringVar(&DBConfig.Password.Value, DBConfig.Password.FlagName, config.DefaultDatabasePassword, DBConfig.Password.FlagDescription)
+	flag.StringVar(&DBConfig.UsernameFile.Value, DBConfig.UsernameFile.FlagName, config.DefaultDatabaseUsernameFile, DBConfig.UsernameFile.FlagDescription)
+	flag.StringVar(&DBConfig.PasswordFile.Value, DBConfig.PasswordFile.FlagName, config.DefaultDatabasePasswordFile, DBConfig.PasswordFile.FlagDescription)
 	flag.StringVar(&DBConfig.Name.Value, DBConfig.Name.FlagName, config.DefaultDatabaseName, DBConfig.Name.FlagDescription)
 	flag.StringVar(&DBConfig.SSLMode.Value, DBConfig.SSLMode.FlagName, config.DefaultDatabaseSSLMode, DBConfig.SSLMode.FlagDescription)
 	flag.StringVar(&DBConfig.TimeZone.Value, DBConfig.TimeZone.FlagName, config.DefaultDatabaseTimeZone, DBConfig.TimeZone.FlagDescription)
 	flag.DurationVar(&DBConfig.MaxPingTimeout.Value, DBConfig.MaxPingTimeout.FlagName, config.DefaultDatabaseMaxPingTimeout, DBConfig.MaxPingTimeout.FlagDescription)
 	flag.DurationVar(&DBConfig.MaxQueryTimeout.Value, DBConfig.MaxQueryTimeout.FlagName, config.DefaultDatabaseMaxQueryTimeout, DBConfig.MaxQueryTimeout.FlagDescription)
+	flag.DurationVar(&DBConfig.CountTimeout.Value, DBConfig.CountTimeout.FlagName, config.DefaultDatabaseCountTimeout, DBConfig.CountTimeout.FlagDescription)
+	flag.IntVar(&DBConfig.PingAttempts.Value, DBConfig.PingAttempts.FlagName, config.DefaultDatabasePingAttempts, DBConfig.PingAttempts.FlagDescription)
+	flag.DurationVar(&DBConfig.PingBackoff.Value, DBConfig.PingBackoff.FlagName, config.DefaultDatabasePingBackoff, DBConfig.PingBackoff.FlagDescription)
+	flag.DurationVar(&DBConfig.PingDeadline.Value, DBConfig.PingDeadline.FlagName, config.DefaultDatabasePingDeadline, DBConfig.PingDeadline.FlagDescription)
+	flag.IntVar(&DBConfig.TxMaxRetries.Value, DBConfig.TxMaxRetries.FlagName, config.DefaultDatabaseTxMaxRetries, DBConfig.TxMaxRetries.FlagDescription)
+	flag.DurationVar(&DBConfig.TxRetryBackoff.Value, DBConfig.TxRetryBackoff.FlagName, config.DefaultDatabaseTxRetryBackoff, DBConfig.TxRetryBackoff.FlagDescription)
 	flag.DurationVar(&DBConfig.ConnMaxLifetime.Value, DBConfig.ConnMaxLifetime.FlagName, config.DefaultDatabaseConnMaxLifetime, DBConfig.ConnMaxLifetime.FlagDescription)
+	flag.IntVar(&DBConfig.ConnMaxLifetimeJitter.Value, DBConfig.ConnMaxLifetimeJitter.FlagName, config.DefaultDatabaseConnMaxLifetimeJitter, DBConfig.ConnMaxLifetimeJitter.FlagDescription)
 	flag.IntVar(&DBConfig.MaxIdleConns.Value, DBConfig.MaxIdleConns.FlagName, config.DefaultDatabaseMaxIdleConns, DBConfig.MaxIdleConns.FlagDescription)
 	flag.IntVar(&DBConfig.MaxOpenConns.Value, DBConfig.MaxOpenConns.FlagName, config.DefaultDatabaseMaxOpenConns, DBConfig.MaxOpenConns.FlagDescription)
 	flag.BoolVar(&DBConfig.MigrationEnable.Value, DBConfig.MigrationEnable.FlagName, config.DefaultDatabaseMigrationEnable, DBConfig.MigrationEnable.FlagDescription)
+	flag.BoolVar(&DBConfig.ReadOnly.Value, DBConfig.ReadOnly.FlagName, config.DefaultDatabaseReadOnly, DBConfig.ReadOnly.FlagDescription)
+	flag.BoolVar(&DBConfig.RequestApplicationName.Value, DBConfig.RequestApplicationName.FlagName, config.DefaultDatabaseRequestApplicationName, DBConfig.RequestApplicationName.FlagDescription)
 
 	// OpenTelemetry configuration values
 	flag.StringVar(&OTConfig.TraceEndpoint.Value, OTConfig.TraceEndpoint.FlagName, config.DefaultTraceEndpoint, OTConfig.TraceEndpoint.FlagDescription)
@@ -174,11 +231,32 @@ func init() {
 	// and override the values when they are set
 	config.ParseEnvVars(LogConfig, HTTPSrvConfig, DBConfig, OTConfig)
 
+	// the credential files override the inline values
+	if err := DBConfig.LoadCredentialFiles(); err != nil {
+		slog.Error("error loading database credential files", "error", err)
+		os.Exit(1)
+	}
+
+	// implement the config dump flag, before the validation to debug the invalid values
+	if showConfigDump {
+		if err := config.Dump(os.Stdout, flag.CommandLine, LogConfig, HTTPSrvConfig, DBConfig, OTConfig); err != nil {
+			slog.Error("error dumping configuration", "error", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Validate the configuration
 	if err := config.Validate(LogConfig, HTTPSrvConfig, DBConfig, OTConfig); err != nil {
 		slog.Error("error validating configuration", "error", err)
 		os.Exit(1)
 	}
+
+	// every log line has the service and the environment, known once the environment variables are parsed
+	logger = o11y.NewLogger(logHandler, LogConfig.ServiceName.Value, LogConfig.Environment.Value)
+	slog.SetDefault(logger)
+
+	configLoadDone()
 }
 
 //	@tile			Golang RESTful API Service Template
@@ -194,7 +272,13 @@ func main() {
 	// Default context
 	ctx := context.Background()
 
+	// a stuck dependency exits the service instead of hanging the startup
+	startupCtx, startupCancel := startup.Deadline(ctx, HTTPSrvConfig.StartupTimeout.Value, func() {
+		os.Exit(1)
+	})
+
 	// create OpenTelemetry
+	telemetryDone := startup.Phase("telemetry")
 	telemetry, err := o11y.New(ctx, OTConfig)
 	if err != nil {
 		slog.Error("error creating OpenTelemetry", "error", err)
@@ -205,6 +289,7 @@ func main() {
 		slog.Error("error starting telemetry", "error", err)
 		os.Exit(1)
 	}
+	telemetryDone()
 
 	// Configure server URL information
 	serverProtocol := "http"
@@ -230,26 +315,29 @@ func main() {
 	docs.SwaggerInfo.Version = version.Version
 
 	// Create PGSQLUserStore
-	dbDSN := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
-		DBConfig.Address.Value,
-		DBConfig.Port.Value,
-		DBConfig.Username.Value,
-		DBConfig.Password.Value,
-		DBConfig.Name.Value,
-		DBConfig.SSLMode.Value,
-		DBConfig.TimeZone.Value,
-	)
+	dbDSN := databaseDSN(DBConfig)
 
-	db, err := sql.Open(DBConfig.Kind.Value, dbDSN)
+	// the connector opens the new connections with the credentials reloaded on SIGHUP
+	dbConnectDone := startup.Phase("database connect")
+	dbConnector, err := database.NewConnector(DBConfig.Kind.Value, dbDSN)
 	if err != nil {
 		slog.Error("database connection error", "error", err)
 		os.Exit(1)
 	}
-	defer db.Close()
+
+	// with jitter, the connector expires each connection within the jitter window
+	// and the pool lifetime is only the backstop at the end of the window
+	connMaxLifetime := DBConfig.ConnMaxLifetime.Value
+	if DBConfig.ConnMaxLifetimeJitter.Value > 0 {
+		dbConnector.SetMaxLifetime(connMaxLifetime, DBConfig.ConnMaxLifetimeJitter.Value)
+		connMaxLifetime += connMaxLifetime * time.Duration(DBConfig.ConnMaxLifetimeJitter.Value) / 100
+	}
+
+	db := sql.OpenDB(dbConnector)
 
 	db.SetMaxIdleConns(DBConfig.MaxIdleConns.Value)
 	db.SetMaxOpenConns(DBConfig.MaxOpenConns.Value)
-	db.SetConnMaxLifetime(DBConfig.ConnMaxLifetime.Value)
+	db.SetConnMaxLifetime(connMaxLifetime)
 	db.SetConnMaxIdleTime(DBConfig.ConnMaxIdleTime.Value)
 
 	slog.Debug("database connection",
@@ -263,14 +351,17 @@ func main() {
 		"max_idle_conns", DBConfig.MaxIdleConns.Value,
 		"max_open_conns", DBConfig.MaxOpenConns.Value,
 		"conn_max_lifetime", DBConfig.ConnMaxLifetime.Value,
+		"conn_max_lifetime_jitter", DBConfig.ConnMaxLifetimeJitter.Value,
 		"conn_max_idle_time", DBConfig.ConnMaxIdleTime.Value,
 	)
 
-	// Test database connection
-	ctx, cancel := context.WithTimeout(ctx, DBConfig.MaxPingTimeout.Value)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
+	// Test database connection, retrying while the database starts
+	if err := database.Ping(startupCtx, db, database.PingOpts{
+		Attempts: DBConfig.PingAttempts.Value,
+		Timeout:  DBConfig.MaxPingTimeout.Value,
+		Backoff:  DBConfig.PingBackoff.Value,
+		Deadline: DBConfig.PingDeadline.Value,
+	}); err != nil {
 		slog.Error("database ping error",
 			"kind", DBConfig.Kind.Value,
 			"address", DBConfig.Address.Value,
@@ -284,23 +375,40 @@ func main() {
 			"error", err)
 		os.Exit(1)
 	}
+	dbConnectDone()
 
-	// Run the database migrations
-	if DBConfig.MigrationEnable.Value {
+	// Run the database migrations, these cannot run in read-only mode
+	if DBConfig.MigrationEnable.Value && DBConfig.ReadOnly.Value {
+		slog.Warn("database migrations skipped in read-only mode")
+	} else if DBConfig.MigrationEnable.Value {
 		slog.Info("running database migrations")
-		if err := database.Migrate(ctx, DBConfig.Kind.Value, db); err != nil {
+		migrationsDone := startup.Phase("database migrations")
+		if err := database.Migrate(startupCtx, DBConfig.Kind.Value, db); err != nil {
 			slog.Error("database migration error", "error", err)
 			os.Exit(1)
 		}
+		migrationsDone()
 	}
 
+	servicesDone := startup.Phase("services")
+
 	// Create a new userRepository
+	// the request ID shows in the database activity as the application name
+	var dbApplicationName repository.ApplicationNameFunc
+	if DBConfig.RequestApplicationName.Value {
+		dbApplicationName = middleware.RequestIDFromContext
+	}
+
 	userRepository, err := repository.NewUsersRepository(
 		repository.UsersRepositoryConfig{
 			DB:              db,
 			MaxPingTimeout:  DBConfig.MaxPingTimeout.Value,
 			MaxQueryTimeout: DBConfig.MaxQueryTimeout.Value,
+			CountTimeout:    DBConfig.CountTimeout.Value,
 			OT:              telemetry,
+			ApplicationName: dbApplicationName,
+			TxMaxRetries:    DBConfig.TxMaxRetries.Value,
+			TxRetryBackoff:  DBConfig.TxRetryBackoff.Value,
 		},
 	)
 	if err != nil {
@@ -308,10 +416,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the services publish the changes of the resources to the event stream
+	eventBroker := service.NewEventBroker(service.DefaultEventBufferSize)
+
 	// Create user Service config
 	userServiceConf := service.UsersServiceConf{
 		Repository: userRepository,
 		OT:         telemetry,
+		Events:     eventBroker,
 	}
 
 	// Create user Services
@@ -323,8 +435,13 @@ func main() {
 
 	// Create handler config
 	userHandlerConf := handler.UsersHandlerConf{
-		Service: userService,
-		OT:      telemetry,
+		Service:               userService,
+		OT:                    telemetry,
+		RejectLeadingWildcard: HTTPSrvConfig.FilterRejectWildcard.Value,
+		FilterMaxPredicates:   HTTPSrvConfig.FilterMaxPredicates.Value,
+		TokenMaxLength:        HTTPSrvConfig.TokenMaxLength.Value,
+		SortDefaultDirection:  HTTPSrvConfig.SortDefaultDirection.Value,
+		SortDefaultNulls:      HTTPSrvConfig.SortDefaultNulls.Value,
 	}
 
 	// Create handlers
@@ -334,7 +451,34 @@ func main() {
 		slog.Error("error creating user handler", "error", err)
 		os.Exit(1)
 	}
-	swaggerHandler := handler.NewSwaggerHandler(swaggerURLDocs)
+	eventsHandler, err := handler.NewEventsHandler(handler.EventsHandlerConf{
+		Events: eventBroker,
+	})
+	if err != nil {
+		slog.Error("error creating events handler", "error", err)
+		os.Exit(1)
+	}
+
+	// the dependencies listed as optional don't make the service unready when down
+	optionalDeps := strings.Split(HTTPSrvConfig.ReadyOptionalDeps.Value, ",")
+	for i := range optionalDeps {
+		optionalDeps[i] = strings.TrimSpace(optionalDeps[i])
+	}
+
+	readinessHandler, err := handler.NewReadinessHandler(handler.ReadinessHandlerConf{
+		Checker: service.NewReadinessChecker(HTTPSrvConfig.ReadyTimeout.Value,
+			service.Dependency{
+				Name:     "database",
+				Required: !slices.Contains(optionalDeps, "database"),
+				Probe:    db.PingContext,
+			},
+		),
+	})
+	if err != nil {
+		slog.Error("error creating readiness handler", "error", err)
+		os.Exit(1)
+	}
+	swaggerHandler := handler.NewSwaggerHandler(swaggerURLDocs, HTTPSrvConfig.SwaggerCacheMaxAge.Value)
 	pprofHandler := handler.NewPprofHandler()
 
 	// Create a new ServeMux and register the handlers
@@ -343,24 +487,32 @@ func main() {
 	swaggerHandler.RegisterRoutes(apiRouter)
 	versionHandler.RegisterRoutes(apiRouter)
 	userHandler.RegisterRoutes(apiRouter)
+	eventsHandler.RegisterRoutes(apiRouter)
+	readinessHandler.RegisterRoutes(apiRouter)
 
 	if HTTPSrvConfig.PprofEnabled.Value {
 		pprofHandler.RegisterRoutes(apiRouter)
 	}
 
+	servicesDone()
+
+	// ready is set once the server is started, requests before respond 503
+	var ready atomic.Bool
+
 	mdws := []middleware.Middleware{
-		middleware.RewriteStandardErrorsAsJSON,
-		middleware.Logging,
-		middleware.HeaderAPIVersion(apiPrefix),
-		middleware.OtelTextMapPropagation,
+		middleware.WriteTimeout(HTTPSrvConfig.WriteTimeout.Value),
+		middleware.RequestID,
 	}
 
+	// the CORS headers are set before any middleware can short-circuit the request,
+	// so the browsers can read the 503, 405 and 415 responses and their headers
 	if HTTPSrvConfig.CorsEnabled.Value {
 		slog.Warn("CORS enabled",
 			"allowed_origins", HTTPSrvConfig.CorsAllowedOrigins.Value,
 			"allowed_methods", HTTPSrvConfig.CorsAllowedMethods.Value,
 			"allowed_headers", HTTPSrvConfig.CorsAllowedHeaders.Value,
 			"allow_credentials", HTTPSrvConfig.CorsAllowCredentials.Value,
+			"route_groups", HTTPSrvConfig.CorsRouteGroups.Value,
 		)
 
 		corsOpts := middleware.CorsOpts{
@@ -370,9 +522,101 @@ func main() {
 			AllowCredentials: HTTPSrvConfig.CorsAllowCredentials.Value,
 		}
 
+		// the route groups only override the allowed origins
+		routeGroups, err := config.ParseCorsRouteGroups(HTTPSrvConfig.CorsRouteGroups.Value)
+		if err != nil {
+			slog.Error("error parsing CORS route groups", "error", err)
+			os.Exit(1)
+		}
+
+		if len(routeGroups) > 0 {
+			corsOpts.RouteGroups = make(map[string]middleware.CorsOpts, len(routeGroups))
+			for prefix, origins := range routeGroups {
+				groupOpts := corsOpts
+				groupOpts.AllowedOrigins = origins
+				corsOpts.RouteGroups[prefix] = groupOpts
+			}
+		}
+
 		mdws = append(mdws, middleware.Cors(corsOpts))
 	}
 
+	mdws = append(mdws,
+		middleware.Ready(&ready),
+		middleware.RewriteStandardErrorsAsJSON,
+		middleware.OtelTextMapPropagation,
+		middleware.Tracing,
+		middleware.Logging,
+		middleware.SlowRequest(HTTPSrvConfig.SlowRequestThreshold.Value),
+		middleware.RequestTimeout(middleware.RequestTimeoutOpts{
+			Timeout:    HTTPSrvConfig.RequestTimeout.Value,
+			SkipRoutes: strings.Split(HTTPSrvConfig.RequestTimeoutSkip.Value, ","),
+		}),
+	)
+
+	// the panics are recovered inside the logging, so the 500 responses are logged
+	if HTTPSrvConfig.RecoverEnabled.Value {
+		mdws = append(mdws, middleware.Recover)
+	}
+
+	if HTTPSrvConfig.ReadOnly.Value {
+		slog.Warn("http server read-only mode enabled, only GET, HEAD and OPTIONS requests are served")
+		mdws = append(mdws, middleware.AllowedMethods(http.MethodGet, http.MethodHead, http.MethodOptions))
+	}
+
+	if DBConfig.ReadOnly.Value {
+		slog.Warn("database read-only mode enabled, mutating endpoints respond 503")
+		mdws = append(mdws, middleware.ReadOnly)
+	}
+
+	// the content types were validated with the configuration
+	contentTypes, _ := config.ParseContentTypes(HTTPSrvConfig.ContentTypes.Value)
+	contentTypeGroups, _ := config.ParseContentTypeRouteGroups(HTTPSrvConfig.ContentTypeGroups.Value)
+	if len(contentTypes) > 0 || len(contentTypeGroups) > 0 {
+		mdws = append(mdws, middleware.ContentType(middleware.ContentTypeOpts{
+			Allowed:     contentTypes,
+			RouteGroups: contentTypeGroups,
+		}))
+	}
+
+	if HTTPSrvConfig.PrettyJSONEnabled.Value {
+		mdws = append(mdws, middleware.PrettyJSON)
+	}
+
+	// after PrettyJSON, so the keys are converted before the responses are indented.
+	// Only when the camel case can be used, as it buffers the request bodies
+	camelCase := HTTPSrvConfig.JSONKeyCasing.Value == config.HTTPServerJSONKeyCasingCamel
+	if camelCase || HTTPSrvConfig.JSONKeyNegotiation.Value {
+		mdws = append(mdws, middleware.CamelCaseJSON(middleware.CamelCaseJSONOpts{
+			Camel:        camelCase,
+			Negotiation:  HTTPSrvConfig.JSONKeyNegotiation.Value,
+			MaxBodyBytes: int64(HTTPSrvConfig.JSONKeyMaxBodyBytes.Value),
+		}))
+	}
+
+	if HTTPSrvConfig.BodyLoggingEnabled.Value {
+		slog.Warn("request and response body logging enabled",
+			"routes", HTTPSrvConfig.BodyLoggingRoutes.Value,
+			"max_size", HTTPSrvConfig.BodyLoggingMaxSize.Value,
+			"sample_rate", LogConfig.SampleRate.Value,
+		)
+
+		bodyLoggingOpts := middleware.BodyLoggingOpts{
+			MaxSize:    HTTPSrvConfig.BodyLoggingMaxSize.Value,
+			SampleRate: LogConfig.SampleRate.Value,
+		}
+
+		if HTTPSrvConfig.BodyLoggingRoutes.Value != "" {
+			bodyLoggingOpts.Routes = strings.Split(HTTPSrvConfig.BodyLoggingRoutes.Value, ",")
+		}
+
+		if HTTPSrvConfig.BodyLoggingRedactKeys.Value != "" {
+			bodyLoggingOpts.RedactKeys = strings.Split(HTTPSrvConfig.BodyLoggingRedactKeys.Value, ",")
+		}
+
+		mdws = append(mdws, middleware.BodyLogging(bodyLoggingOpts))
+	}
+
 	// middleware chain
 	apiMiddlewares := middleware.Chain(
 		mdws...,
@@ -381,23 +625,93 @@ func main() {
 	mainRouter := http.NewServeMux()
 	mainRouter.Handle(fmt.Sprintf("/%s/", apiPrefix), http.StripPrefix(fmt.Sprintf("/%s", apiPrefix), apiMiddlewares(apiRouter)))
 
+	// every response has the version of the service, also the ones outside the API prefix
+	var mainHandler http.Handler = mainRouter
+	if HTTPSrvConfig.APIVersionHeader.Value != "" {
+		mainHandler = middleware.HeaderAPIVersion(HTTPSrvConfig.APIVersionHeader.Value, version.Version)(mainRouter)
+	}
+
 	httpServer := server.NewHTTPServer(
 		server.HTTPServerConfig{
 			Ctx:         ctx,
-			HttpHandler: mainRouter,
+			HttpHandler: mainHandler,
 			Config:      HTTPSrvConfig,
+			OnReload: func() {
+				reloadDatabaseCredentials(dbConnector)
+			},
 		},
 	)
 
 	// Start the server
+	listenerDone := startup.Phase("http listener")
 	go httpServer.Start()
 
-	// Wait for stopChan to close
-	<-httpServer.Wait()
+	// Wait for stopChan to close, the server stops before listening
+	// when the listener can't start
+	select {
+	case <-httpServer.Listening():
+		listenerDone()
+		startupCancel()
+		ready.Store(true)
+		startup.Done("ready")
+
+		<-httpServer.Wait()
+	case <-httpServer.Wait():
+		startupCancel()
+	}
+
+	// the http server phase is logged by the server on the shutdown signal
+	shutdown := o11y.NewLifecycle("shutdown")
 
 	// Shutdown OpenTelemetry
 	slog.Info("shutting down OpenTelemetry")
+	telemetryDone = shutdown.Phase("telemetry")
 	telemetry.Shutdown()
+	telemetryDone()
+
+	dbCloseDone := shutdown.Phase("database close")
+	if err := db.Close(); err != nil {
+		slog.Error("database close error", "error", err)
+	}
+	dbCloseDone()
+
+	shutdown.Done("server stopped gracefully")
+}
+
+// databaseDSN returns the DSN of the database connections.
+func databaseDSN(c *config.DatabaseConfig) string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=%s",
+		c.Address.Value,
+		c.Port.Value,
+		c.Username.Value,
+		c.Password.Value,
+		c.Name.Value,
+		c.SSLMode.Value,
+		c.TimeZone.Value,
+	)
+}
+
+// reloadDatabaseCredentials re-reads the database credential files, the new
+// connections use them and the open ones keep the previous credentials
+// until they reach their max lifetime. The credentials are loaded into a copy
+// of the configuration, so the global one is never written from the signal
+// goroutine and the rejected credentials are not kept.
+func reloadDatabaseCredentials(connector *database.Connector) {
+	if DBConfig.UsernameFile.Value == "" && DBConfig.PasswordFile.Value == "" {
+		return
+	}
+
+	reloaded := *DBConfig
+	if err := reloaded.LoadCredentialFiles(); err != nil {
+		slog.Error("error reloading database credential files", "error", err)
+		return
+	}
+
+	if err := reloaded.Validate(); err != nil {
+		slog.Error("error validating reloaded database credentials", "error", err)
+		return
+	}
 
-	slog.Info("server stopped gracefully")
+	connector.SetDSN(databaseDSN(&reloaded))
+	slog.Info("database credentials reloaded")
 }
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"sync/atomic"
+	"time"
+)
+
+// Connector opens the connections of a *sql.DB with a DSN that can be
+// replaced at runtime, like when the credentials are rotated.
+// The open connections keep the DSN they were opened with.
+type Connector struct {
+	driver driver.Driver
+	dsn    atomic.Pointer[string]
+
+	maxLifetime   time.Duration
+	jitterPercent int
+}
+
+// NewConnector creates a new Connector opening the connections with the driver registered as driverName.
+func NewConnector(driverName string, dsn string) (*Connector, error) {
+	// the registered drivers are only reachable through a *sql.DB
+	db, err := sql.Open(driverName, "")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	c := &Connector{driver: db.Driver()}
+	c.SetDSN(dsn)
+
+	return c, nil
+}
+
+// SetDSN sets the DSN of the connections opened from now on.
+func (c *Connector) SetDSN(dsn string) {
+	c.dsn.Store(&dsn)
+}
+
+// SetMaxLifetime makes the connections opened from now on expire after maxLifetime,
+// shifted by a random amount of up to jitterPercent percent in either direction,
+// so the connections opened together don't expire together. Zero disables it.
+// It is not safe to call concurrently with Connect, call it before using the connector.
+func (c *Connector) SetMaxLifetime(maxLifetime time.Duration, jitterPercent int) {
+	c.maxLifetime = maxLifetime
+	c.jitterPercent = jitterPercent
+}
+
+// Connect opens a new connection with the current DSN.
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connect(ctx)
+	if err != nil || c.maxLifetime <= 0 {
+		return conn, err
+	}
+
+	return &expiringConn{
+		Conn:      conn,
+		expiresAt: time.Now().Add(jitteredLifetime(c.maxLifetime, c.jitterPercent)),
+	}, nil
+}
+
+// connect opens a new connection of the driver with the current DSN.
+func (c *Connector) connect(ctx context.Context) (driver.Conn, error) {
+	dsn := *c.dsn.Load()
+
+	if dc, ok := c.driver.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+
+		return connector.Connect(ctx)
+	}
+
+	return c.driver.Open(dsn)
+}
+
+// Driver returns the driver of the connections.
+func (c *Connector) Driver() driver.Driver {
+	return c.driver
+}
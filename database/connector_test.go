@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"testing"
+)
+
+func TestConnector_SetDSN(t *testing.T) {
+	dsnRecorder.reset()
+
+	connector, err := NewConnector("dsn-recording", "password=old")
+	if err != nil {
+		t.Fatalf("could not create connector: %v", err)
+	}
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	// each connection is closed after use, so the next one is opened again
+	db.SetMaxIdleConns(0)
+
+	ctx := context.Background()
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+
+	connector.SetDSN("password=new")
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+
+	want := []string{"password=old", "password=new"}
+	if !slices.Equal(dsnRecorder.dsns, want) {
+		t.Errorf("expected DSNs %q, got %q", want, dsnRecorder.dsns)
+	}
+}
+
+func TestNewConnector_UnknownDriver(t *testing.T) {
+	if _, err := NewConnector("unknown-driver", ""); err == nil {
+		t.Error("expected an error for an unknown driver")
+	}
+}
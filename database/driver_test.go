@@ -0,0 +1,45 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+)
+
+// dsnRecorder is the driver of the tests, registered once as "dsn-recording"
+// since NewConnector looks the drivers up by name.
+var dsnRecorder = &dsnDriver{}
+
+func init() {
+	sql.Register("dsn-recording", dsnRecorder)
+}
+
+// dsnDriver records the DSN of the opened connections.
+type dsnDriver struct {
+	mu   sync.Mutex
+	dsns []string
+}
+
+func (d *dsnDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dsns = append(d.dsns, dsn)
+
+	return &dsnConn{}, nil
+}
+
+// reset forgets the recorded DSNs.
+func (d *dsnDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.dsns = nil
+}
+
+type dsnConn struct{}
+
+func (c *dsnConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (c *dsnConn) Close() error                        { return nil }
+func (c *dsnConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// jitteredLifetime returns the lifetime shifted by a random amount
+// of up to jitterPercent percent, in either direction.
+func jitteredLifetime(lifetime time.Duration, jitterPercent int) time.Duration {
+	if jitterPercent <= 0 {
+		return lifetime
+	}
+
+	window := float64(lifetime) * float64(jitterPercent) / 100
+
+	return lifetime + time.Duration((rand.Float64()*2-1)*window)
+}
+
+// expiringConn is a connection reported as bad to the pool once it expires,
+// so the pool closes it instead of reusing it. The optional interfaces of
+// the wrapped connection are forwarded, or skipped when it has none.
+type expiringConn struct {
+	driver.Conn
+	expiresAt time.Time
+}
+
+// expired returns true when the connection must not be reused.
+func (c *expiringConn) expired() bool {
+	return time.Now().After(c.expiresAt)
+}
+
+// IsValid is called before the connection goes back to the pool.
+func (c *expiringConn) IsValid() bool {
+	if c.expired() {
+		return false
+	}
+
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+
+	return true
+}
+
+// ResetSession is called before an idle connection is reused.
+func (c *expiringConn) ResetSession(ctx context.Context) error {
+	if c.expired() {
+		return driver.ErrBadConn
+	}
+
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+
+	return nil
+}
+
+func (c *expiringConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+
+	return c.Conn.Prepare(query)
+}
+
+func (c *expiringConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+
+	// like database/sql, the drivers without BeginTx only support the default options
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		return nil, errors.New("database: driver does not support non-default transaction options")
+	}
+
+	//lint:ignore SA1019 Begin is the fallback of the drivers without BeginTx
+	return c.Conn.Begin()
+}
+
+func (c *expiringConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := c.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *expiringConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := c.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+
+	return nil, driver.ErrSkip
+}
+
+func (c *expiringConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+
+	return nil
+}
+
+func (c *expiringConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+
+	return driver.ErrSkip
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConnector_SetMaxLifetime(t *testing.T) {
+	connector, err := NewConnector("dsn-recording", "")
+	if err != nil {
+		t.Fatalf("could not create connector: %v", err)
+	}
+
+	lifetime := 10 * time.Minute
+	jitter := 20
+	connector.SetMaxLifetime(lifetime, jitter)
+
+	window := lifetime * time.Duration(jitter) / 100
+
+	ctx := context.Background()
+	expirations := make(map[time.Time]struct{})
+
+	for range 100 {
+		before := time.Now()
+		conn, err := connector.Connect(ctx)
+		if err != nil {
+			t.Fatalf("could not connect: %v", err)
+		}
+		after := time.Now()
+
+		expiring, ok := conn.(*expiringConn)
+		if !ok {
+			t.Fatalf("expected an expiring connection, got %T", conn)
+		}
+
+		earliest := before.Add(lifetime - window)
+		latest := after.Add(lifetime + window)
+		if expiring.expiresAt.Before(earliest) || expiring.expiresAt.After(latest) {
+			t.Errorf("expected the expiry between %s and %s, got %s", earliest, latest, expiring.expiresAt)
+		}
+
+		expirations[expiring.expiresAt] = struct{}{}
+	}
+
+	// the connections opened together must not expire together
+	if len(expirations) < 2 {
+		t.Errorf("expected the expiries to be distributed, got %d distinct", len(expirations))
+	}
+}
+
+func TestExpiringConn_Expired(t *testing.T) {
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		valid     bool
+	}{
+		{name: "not expired", expiresAt: time.Now().Add(time.Hour), valid: true},
+		{name: "expired", expiresAt: time.Now().Add(-time.Second), valid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given
+			conn := &expiringConn{Conn: &dsnConn{}, expiresAt: tt.expiresAt}
+
+			// When
+			valid := conn.IsValid()
+			err := conn.ResetSession(context.Background())
+
+			// Then
+			if valid != tt.valid {
+				t.Errorf("expected IsValid %t, got %t", tt.valid, valid)
+			}
+
+			if tt.valid && err != nil {
+				t.Errorf("expected no reset error, got %v", err)
+			}
+
+			if !tt.valid && !errors.Is(err, driver.ErrBadConn) {
+				t.Errorf("expected reset error %v, got %v", driver.ErrBadConn, err)
+			}
+		})
+	}
+}
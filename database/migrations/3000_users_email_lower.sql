@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- the emails are stored normalized, trimmed and in lower case, since they are
+-- looked up that way. Two rows differing only in the case of their email make
+-- this migration fail, they must be merged by hand first
+UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email));
+
+-- email is unique regardless of its case, the index keeps the name of the
+-- constraint so the duplicated emails are still reported as such
+ALTER TABLE users DROP CONSTRAINT IF EXISTS "users_email";
+DROP INDEX IF EXISTS "idx_users_email";
+CREATE UNIQUE INDEX "users_email" ON users (lower(email));
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- the emails stay in lower case
+DROP INDEX IF EXISTS "users_email";
+ALTER TABLE users ADD CONSTRAINT "users_email" UNIQUE (email);
+CREATE INDEX "idx_users_email" ON users (email);
+
+-- +goose StatementEnd
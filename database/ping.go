@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Pinger is implemented by *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingOpts represents the options for Ping.
+// Timeout is the time allowed for each attempt.
+// Backoff is the wait before the second attempt, doubled after each failed attempt.
+// Deadline is the time allowed for all the attempts.
+type PingOpts struct {
+	Attempts int
+	Timeout  time.Duration
+	Backoff  time.Duration
+	Deadline time.Duration
+}
+
+// Ping pings the database until it answers, retrying with an exponential backoff,
+// so the service can start slightly before the database is available.
+// It returns the error of the last attempt when all the attempts fail or the deadline is exceeded.
+func Ping(ctx context.Context, db Pinger, opts PingOpts) error {
+	if opts.Attempts < 1 {
+		opts.Attempts = 1
+	}
+
+	if opts.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Deadline)
+		defer cancel()
+	}
+
+	backoff := opts.Backoff
+
+	var err error
+	for attempt := 1; attempt <= opts.Attempts; attempt++ {
+		err = ping(ctx, db, opts.Timeout)
+		if err == nil {
+			return nil
+		}
+
+		if attempt == opts.Attempts {
+			break
+		}
+
+		slog.Warn("database ping failed, retrying",
+			"attempt", attempt,
+			"attempts", opts.Attempts,
+			"backoff", backoff,
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+
+	return err
+}
+
+// ping pings the database once, within the given timeout if any.
+func ping(ctx context.Context, db Pinger, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var errUnavailable = errors.New("database unavailable")
+
+// flakyPinger fails the first failures pings.
+type flakyPinger struct {
+	failures int
+	calls    int
+}
+
+func (p *flakyPinger) PingContext(context.Context) error {
+	p.calls++
+	if p.calls <= p.failures {
+		return errUnavailable
+	}
+
+	return nil
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		opts      PingOpts
+		wantErr   error
+		wantCalls int
+	}{
+		{
+			name:      "available at once",
+			failures:  0,
+			opts:      PingOpts{Attempts: 3, Backoff: time.Millisecond},
+			wantCalls: 1,
+		},
+		{
+			name:      "available after some failed attempts",
+			failures:  2,
+			opts:      PingOpts{Attempts: 3, Backoff: time.Millisecond},
+			wantCalls: 3,
+		},
+		{
+			name:      "unavailable for all the attempts",
+			failures:  5,
+			opts:      PingOpts{Attempts: 3, Backoff: time.Millisecond},
+			wantErr:   errUnavailable,
+			wantCalls: 3,
+		},
+		{
+			name:      "deadline exceeded before the attempts",
+			failures:  5,
+			opts:      PingOpts{Attempts: 5, Backoff: time.Second, Deadline: 50 * time.Millisecond},
+			wantErr:   errUnavailable,
+			wantCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &flakyPinger{failures: tc.failures}
+
+			err := Ping(context.Background(), p, tc.opts)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if p.calls != tc.wantCalls {
+				t.Errorf("expected %d ping calls, got %d", tc.wantCalls, p.calls)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"slices"
 	"strings"
 	"time"
@@ -38,14 +39,35 @@ var (
 	// ErrDBInvalidMaxPingTimeout is returned when an invalid max ping timeout is provided
 	ErrDBInvalidMaxPingTimeout = errors.New("invalid max ping timeout, must be between 1s and 30s")
 
+	// ErrDBInvalidPingAttempts is returned when an invalid number of startup ping attempts is provided
+	ErrDBInvalidPingAttempts = errors.New("invalid ping attempts, must be between 1 and 100")
+
+	// ErrDBInvalidPingBackoff is returned when an invalid startup ping backoff is provided
+	ErrDBInvalidPingBackoff = errors.New("invalid ping backoff, must be between 0s and 60s")
+
+	// ErrDBInvalidPingDeadline is returned when an invalid startup ping deadline is provided
+	ErrDBInvalidPingDeadline = errors.New("invalid ping deadline, must be between 1s and 600s")
+
 	// ErrDBInvalidMaxQueryTimeout is returned when an invalid max query timeout is provided
 	ErrDBInvalidMaxQueryTimeout = errors.New("invalid max query timeout, must be between 1s and 30s")
 
+	// ErrDBInvalidCountTimeout is returned when an invalid count timeout is provided
+	ErrDBInvalidCountTimeout = errors.New("invalid count timeout, must be between 0s and the max query timeout")
+
 	// ErrInvalidConnMaxIdleTime is returned when an invalid connection max idle time is provided
 	ErrInvalidConnMaxIdleTime = errors.New("invalid connection max idle time, must be between 1s and 60m")
 
+	// ErrDBInvalidTxMaxRetries is returned when an invalid number of transaction retries is provided
+	ErrDBInvalidTxMaxRetries = errors.New("invalid transaction max retries, must be between 0 and 10")
+
+	// ErrDBInvalidTxRetryBackoff is returned when an invalid transaction retry backoff is provided
+	ErrDBInvalidTxRetryBackoff = errors.New("invalid transaction retry backoff, must be between 0s and 5s")
+
 	// ErrInvalidConnMaxLifetime is returned when an invalid connection max lifetime is provided
 	ErrInvalidConnMaxLifetime = errors.New("invalid connection max lifetime, must be between 1s and 600s")
+
+	// ErrInvalidConnMaxLifetimeJitter is returned when an invalid connection max lifetime jitter is provided
+	ErrInvalidConnMaxLifetimeJitter = errors.New("invalid connection max lifetime jitter, must be between 0 and 50 percent")
 )
 
 const (
@@ -61,16 +83,55 @@ const (
 	DefaultDatabaseSSLMode  = "disable"
 	DefaultDatabaseTimeZone = "UTC"
 
+	// DefaultDatabaseUsernameFile and DefaultDatabasePasswordFile are the default
+	// files the credentials are read from, empty to use the inline values
+	DefaultDatabaseUsernameFile = ""
+	DefaultDatabasePasswordFile = ""
+
 	DefaultDatabaseMaxPingTimeout  = 5 * time.Second
 	DefaultDatabaseMaxQueryTimeout = 5 * time.Second
 
+	// DefaultDatabaseCountTimeout is the default time allowed to count the rows exactly
+	// before estimating them from the table statistics. Zero always counts them exactly
+	DefaultDatabaseCountTimeout = 0 * time.Second
+
+	// DefaultDatabasePingAttempts is the default number of times the database is pinged at startup
+	DefaultDatabasePingAttempts = 5
+
+	// DefaultDatabasePingBackoff is the default wait before the second startup ping,
+	// it doubles after each failed attempt
+	DefaultDatabasePingBackoff = 1 * time.Second
+
+	// DefaultDatabasePingDeadline is the default time allowed for all the startup pings
+	DefaultDatabasePingDeadline = 60 * time.Second
+
+	// DefaultDatabaseTxMaxRetries is the default number of times a transaction failing
+	// with a serialization failure or a deadlock is retried
+	DefaultDatabaseTxMaxRetries = 3
+
+	// DefaultDatabaseTxRetryBackoff is the default wait before the first retry
+	// of a transaction, it doubles after each retry
+	DefaultDatabaseTxRetryBackoff = 50 * time.Millisecond
+
 	DefaultDatabaseMaxIdleConns = 10
 	DefaultDatabaseMaxOpenConns = 100
 
 	DefaultDatabaseConnMaxIdleTime = 30 * time.Minute
 	DefaultDatabaseConnMaxLifetime = 15 * time.Second
 
+	// DefaultDatabaseConnMaxLifetimeJitter is the default percent the max lifetime
+	// of each connection is randomly shifted by, so they don't expire together
+	DefaultDatabaseConnMaxLifetimeJitter = 0
+
 	DefaultDatabaseMigrationEnable = false
+
+	// DefaultDatabaseReadOnly is the default value for the read-only mode,
+	// where the mutating endpoints respond 503 and the reads continue to work
+	DefaultDatabaseReadOnly = false
+
+	// DefaultDatabaseRequestApplicationName is the default value for setting the
+	// application_name of the connection to the request ID running the queries
+	DefaultDatabaseRequestApplicationName = false
 )
 
 type DatabaseConfig struct {
@@ -83,16 +144,32 @@ type DatabaseConfig struct {
 	Port     Field[int]
 	TimeZone Field[string]
 
+	UsernameFile Field[string]
+	PasswordFile Field[string]
+
 	MaxIdleConns Field[int]
 	MaxOpenConns Field[int]
 
 	MaxQueryTimeout Field[time.Duration]
 	MaxPingTimeout  Field[time.Duration]
+	CountTimeout    Field[time.Duration]
+
+	PingAttempts Field[int]
+	PingBackoff  Field[time.Duration]
+	PingDeadline Field[time.Duration]
+
+	TxMaxRetries   Field[int]
+	TxRetryBackoff Field[time.Duration]
 
 	ConnMaxIdleTime Field[time.Duration]
 	ConnMaxLifetime Field[time.Duration]
 
+	ConnMaxLifetimeJitter Field[int]
+
 	MigrationEnable Field[bool]
+	ReadOnly        Field[bool]
+
+	RequestApplicationName Field[bool]
 }
 
 func NewDatabaseConfig() *DatabaseConfig {
@@ -101,13 +178,24 @@ func NewDatabaseConfig() *DatabaseConfig {
 		Address:  NewField("database.address", "DATABASE_ADDRESS", "Database IP Address or Hostname", DefaultDatabaseAddress),
 		Port:     NewField("database.port", "DATABASE_PORT", "Database Port", DefaultDatabasePort),
 		Username: NewField("database.username", "DATABASE_USERNAME", "Database Username", DefaultDatabaseUsername),
-		Password: NewField("database.password", "DATABASE_PASSWORD", "Database Password", DefaultDatabasePassword),
+		Password: NewSecretField("database.password", "DATABASE_PASSWORD", "Database Password", DefaultDatabasePassword),
 		Name:     NewField("database.name", "DATABASE_NAME", "Database Name", DefaultDatabaseName),
 		SSLMode:  NewField("database.ssl.mode", "DATABASE_SSL_MODE", "Database SSL Mode. Possible values ["+ValidSSLModes+"]", DefaultDatabaseSSLMode),
 		TimeZone: NewField("database.time.zone", "DATABASE_TIME_ZONE", "Database Time Zone", DefaultDatabaseTimeZone),
 
+		UsernameFile: NewField("database.username.file", "DATABASE_USERNAME_FILE", "Database Username file, overrides the Database Username and is re-read on SIGHUP", DefaultDatabaseUsernameFile),
+		PasswordFile: NewField("database.password.file", "DATABASE_PASSWORD_FILE", "Database Password file, overrides the Database Password and is re-read on SIGHUP", DefaultDatabasePasswordFile),
+
 		MaxPingTimeout:  NewField("database.max.ping.timeout", "DATABASE_MAX_PING_TIMEOUT", "Database Max Ping Timeout", DefaultDatabaseMaxPingTimeout),
 		MaxQueryTimeout: NewField("database.max.query.timeout", "DATABASE_MAX_QUERY_TIMEOUT", "Database Max Query Timeout", DefaultDatabaseMaxQueryTimeout),
+		CountTimeout:    NewField("database.count.timeout", "DATABASE_COUNT_TIMEOUT", "Database timeout of the exact counts before estimating them, 0 to always count exactly", DefaultDatabaseCountTimeout),
+
+		PingAttempts: NewField("database.ping.attempts", "DATABASE_PING_ATTEMPTS", "Database startup ping attempts", DefaultDatabasePingAttempts),
+		PingBackoff:  NewField("database.ping.backoff", "DATABASE_PING_BACKOFF", "Database startup ping backoff, doubled after each failed attempt", DefaultDatabasePingBackoff),
+		PingDeadline: NewField("database.ping.deadline", "DATABASE_PING_DEADLINE", "Database startup ping deadline for all the attempts", DefaultDatabasePingDeadline),
+
+		TxMaxRetries:   NewField("database.tx.max.retries", "DATABASE_TX_MAX_RETRIES", "Database transaction retries on serialization failures and deadlocks", DefaultDatabaseTxMaxRetries),
+		TxRetryBackoff: NewField("database.tx.retry.backoff", "DATABASE_TX_RETRY_BACKOFF", "Database transaction retry backoff, doubled after each retry", DefaultDatabaseTxRetryBackoff),
 
 		MaxIdleConns: NewField("database.max.idle.conns", "DATABASE_MAX_IDLE_CONNS", "Database Max Idle Connections", DefaultDatabaseMaxIdleConns),
 		MaxOpenConns: NewField("database.max.open.conns", "DATABASE_MAX_OPEN_CONNS", "Database Max Open Connections", DefaultDatabaseMaxOpenConns),
@@ -115,7 +203,12 @@ func NewDatabaseConfig() *DatabaseConfig {
 		ConnMaxIdleTime: NewField("database.conn.max.idle.time", "DATABASE_CONN_MAX_IDLE_TIME", "Database Connection Max Idle Time", DefaultDatabaseConnMaxIdleTime),
 		ConnMaxLifetime: NewField("database.conn.max.lifetime", "DATABASE_CONN_MAX_LIFETIME", "Database Connection Max Lifetime", DefaultDatabaseConnMaxLifetime),
 
+		ConnMaxLifetimeJitter: NewField("database.conn.max.lifetime.jitter", "DATABASE_CONN_MAX_LIFETIME_JITTER", "Database Connection Max Lifetime jitter, in percent, to spread the connection expirations", DefaultDatabaseConnMaxLifetimeJitter),
+
 		MigrationEnable: NewField("database.migration.enable", "DATABASE_MIGRATION_ENABLE", "Database migration is enables?", DefaultDatabaseMigrationEnable),
+		ReadOnly:        NewField("database.read.only", "DATABASE_READ_ONLY", "Database read-only mode, the mutating endpoints respond 503", DefaultDatabaseReadOnly),
+
+		RequestApplicationName: NewField("database.request.application.name", "DATABASE_REQUEST_APPLICATION_NAME", "Set the PostgreSQL application_name to the request ID running the queries", DefaultDatabaseRequestApplicationName),
 	}
 }
 
@@ -131,16 +224,70 @@ func (c *DatabaseConfig) ParseEnvVars() {
 	c.SSLMode.Value = GetEnv(c.SSLMode.EnVarName, c.SSLMode.Value)
 	c.TimeZone.Value = GetEnv(c.TimeZone.EnVarName, c.TimeZone.Value)
 
+	c.UsernameFile.Value = GetEnv(c.UsernameFile.EnVarName, c.UsernameFile.Value)
+	c.PasswordFile.Value = GetEnv(c.PasswordFile.EnVarName, c.PasswordFile.Value)
+
 	c.MaxPingTimeout.Value = GetEnv(c.MaxPingTimeout.EnVarName, c.MaxPingTimeout.Value)
 	c.MaxQueryTimeout.Value = GetEnv(c.MaxQueryTimeout.EnVarName, c.MaxQueryTimeout.Value)
+	c.CountTimeout.Value = GetEnv(c.CountTimeout.EnVarName, c.CountTimeout.Value)
+
+	c.PingAttempts.Value = GetEnv(c.PingAttempts.EnVarName, c.PingAttempts.Value)
+	c.PingBackoff.Value = GetEnv(c.PingBackoff.EnVarName, c.PingBackoff.Value)
+	c.PingDeadline.Value = GetEnv(c.PingDeadline.EnVarName, c.PingDeadline.Value)
+
+	c.TxMaxRetries.Value = GetEnv(c.TxMaxRetries.EnVarName, c.TxMaxRetries.Value)
+	c.TxRetryBackoff.Value = GetEnv(c.TxRetryBackoff.EnVarName, c.TxRetryBackoff.Value)
 
 	c.MaxIdleConns.Value = GetEnv(c.MaxIdleConns.EnVarName, c.MaxIdleConns.Value)
 	c.MaxOpenConns.Value = GetEnv(c.MaxOpenConns.EnVarName, c.MaxOpenConns.Value)
 
 	c.ConnMaxIdleTime.Value = GetEnv(c.ConnMaxIdleTime.EnVarName, c.ConnMaxIdleTime.Value)
 	c.ConnMaxLifetime.Value = GetEnv(c.ConnMaxLifetime.EnVarName, c.ConnMaxLifetime.Value)
+	c.ConnMaxLifetimeJitter.Value = GetEnv(c.ConnMaxLifetimeJitter.EnVarName, c.ConnMaxLifetimeJitter.Value)
 
 	c.MigrationEnable.Value = GetEnv(c.MigrationEnable.EnVarName, c.MigrationEnable.Value)
+	c.ReadOnly.Value = GetEnv(c.ReadOnly.EnVarName, c.ReadOnly.Value)
+	c.RequestApplicationName.Value = GetEnv(c.RequestApplicationName.EnVarName, c.RequestApplicationName.Value)
+}
+
+// LoadCredentialFiles overrides the username and the password with the content
+// of their files when set, so the secrets mounted as files are not passed
+// as flags or environment variables
+func (c *DatabaseConfig) LoadCredentialFiles() error {
+	if c.UsernameFile.Value != "" {
+		username, err := ReadSecretFile(c.UsernameFile.Value)
+		if err != nil {
+			return fmt.Errorf("reading database username file: %w", err)
+		}
+
+		c.Username.Value = username
+	}
+
+	if c.PasswordFile.Value != "" {
+		password, err := ReadSecretFile(c.PasswordFile.Value)
+		if err != nil {
+			return fmt.Errorf("reading database password file: %w", err)
+		}
+
+		c.Password.Value = password
+	}
+
+	return nil
+}
+
+// FileSources returns the credential files overriding the username and the password
+func (c *DatabaseConfig) FileSources() map[string]string {
+	sources := make(map[string]string)
+
+	if c.UsernameFile.Value != "" {
+		sources[c.Username.FlagName] = c.UsernameFile.Value
+	}
+
+	if c.PasswordFile.Value != "" {
+		sources[c.Password.FlagName] = c.PasswordFile.Value
+	}
+
+	return sources
 }
 
 // Validate validates the database configuration values
@@ -189,6 +336,30 @@ func (c *DatabaseConfig) Validate() error {
 		return ErrDBInvalidMaxQueryTimeout
 	}
 
+	if c.CountTimeout.Value < 0 || c.CountTimeout.Value > c.MaxQueryTimeout.Value {
+		return ErrDBInvalidCountTimeout
+	}
+
+	if c.PingAttempts.Value < 1 || c.PingAttempts.Value > 100 {
+		return ErrDBInvalidPingAttempts
+	}
+
+	if c.PingBackoff.Value < 0 || c.PingBackoff.Value > 60*time.Second {
+		return ErrDBInvalidPingBackoff
+	}
+
+	if c.PingDeadline.Value < 1*time.Second || c.PingDeadline.Value > 600*time.Second {
+		return ErrDBInvalidPingDeadline
+	}
+
+	if c.TxMaxRetries.Value < 0 || c.TxMaxRetries.Value > 10 {
+		return ErrDBInvalidTxMaxRetries
+	}
+
+	if c.TxRetryBackoff.Value < 0 || c.TxRetryBackoff.Value > 5*time.Second {
+		return ErrDBInvalidTxRetryBackoff
+	}
+
 	if c.ConnMaxIdleTime.Value < 1*time.Second || c.ConnMaxIdleTime.Value > 600*time.Minute {
 		return ErrInvalidConnMaxIdleTime
 	}
@@ -197,5 +368,9 @@ func (c *DatabaseConfig) Validate() error {
 		return ErrInvalidConnMaxLifetime
 	}
 
+	if c.ConnMaxLifetimeJitter.Value < 0 || c.ConnMaxLifetimeJitter.Value > 50 {
+		return ErrInvalidConnMaxLifetimeJitter
+	}
+
 	return nil
 }
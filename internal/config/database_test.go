@@ -0,0 +1,81 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDatabaseConfig_LoadCredentialFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cr3t-from-file\n"), 0o600); err != nil {
+		t.Fatalf("could not write password file: %v", err)
+	}
+
+	usernameFile := filepath.Join(dir, "username")
+	if err := os.WriteFile(usernameFile, []byte("file-user"), 0o600); err != nil {
+		t.Fatalf("could not write username file: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		usernameFile string
+		passwordFile string
+		wantUsername string
+		wantPassword string
+		wantErr      bool
+	}{
+		{
+			name:         "no files keep the inline values",
+			wantUsername: "inline-user",
+			wantPassword: "inline-password",
+		},
+		{
+			name:         "password file overrides the inline password",
+			passwordFile: passwordFile,
+			wantUsername: "inline-user",
+			wantPassword: "s3cr3t-from-file",
+		},
+		{
+			name:         "both files override the inline values",
+			usernameFile: usernameFile,
+			passwordFile: passwordFile,
+			wantUsername: "file-user",
+			wantPassword: "s3cr3t-from-file",
+		},
+		{
+			name:         "missing file",
+			passwordFile: filepath.Join(dir, "missing"),
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewDatabaseConfig()
+			c.Username.Value = "inline-user"
+			c.Password.Value = "inline-password"
+			c.UsernameFile.Value = tc.usernameFile
+			c.PasswordFile.Value = tc.passwordFile
+
+			err := c.LoadCredentialFiles()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if tc.wantErr {
+				return
+			}
+
+			if c.Username.Value != tc.wantUsername {
+				t.Errorf("expected username %q, got %q", tc.wantUsername, c.Username.Value)
+			}
+
+			if c.Password.Value != tc.wantPassword {
+				t.Errorf("expected password %q, got %q", tc.wantPassword, c.Password.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Sources of the configuration values, by increasing precedence:
+// the flags are overridden by the environment variables, set directly
+// or from the .env file, which are overridden by the files of the secrets.
+const (
+	SourceDefault = "default"
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+)
+
+// redactedValue replaces the values of the secret fields in the dump.
+const redactedValue = "[REDACTED]"
+
+// envFileVars are the environment variables set from the .env file.
+var envFileVars = make(map[string]struct{})
+
+// FileSourcer is satisfied by any configuration struct
+// that overrides values with the content of files.
+type FileSourcer interface {
+	// FileSources returns the files read, by the flag name of the field they override
+	FileSources() map[string]string
+}
+
+// DumpField is a configuration field with its effective value and its source.
+type DumpField struct {
+	Name   string
+	EnVar  string
+	Value  string
+	Source string
+}
+
+// DumpFields returns the fields of the configuration structs with their effective value
+// and where it came from, with the values of the secret fields redacted. The flags set in the command line
+// are taken from flagSet.
+func DumpFields(flagSet *flag.FlagSet, configs ...any) []DumpField {
+	setFlags := make(map[string]struct{})
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = struct{}{}
+	})
+
+	var fields []DumpField
+	for _, config := range configs {
+		var fileSources map[string]string
+		if fs, ok := config.(FileSourcer); ok {
+			fileSources = fs.FileSources()
+		}
+
+		v := reflect.ValueOf(config).Elem()
+		for i := range v.NumField() {
+			field, ok := dumpField(v.Field(i))
+			if !ok {
+				continue
+			}
+
+			switch _, envSet := os.LookupEnv(field.EnVar); {
+			case fileSources[field.Name] != "":
+				field.Source = SourceFile
+			case envSet:
+				field.Source = SourceEnv
+				if _, ok := envFileVars[field.EnVar]; ok {
+					field.Source = SourceFile
+				}
+			default:
+				field.Source = SourceDefault
+				if _, ok := setFlags[field.Name]; ok {
+					field.Source = SourceFlag
+				}
+			}
+
+			fields = append(fields, field)
+		}
+	}
+
+	return fields
+}
+
+// Dump writes the fields of the configuration structs as a table,
+// with their effective value and where it came from.
+func Dump(w io.Writer, flagSet *flag.FlagSet, configs ...any) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "NAME\tENV\tVALUE\tSOURCE")
+	for _, field := range DumpFields(flagSet, configs...) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", field.Name, field.EnVar, field.Value, field.Source)
+	}
+
+	return tw.Flush()
+}
+
+// dumpField returns the name, environment variable and value of v
+// when it is a Field, and false otherwise. The value of a secret Field is redacted.
+func dumpField(v reflect.Value) (DumpField, bool) {
+	if v.Kind() != reflect.Struct || !strings.HasPrefix(v.Type().Name(), "Field[") {
+		return DumpField{}, false
+	}
+
+	value := v.FieldByName("Value")
+
+	// the values like FileVar only implement fmt.Stringer on their pointer
+	var s string
+	if stringer, ok := value.Addr().Interface().(fmt.Stringer); ok {
+		s = stringer.String()
+	} else {
+		s = fmt.Sprint(value.Interface())
+	}
+
+	if v.FieldByName("Secret").Bool() && s != "" {
+		s = redactedValue
+	}
+
+	return DumpField{
+		Name:  v.FieldByName("FlagName").String(),
+		EnVar: v.FieldByName("EnVarName").String(),
+		Value: s,
+	}, true
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpFields(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cr3t-from-file"), 0o600); err != nil {
+		t.Fatalf("could not write password file: %v", err)
+	}
+
+	t.Setenv("SERVER_PORT", "9090")
+	t.Setenv("DATABASE_PASSWORD_FILE", passwordFile)
+
+	httpConfig := NewHTTPServerConfig()
+	dbConfig := NewDatabaseConfig()
+
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.IntVar(&httpConfig.Port.Value, httpConfig.Port.FlagName, DefaultHTTPServerPort, httpConfig.Port.FlagDescription)
+	flagSet.StringVar(&httpConfig.Address.Value, httpConfig.Address.FlagName, DefaultHTTPServerAddress, httpConfig.Address.FlagDescription)
+	flagSet.DurationVar(&httpConfig.ShutdownTimeout.Value, httpConfig.ShutdownTimeout.FlagName, DefaultHTTPServerShutdownTimeout, httpConfig.ShutdownTimeout.FlagDescription)
+
+	// the environment overrides the port set by the flag
+	if err := flagSet.Parse([]string{"-http.server.port=8081", "-http.server.address=0.0.0.0"}); err != nil {
+		t.Fatalf("could not parse flags: %v", err)
+	}
+
+	ParseEnvVars(httpConfig, dbConfig)
+	if err := dbConfig.LoadCredentialFiles(); err != nil {
+		t.Fatalf("could not load credential files: %v", err)
+	}
+
+	fields := make(map[string]DumpField)
+	for _, field := range DumpFields(flagSet, httpConfig, dbConfig) {
+		fields[field.Name] = field
+	}
+
+	tests := []struct {
+		name   string
+		value  string
+		source string
+	}{
+		{name: "http.server.port", value: "9090", source: SourceEnv},
+		{name: "http.server.address", value: "0.0.0.0", source: SourceFlag},
+		{name: "http.server.shutdown.timeout", value: DefaultHTTPServerShutdownTimeout.String(), source: SourceDefault},
+		{name: "database.password", value: redactedValue, source: SourceFile},
+		{name: "database.password.file", value: passwordFile, source: SourceEnv},
+		// only the fields marked as secret are redacted, whatever their name
+		{name: "http.server.pagination.token.max.length", value: "128", source: SourceDefault},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			field, ok := fields[tc.name]
+			if !ok {
+				t.Fatalf("expected field %s in the dump", tc.name)
+			}
+
+			if field.Value != tc.value {
+				t.Errorf("expected value %q, got %q", tc.value, field.Value)
+			}
+
+			if field.Source != tc.source {
+				t.Errorf("expected source %s, got %s", tc.source, field.Source)
+			}
+		})
+	}
+}
@@ -97,6 +97,10 @@ type Field[T any] struct {
 
 	// Value is the value of the configuration item
 	Value T
+
+	// Secret marks the configuration items holding a secret,
+	// redacted when the configuration is dumped
+	Secret bool
 }
 
 // NewField creates a new configuration field
@@ -114,6 +118,14 @@ func NewField[T any](flagName string, enVarName string, flagDescription string,
 	return ret
 }
 
+// NewSecretField creates a new configuration field holding a secret
+func NewSecretField[T any](flagName string, enVarName string, flagDescription string, value T) Field[T] {
+	ret := NewField(flagName, enVarName, flagDescription, value)
+	ret.Secret = true
+
+	return ret
+}
+
 // GetEnv retrieves the value of an environment variable or returns a default value
 func GetEnv[T any](key string, defaultValue T) T {
 	if value, exists := os.LookupEnv(key); exists {
@@ -164,6 +176,17 @@ func GetEnv[T any](key string, defaultValue T) T {
 	return defaultValue
 }
 
+// ReadSecretFile reads a secret from a file, like the ones mounted by the secret
+// managers, without the trailing newline
+func ReadSecretFile(name string) (string, error) {
+	content, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
 // SetEnvVarFromFile loads all .env files in the current current working directory and sets the key-value pairs in the environment
 // if there are multiple .env files, it returns an error
 func SetEnvVarFromFile() error {
@@ -217,6 +240,7 @@ func SetEnvVarFromFile() error {
 			// Set the environment variable
 			slog.Debug("setting environment variable", "key", key, "value", value)
 			os.Setenv(key, value)
+			envFileVars[key] = struct{}{}
 		}
 
 		if err := scanner.Err(); err != nil {
@@ -2,8 +2,10 @@ package config
 
 import (
 	"errors"
+	"mime"
 	"net"
 	"os"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -13,27 +15,163 @@ var (
 	ErrHTTPServerInvalidConfigAddress            = errors.New("invalid server address, must not be empty and a valid IP Address or Hostname")
 	ErrHTTPServerInvalidConfigPort               = errors.New("invalid server port, must be between 1 and 65535")
 	ErrHTTPServerInvalidConfigShutdownTimeout    = errors.New("invalid server shutdown timeout, must be between 1s and 600s")
+	ErrHTTPServerInvalidConfigStartupTimeout     = errors.New("invalid server startup timeout, must be between 0s and 3600s")
+	ErrHTTPServerInvalidConfigWriteTimeout       = errors.New("invalid server write timeout, must be between 0s and 600s")
+	ErrHTTPServerInvalidConfigRequestTimeout     = errors.New("invalid server request timeout, must be between 0s and 600s")
+	ErrHTTPServerInvalidConfigSlowRequest        = errors.New("invalid server slow request threshold, must be between 0s and 600s")
+	ErrHTTPServerInvalidConfigMaxHeaderBytes     = errors.New("invalid server max header bytes, must be between 1024 and 16777216 bytes")
+	ErrHTTPServerInvalidConfigTLSProfile         = errors.New("invalid TLS profile. Must be one of [" + ValidHTTPServerTLSProfiles + "]")
+	ErrHTTPServerInvalidConfigJSONKeyCasing      = errors.New("invalid JSON key casing. Must be one of [" + ValidHTTPServerJSONKeyCasings + "]")
+	ErrHTTPServerInvalidConfigJSONKeyCasingBody  = errors.New("invalid JSON key casing max body bytes, must be between 1024 and 67108864 bytes")
+	ErrHTTPServerInvalidConfigTLSVersion         = errors.New("invalid TLS version. Must be empty or one of [" + ValidHTTPServerTLSVersions + "], with the min version not above the max version")
+	ErrHTTPServerInvalidConfigSortDirection      = errors.New("invalid sort default direction. Must be empty or one of [" + ValidHTTPServerSortDirections + "]")
+	ErrHTTPServerInvalidConfigSortNulls          = errors.New("invalid sort default nulls. Must be empty or one of [" + ValidHTTPServerSortNulls + "]")
+	ErrHTTPServerInvalidConfigTokenMaxLength     = errors.New("invalid pagination token max length, must be between 80 and 4096 characters")
+	ErrHTTPServerInvalidConfigFilterMaxPreds     = errors.New("invalid filter max predicates, must be between 0 and 1000")
+	ErrHTTPServerInvalidConfigAPIVersionHeader   = errors.New("invalid API version header, must be empty or a header name of letters, digits and dashes")
+	ErrHTTPServerInvalidConfigSwaggerCacheMaxAge = errors.New("invalid swagger cache max age, must be between 0s and 168h")
+	ErrHTTPServerInvalidConfigReadyTimeout       = errors.New("invalid ready check timeout, must be between 100ms and 30s")
+	ErrHTTPServerInvalidConfigBodyLoggingMaxSize = errors.New("invalid body logging max size, must be between 1 and 1048576 bytes")
 	ErrHTTPServerInvalidConfigCorsAllowedOrigins = errors.New("invalid CORS allowed origins. Must not be empty")
 	ErrHTTPServerInvalidConfigCorsAllowedMethods = errors.New("invalid CORS allowed methods. Must be one of [" + ValidHTTPServerCorsAllowedMethods + "]")
 	ErrHTTPServerInvalidConfigCorsAllowedHeaders = errors.New("invalid CORS allowed headers. Must be at least 2 characters long")
+	ErrHTTPServerInvalidConfigCorsRouteGroups    = errors.New("invalid CORS route groups. Must be a semicolon separated list of /prefix=origin[,origin]")
+	ErrHTTPServerInvalidConfigContentTypes       = errors.New("invalid content types. Must be a comma separated list of media types, like application/json")
+	ErrHTTPServerInvalidConfigContentTypeGroups  = errors.New("invalid content type route groups. Must be a semicolon separated list of /prefix=media/type[,media/type]")
 )
 
+// headerNameRegexp matches the valid names of the configured response headers.
+var headerNameRegexp = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+
 const (
 	// DefaultHTTPServerShutdownTimeout is the default time to wait for the server to shutdown
 	DefaultHTTPServerShutdownTimeout = 5 * time.Second
 
+	// DefaultHTTPServerStartupTimeout is the default time allowed for the whole initialization,
+	// including the database pings and migrations, before exiting. Zero disables the deadline
+	DefaultHTTPServerStartupTimeout = 2 * time.Minute
+
+	// DefaultHTTPServerWriteTimeout is the default time allowed to write a response.
+	// Zero disables the write deadline
+	DefaultHTTPServerWriteTimeout = 0 * time.Second
+
+	// DefaultHTTPServerRequestTimeout is the default time allowed to handle a request,
+	// after which the server responds 504 Gateway Timeout. Zero disables it
+	DefaultHTTPServerRequestTimeout = 0 * time.Second
+
+	// DefaultHTTPServerRequestTimeoutSkipRoutes is the default comma separated list of
+	// path prefixes of the long-lived streams exempted from the request timeout
+	DefaultHTTPServerRequestTimeoutSkipRoutes = "/events"
+
+	// DefaultHTTPServerSlowRequestThreshold is the default time after which the handling
+	// of a request is logged at warn level as slow. Zero disables it
+	DefaultHTTPServerSlowRequestThreshold = 0 * time.Second
+
 	// DefaultHTTPServerAddress is the default address for the server
 	DefaultHTTPServerAddress = "localhost"
 
 	// DefaultHTTPServerPort is the default port for the server
 	DefaultHTTPServerPort = 8080
 
+	// DefaultHTTPServerMaxHeaderBytes is the default maximum size in bytes of the request headers.
+	// Requests with bigger headers are rejected with 431 Request Header Fields Too Large
+	DefaultHTTPServerMaxHeaderBytes = 1 << 20
+
 	// DefaultHTTPServerTLSEnabled is the default value for enabling TLS
 	DefaultHTTPServerTLSEnabled = false
 
+	// DefaultHTTPServerTLSProfile is the default TLS profile. modern only accepts TLS 1.3,
+	// intermediate also accepts TLS 1.2 with the forward secret AEAD cipher suites
+	DefaultHTTPServerTLSProfile = HTTPServerTLSProfileIntermediate
+
+	// DefaultHTTPServerTLSMinVersion and DefaultHTTPServerTLSMaxVersion are the default
+	// bounds of the accepted TLS versions, 1.2 or 1.3. Empty means the bound of the TLS profile
+	DefaultHTTPServerTLSMinVersion = ""
+	DefaultHTTPServerTLSMaxVersion = ""
+
+	// DefaultHTTPServerTLSOCSPStaplingEnabled is the default value for enabling the OCSP stapling.
+	// The staple is requested to the OCSP responder of the certificate, so it requires network access to it
+	DefaultHTTPServerTLSOCSPStaplingEnabled = false
+
 	// DefaultHTTPServerPprofEnabled is the default value for enabling pprof
 	DefaultHTTPServerPprofEnabled = false
 
+	// DefaultHTTPServerAPIVersionHeader is the default response header with
+	// the version of the service. Empty disables the header
+	DefaultHTTPServerAPIVersionHeader = "X-API-Version"
+
+	// DefaultHTTPServerSwaggerCacheMaxAge is the default time the browsers cache
+	// the swagger UI and documentation. Zero makes them revalidate on every use
+	DefaultHTTPServerSwaggerCacheMaxAge = 1 * time.Hour
+
+	// DefaultHTTPServerReadyTimeout is the default time allowed to probe
+	// each dependency of the service in the readiness checks
+	DefaultHTTPServerReadyTimeout = 2 * time.Second
+
+	// DefaultHTTPServerReadyOptionalDependencies is the default comma separated list of
+	// the dependencies that don't make the service unready when down. Empty means all are required
+	DefaultHTTPServerReadyOptionalDependencies = ""
+
+	// DefaultHTTPServerRecoverEnabled is the default value for responding 500
+	// to the requests whose handler panics. If disabled, their connection is dropped
+	DefaultHTTPServerRecoverEnabled = true
+
+	// DefaultHTTPServerJSONKeyCasing is the default casing of the JSON keys, like first_name
+	// in snake case or firstName in camel case
+	DefaultHTTPServerJSONKeyCasing = HTTPServerJSONKeyCasingSnake
+
+	// DefaultHTTPServerJSONKeyCasingNegotiation is the default value for letting the clients
+	// ask for the other casing with the casing parameter of the Accept header,
+	// like application/json; casing=camel
+	DefaultHTTPServerJSONKeyCasingNegotiation = false
+
+	// DefaultHTTPServerJSONKeyCasingMaxBodyBytes is the default maximum size of the
+	// request bodies whose keys are converted to snake case, the bigger ones respond 413
+	DefaultHTTPServerJSONKeyCasingMaxBodyBytes = 1 << 20
+
+	// DefaultHTTPServerReadOnly is the default value for the read-only mode, where only
+	// the GET, HEAD and OPTIONS requests are served and the others respond 405
+	DefaultHTTPServerReadOnly = false
+
+	// DefaultHTTPServerPrettyJSONEnabled is the default value for enabling
+	// the pretty=true query parameter to indent the JSON responses
+	DefaultHTTPServerPrettyJSONEnabled = false
+
+	// DefaultHTTPServerFilterRejectLeadingWildcard is the default value for rejecting
+	// filters with a LIKE value starting with a wildcard. If disabled, these are only logged
+	DefaultHTTPServerFilterRejectLeadingWildcard = false
+
+	// DefaultHTTPServerFilterMaxPredicates is the default maximum number of predicates
+	// in a filter, to bound the cost of planning the query. Zero means no limit
+	DefaultHTTPServerFilterMaxPredicates = 20
+
+	// DefaultHTTPServerTokenMaxLength is the default maximum length of the pagination
+	// tokens, the longer ones respond 400 before being decoded
+	DefaultHTTPServerTokenMaxLength = 128
+
+	// DefaultHTTPServerSortDefaultDirection is the default direction of the sort columns
+	// without one. Empty means every sort column must have a direction
+	DefaultHTTPServerSortDefaultDirection = ""
+
+	// DefaultHTTPServerSortDefaultNulls is the default placement of the null values of the sort
+	// columns without one, FIRST or LAST. Empty means the database default placement
+	DefaultHTTPServerSortDefaultNulls = ""
+
+	// DefaultHTTPServerBodyLoggingEnabled is the default value for logging
+	// the request and response bodies at debug level
+	DefaultHTTPServerBodyLoggingEnabled = false
+
+	// DefaultHTTPServerBodyLoggingRoutes is the default comma separated list of
+	// path prefixes to log the bodies for. Empty means every route
+	DefaultHTTPServerBodyLoggingRoutes = ""
+
+	// DefaultHTTPServerBodyLoggingRedactKeys is the default comma separated list of
+	// extra JSON keys redacted in the logged bodies. password and tokens are always redacted
+	DefaultHTTPServerBodyLoggingRedactKeys = ""
+
+	// DefaultHTTPServerBodyLoggingMaxSize is the default maximum size in bytes of a logged body
+	DefaultHTTPServerBodyLoggingMaxSize = 4096
+
 	// DefaultHTTPServerCorsEnabled is the default value for enabling CORS
 	// If enabled, the server will use the following values for CORS
 	// - AllowedOrigins: "*"
@@ -52,12 +190,44 @@ const (
 	// DefaultHTTPServerCorsAllowedMethods is the default value for allowed methods
 	DefaultHTTPServerCorsAllowedMethods = "GET, POST, PUT, DELETE, OPTIONS, PATCH, HEAD"
 
+	// DefaultHTTPServerCorsRouteGroups is the default value for the CORS allowed origins per route group.
+	// Could be a semicolon separated list of path prefix and comma separated origins.
+	// Example: "/admin/=https://admin.example.com; /public/=*". Empty means every route uses the allowed origins
+	DefaultHTTPServerCorsRouteGroups = ""
+
+	// DefaultHTTPServerContentTypes is the default comma separated list of the content types
+	// accepted in the request bodies, the others respond 415. Empty accepts any content type,
+	// so the check is opt-in. Example: "application/json"
+	DefaultHTTPServerContentTypes = ""
+
+	// DefaultHTTPServerContentTypeRouteGroups is the default value for the accepted content types per route group.
+	// Could be a semicolon separated list of path prefix and comma separated content types.
+	// Example: "/users/import=text/csv". Empty means every route accepts the content types
+	DefaultHTTPServerContentTypeRouteGroups = ""
+
 	// DefaultHTTPServerCorsAllowedHeaders is the default value for allowed headers
 	DefaultHTTPServerCorsAllowedHeaders = "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token, X-Requested-With, X-Api-Version, Access-Control-Allow-Headers"
 )
 
+// TLS profiles of the server.
+const (
+	HTTPServerTLSProfileModern       = "modern"
+	HTTPServerTLSProfileIntermediate = "intermediate"
+)
+
+// JSON key casings of the requests and responses.
+const (
+	HTTPServerJSONKeyCasingSnake = "snake"
+	HTTPServerJSONKeyCasingCamel = "camel"
+)
+
 const (
 	ValidHTTPServerCorsAllowedMethods = "GET|POST|PUT|DELETE|OPTIONS|PATCH|HEAD"
+	ValidHTTPServerTLSProfiles        = HTTPServerTLSProfileModern + "|" + HTTPServerTLSProfileIntermediate
+	ValidHTTPServerJSONKeyCasings     = HTTPServerJSONKeyCasingSnake + "|" + HTTPServerJSONKeyCasingCamel
+	ValidHTTPServerTLSVersions        = "1.2|1.3"
+	ValidHTTPServerSortDirections     = "ASC|DESC"
+	ValidHTTPServerSortNulls          = "FIRST|LAST"
 )
 
 var (
@@ -72,18 +242,50 @@ var (
 
 // HTTPServerConfig is the configuration for the server
 type HTTPServerConfig struct {
-	Address              Field[string]
-	Port                 Field[int]
-	ShutdownTimeout      Field[time.Duration]
-	PrivateKeyFile       Field[FileVar]
-	CertificateFile      Field[FileVar]
-	CorsAllowedOrigins   Field[string]
-	CorsAllowedMethods   Field[string]
-	CorsAllowedHeaders   Field[string]
-	TLSEnabled           Field[bool]
-	PprofEnabled         Field[bool]
-	CorsEnabled          Field[bool]
-	CorsAllowCredentials Field[bool]
+	Address               Field[string]
+	Port                  Field[int]
+	ShutdownTimeout       Field[time.Duration]
+	StartupTimeout        Field[time.Duration]
+	WriteTimeout          Field[time.Duration]
+	RequestTimeout        Field[time.Duration]
+	RequestTimeoutSkip    Field[string]
+	SlowRequestThreshold  Field[time.Duration]
+	MaxHeaderBytes        Field[int]
+	PrivateKeyFile        Field[FileVar]
+	CertificateFile       Field[FileVar]
+	CorsAllowedOrigins    Field[string]
+	CorsAllowedMethods    Field[string]
+	CorsAllowedHeaders    Field[string]
+	CorsRouteGroups       Field[string]
+	ContentTypes          Field[string]
+	ContentTypeGroups     Field[string]
+	TLSEnabled            Field[bool]
+	TLSProfile            Field[string]
+	TLSMinVersion         Field[string]
+	TLSMaxVersion         Field[string]
+	TLSOCSPStapling       Field[bool]
+	PprofEnabled          Field[bool]
+	PrettyJSONEnabled     Field[bool]
+	RecoverEnabled        Field[bool]
+	ReadOnly              Field[bool]
+	JSONKeyCasing         Field[string]
+	JSONKeyNegotiation    Field[bool]
+	JSONKeyMaxBodyBytes   Field[int]
+	APIVersionHeader      Field[string]
+	SwaggerCacheMaxAge    Field[time.Duration]
+	ReadyTimeout          Field[time.Duration]
+	ReadyOptionalDeps     Field[string]
+	FilterRejectWildcard  Field[bool]
+	FilterMaxPredicates   Field[int]
+	TokenMaxLength        Field[int]
+	SortDefaultDirection  Field[string]
+	SortDefaultNulls      Field[string]
+	BodyLoggingEnabled    Field[bool]
+	BodyLoggingRoutes     Field[string]
+	BodyLoggingRedactKeys Field[string]
+	BodyLoggingMaxSize    Field[int]
+	CorsEnabled           Field[bool]
+	CorsAllowCredentials  Field[bool]
 }
 
 // NewHTTPServerConfig creates a new server configuration
@@ -92,16 +294,58 @@ func NewHTTPServerConfig() *HTTPServerConfig {
 		Address:         NewField("http.server.address", "SERVER_ADDRESS", "Server IP Address or Hostname", DefaultHTTPServerAddress),
 		Port:            NewField("http.server.port", "SERVER_PORT", "Server Port", DefaultHTTPServerPort),
 		ShutdownTimeout: NewField("http.server.shutdown.timeout", "SERVER_SHUTDOWN_TIMEOUT", "Server Shutdown Timeout", DefaultHTTPServerShutdownTimeout),
+		StartupTimeout:  NewField("http.server.startup.timeout", "SERVER_STARTUP_TIMEOUT", "Server Startup Timeout of the whole initialization, 0 to wait forever", DefaultHTTPServerStartupTimeout),
+		WriteTimeout:    NewField("http.server.write.timeout", "SERVER_WRITE_TIMEOUT", "Server Write Timeout for a response, 0 disables it", DefaultHTTPServerWriteTimeout),
+		RequestTimeout:  NewField("http.server.request.timeout", "SERVER_REQUEST_TIMEOUT", "Server Request Timeout of the handling of a request, responded 504, 0 disables it", DefaultHTTPServerRequestTimeout),
+
+		RequestTimeoutSkip:   NewField("http.server.request.timeout.skip.routes", "SERVER_REQUEST_TIMEOUT_SKIP_ROUTES", "Comma separated path prefixes of the streams exempted from the request timeout", DefaultHTTPServerRequestTimeoutSkipRoutes),
+		SlowRequestThreshold: NewField("http.server.slow.request.threshold", "SERVER_SLOW_REQUEST_THRESHOLD", "Server handling time after which a request is logged as slow, 0 disables it", DefaultHTTPServerSlowRequestThreshold),
+
+		MaxHeaderBytes:  NewField("http.server.max.header.bytes", "SERVER_MAX_HEADER_BYTES", "Server maximum size in bytes of the request headers", DefaultHTTPServerMaxHeaderBytes),
 		PrivateKeyFile:  NewField("http.server.private.key.file", "SERVER_PRIVATE_KEY_FILE", "Server Private Key File", DefaultHTTPServerPrivateKeyFile),
-		CertificateFile: NewField("http.server.certificate.file", "SERVER_CERTIFICATE_FILE", "Server Certificate File", DefaultHTTPServerCertificateFile),
+		CertificateFile: NewField("http.server.certificate.file", "SERVER_CERTIFICATE_FILE", "Server Certificate File, reloaded with the private key when they change and on SIGHUP", DefaultHTTPServerCertificateFile),
 		TLSEnabled:      NewField("http.server.tls.enabled", "SERVER_TLS_ENABLED", "Enable TLS", DefaultHTTPServerTLSEnabled),
+		TLSOCSPStapling: NewField("http.server.tls.ocsp.stapling.enabled", "SERVER_TLS_OCSP_STAPLING_ENABLED", "Enable the OCSP stapling, requires network access to the OCSP responder of the certificate", DefaultHTTPServerTLSOCSPStaplingEnabled),
+		TLSProfile:      NewField("http.server.tls.profile", "SERVER_TLS_PROFILE", "TLS profile, modern for TLS 1.3 only or intermediate to also accept TLS 1.2", DefaultHTTPServerTLSProfile),
+		TLSMinVersion:   NewField("http.server.tls.min.version", "SERVER_TLS_MIN_VERSION", "Minimum TLS version, 1.2 or 1.3. Empty uses the one of the TLS profile", DefaultHTTPServerTLSMinVersion),
+		TLSMaxVersion:   NewField("http.server.tls.max.version", "SERVER_TLS_MAX_VERSION", "Maximum TLS version, 1.2 or 1.3. Empty uses the one of the TLS profile", DefaultHTTPServerTLSMaxVersion),
 		PprofEnabled:    NewField("http.server.pprof.enabled", "SERVER_PPROF_ENABLED", "Enable pprof", DefaultHTTPServerPprofEnabled),
 
+		PrettyJSONEnabled: NewField("http.server.pretty.json.enabled", "SERVER_PRETTY_JSON_ENABLED", "Enable the pretty=true query parameter to indent JSON responses", DefaultHTTPServerPrettyJSONEnabled),
+		RecoverEnabled:    NewField("http.server.recover.enabled", "SERVER_RECOVER_ENABLED", "Respond 500 and log the stack trace when a handler panics, instead of dropping the connection", DefaultHTTPServerRecoverEnabled),
+		ReadOnly:          NewField("http.server.read.only", "SERVER_READ_ONLY", "Server read-only mode, like for a mirror of a read replica, the requests other than GET, HEAD and OPTIONS respond 405", DefaultHTTPServerReadOnly),
+		JSONKeyCasing:     NewField("http.server.json.key.casing", "SERVER_JSON_KEY_CASING", "Casing of the JSON keys of the requests and responses. Possible values ["+ValidHTTPServerJSONKeyCasings+"]", DefaultHTTPServerJSONKeyCasing),
+
+		JSONKeyNegotiation:  NewField("http.server.json.key.casing.negotiation", "SERVER_JSON_KEY_CASING_NEGOTIATION", "Let the clients ask for the casing of the JSON keys with the Accept header, like application/json; casing=camel", DefaultHTTPServerJSONKeyCasingNegotiation),
+		JSONKeyMaxBodyBytes: NewField("http.server.json.key.casing.max.body.bytes", "SERVER_JSON_KEY_CASING_MAX_BODY_BYTES", "Maximum size in bytes of the request bodies whose JSON keys are converted, the bigger ones respond 413", DefaultHTTPServerJSONKeyCasingMaxBodyBytes),
+
+		APIVersionHeader: NewField("http.server.api.version.header", "SERVER_API_VERSION_HEADER", "Response header with the version of the service, empty to disable it", DefaultHTTPServerAPIVersionHeader),
+
+		ReadyTimeout:      NewField("http.server.ready.timeout", "SERVER_READY_TIMEOUT", "Time allowed to probe each dependency in the readiness checks", DefaultHTTPServerReadyTimeout),
+		ReadyOptionalDeps: NewField("http.server.ready.optional.dependencies", "SERVER_READY_OPTIONAL_DEPENDENCIES", "Comma separated dependencies that don't make the service unready when down", DefaultHTTPServerReadyOptionalDependencies),
+
+		SwaggerCacheMaxAge: NewField("http.server.swagger.cache.max.age", "SERVER_SWAGGER_CACHE_MAX_AGE", "Time the browsers cache the swagger UI and documentation, 0 to revalidate on every use", DefaultHTTPServerSwaggerCacheMaxAge),
+
+		FilterRejectWildcard: NewField("http.server.filter.reject.leading.wildcard", "SERVER_FILTER_REJECT_LEADING_WILDCARD", "Reject filters with a LIKE value starting with a wildcard instead of logging a warning", DefaultHTTPServerFilterRejectLeadingWildcard),
+		FilterMaxPredicates:  NewField("http.server.filter.max.predicates", "SERVER_FILTER_MAX_PREDICATES", "Maximum number of predicates in a filter, 0 for no limit", DefaultHTTPServerFilterMaxPredicates),
+		TokenMaxLength:       NewField("http.server.pagination.token.max.length", "SERVER_PAGINATION_TOKEN_MAX_LENGTH", "Maximum length of the next_token and prev_token pagination tokens", DefaultHTTPServerTokenMaxLength),
+		SortDefaultDirection: NewField("http.server.sort.default.direction", "SERVER_SORT_DEFAULT_DIRECTION", "Direction of the sort columns without one, ASC or DESC. Empty requires a direction", DefaultHTTPServerSortDefaultDirection),
+		SortDefaultNulls:     NewField("http.server.sort.default.nulls", "SERVER_SORT_DEFAULT_NULLS", "Placement of the null values of the sort columns without one, FIRST or LAST", DefaultHTTPServerSortDefaultNulls),
+
+		BodyLoggingEnabled:    NewField("http.server.body.logging.enabled", "SERVER_BODY_LOGGING_ENABLED", "Log the request and response bodies at debug level", DefaultHTTPServerBodyLoggingEnabled),
+		BodyLoggingRoutes:     NewField("http.server.body.logging.routes", "SERVER_BODY_LOGGING_ROUTES", "Comma separated path prefixes to log the bodies for, empty for all", DefaultHTTPServerBodyLoggingRoutes),
+		BodyLoggingRedactKeys: NewField("http.server.body.logging.redact.keys", "SERVER_BODY_LOGGING_REDACT_KEYS", "Comma separated extra JSON keys to redact in the logged bodies", DefaultHTTPServerBodyLoggingRedactKeys),
+		BodyLoggingMaxSize:    NewField("http.server.body.logging.max.size", "SERVER_BODY_LOGGING_MAX_SIZE", "Maximum size in bytes of a logged body", DefaultHTTPServerBodyLoggingMaxSize),
+
 		CorsEnabled:          NewField("http.server.cors.enabled", "SERVER_CORS_ENABLED", "Enable CORS", DefaultHTTPServerCorsEnabled),
 		CorsAllowCredentials: NewField("http.server.cors.allow.credentials", "SERVER_CORS_ALLOW_CREDENTIALS", "Allow Credentials for CORS", DefaultHTTPServerCorsAllowCredentials),
 		CorsAllowedOrigins:   NewField("http.server.cors.allowed.origins", "SERVER_CORS_ALLOWED_ORIGINS", "Allowed Origins for CORS", DefaultHTTPServerCorsAllowedOrigins),
 		CorsAllowedMethods:   NewField("http.server.cors.allowed.methods", "SERVER_CORS_ALLOWED_METHODS", "Allowed Methods for CORS", DefaultHTTPServerCorsAllowedMethods),
 		CorsAllowedHeaders:   NewField("http.server.cors.allowed.headers", "SERVER_CORS_ALLOWED_HEADERS", "Allowed Headers for CORS", DefaultHTTPServerCorsAllowedHeaders),
+		CorsRouteGroups:      NewField("http.server.cors.route.groups", "SERVER_CORS_ROUTE_GROUPS", "Allowed Origins for CORS per route group, like /admin/=https://admin.example.com; /public/=*", DefaultHTTPServerCorsRouteGroups),
+
+		ContentTypes:      NewField("http.server.content.types", "SERVER_CONTENT_TYPES", "Content types accepted in the request bodies, empty to accept any", DefaultHTTPServerContentTypes),
+		ContentTypeGroups: NewField("http.server.content.type.route.groups", "SERVER_CONTENT_TYPE_ROUTE_GROUPS", "Content types accepted in the request bodies per route group, like /users/import=text/csv", DefaultHTTPServerContentTypeRouteGroups),
 	}
 }
 
@@ -111,16 +355,48 @@ func (c *HTTPServerConfig) ParseEnvVars() {
 	c.Address.Value = GetEnv(c.Address.EnVarName, c.Address.Value)
 	c.Port.Value = GetEnv(c.Port.EnVarName, c.Port.Value)
 	c.ShutdownTimeout.Value = GetEnv(c.ShutdownTimeout.EnVarName, c.ShutdownTimeout.Value)
+	c.StartupTimeout.Value = GetEnv(c.StartupTimeout.EnVarName, c.StartupTimeout.Value)
+	c.WriteTimeout.Value = GetEnv(c.WriteTimeout.EnVarName, c.WriteTimeout.Value)
+	c.RequestTimeout.Value = GetEnv(c.RequestTimeout.EnVarName, c.RequestTimeout.Value)
+	c.RequestTimeoutSkip.Value = GetEnv(c.RequestTimeoutSkip.EnVarName, c.RequestTimeoutSkip.Value)
+	c.SlowRequestThreshold.Value = GetEnv(c.SlowRequestThreshold.EnVarName, c.SlowRequestThreshold.Value)
+	c.MaxHeaderBytes.Value = GetEnv(c.MaxHeaderBytes.EnVarName, c.MaxHeaderBytes.Value)
 	c.PrivateKeyFile.Value = GetEnv(c.PrivateKeyFile.EnVarName, c.PrivateKeyFile.Value)
 	c.CertificateFile.Value = GetEnv(c.CertificateFile.EnVarName, c.CertificateFile.Value)
 	c.TLSEnabled.Value = GetEnv(c.TLSEnabled.EnVarName, c.TLSEnabled.Value)
+	c.TLSProfile.Value = GetEnv(c.TLSProfile.EnVarName, c.TLSProfile.Value)
+	c.TLSMinVersion.Value = GetEnv(c.TLSMinVersion.EnVarName, c.TLSMinVersion.Value)
+	c.TLSMaxVersion.Value = GetEnv(c.TLSMaxVersion.EnVarName, c.TLSMaxVersion.Value)
+	c.TLSOCSPStapling.Value = GetEnv(c.TLSOCSPStapling.EnVarName, c.TLSOCSPStapling.Value)
 	c.PprofEnabled.Value = GetEnv(c.PprofEnabled.EnVarName, c.PprofEnabled.Value)
+	c.PrettyJSONEnabled.Value = GetEnv(c.PrettyJSONEnabled.EnVarName, c.PrettyJSONEnabled.Value)
+	c.RecoverEnabled.Value = GetEnv(c.RecoverEnabled.EnVarName, c.RecoverEnabled.Value)
+	c.ReadOnly.Value = GetEnv(c.ReadOnly.EnVarName, c.ReadOnly.Value)
+	c.JSONKeyCasing.Value = GetEnv(c.JSONKeyCasing.EnVarName, c.JSONKeyCasing.Value)
+	c.JSONKeyNegotiation.Value = GetEnv(c.JSONKeyNegotiation.EnVarName, c.JSONKeyNegotiation.Value)
+	c.JSONKeyMaxBodyBytes.Value = GetEnv(c.JSONKeyMaxBodyBytes.EnVarName, c.JSONKeyMaxBodyBytes.Value)
+	c.APIVersionHeader.Value = GetEnv(c.APIVersionHeader.EnVarName, c.APIVersionHeader.Value)
+	c.SwaggerCacheMaxAge.Value = GetEnv(c.SwaggerCacheMaxAge.EnVarName, c.SwaggerCacheMaxAge.Value)
+	c.ReadyTimeout.Value = GetEnv(c.ReadyTimeout.EnVarName, c.ReadyTimeout.Value)
+	c.ReadyOptionalDeps.Value = GetEnv(c.ReadyOptionalDeps.EnVarName, c.ReadyOptionalDeps.Value)
+	c.FilterRejectWildcard.Value = GetEnv(c.FilterRejectWildcard.EnVarName, c.FilterRejectWildcard.Value)
+	c.FilterMaxPredicates.Value = GetEnv(c.FilterMaxPredicates.EnVarName, c.FilterMaxPredicates.Value)
+	c.TokenMaxLength.Value = GetEnv(c.TokenMaxLength.EnVarName, c.TokenMaxLength.Value)
+	c.SortDefaultDirection.Value = GetEnv(c.SortDefaultDirection.EnVarName, c.SortDefaultDirection.Value)
+	c.SortDefaultNulls.Value = GetEnv(c.SortDefaultNulls.EnVarName, c.SortDefaultNulls.Value)
+	c.BodyLoggingEnabled.Value = GetEnv(c.BodyLoggingEnabled.EnVarName, c.BodyLoggingEnabled.Value)
+	c.BodyLoggingRoutes.Value = GetEnv(c.BodyLoggingRoutes.EnVarName, c.BodyLoggingRoutes.Value)
+	c.BodyLoggingRedactKeys.Value = GetEnv(c.BodyLoggingRedactKeys.EnVarName, c.BodyLoggingRedactKeys.Value)
+	c.BodyLoggingMaxSize.Value = GetEnv(c.BodyLoggingMaxSize.EnVarName, c.BodyLoggingMaxSize.Value)
 
 	c.CorsEnabled.Value = GetEnv(c.CorsEnabled.EnVarName, c.CorsEnabled.Value)
 	c.CorsAllowCredentials.Value = GetEnv(c.CorsAllowCredentials.EnVarName, c.CorsAllowCredentials.Value)
 	c.CorsAllowedOrigins.Value = GetEnv(c.CorsAllowedOrigins.EnVarName, c.CorsAllowedOrigins.Value)
 	c.CorsAllowedMethods.Value = GetEnv(c.CorsAllowedMethods.EnVarName, c.CorsAllowedMethods.Value)
 	c.CorsAllowedHeaders.Value = GetEnv(c.CorsAllowedHeaders.EnVarName, c.CorsAllowedHeaders.Value)
+	c.CorsRouteGroups.Value = GetEnv(c.CorsRouteGroups.EnVarName, c.CorsRouteGroups.Value)
+	c.ContentTypes.Value = GetEnv(c.ContentTypes.EnVarName, c.ContentTypes.Value)
+	c.ContentTypeGroups.Value = GetEnv(c.ContentTypeGroups.EnVarName, c.ContentTypeGroups.Value)
 }
 
 // Validate validates the server configuration values
@@ -139,6 +415,86 @@ func (c *HTTPServerConfig) Validate() error {
 		return ErrHTTPServerInvalidConfigShutdownTimeout
 	}
 
+	if c.StartupTimeout.Value < 0 || c.StartupTimeout.Value > 3600*time.Second {
+		return ErrHTTPServerInvalidConfigStartupTimeout
+	}
+
+	if c.WriteTimeout.Value < 0 || c.WriteTimeout.Value > 600*time.Second {
+		return ErrHTTPServerInvalidConfigWriteTimeout
+	}
+
+	if c.RequestTimeout.Value < 0 || c.RequestTimeout.Value > 600*time.Second {
+		return ErrHTTPServerInvalidConfigRequestTimeout
+	}
+
+	if c.SlowRequestThreshold.Value < 0 || c.SlowRequestThreshold.Value > 600*time.Second {
+		return ErrHTTPServerInvalidConfigSlowRequest
+	}
+
+	if c.MaxHeaderBytes.Value < 1<<10 || c.MaxHeaderBytes.Value > 16<<20 {
+		return ErrHTTPServerInvalidConfigMaxHeaderBytes
+	}
+
+	if !slices.Contains(strings.Split(ValidHTTPServerJSONKeyCasings, "|"), c.JSONKeyCasing.Value) {
+		return ErrHTTPServerInvalidConfigJSONKeyCasing
+	}
+
+	if c.JSONKeyMaxBodyBytes.Value < 1<<10 || c.JSONKeyMaxBodyBytes.Value > 64<<20 {
+		return ErrHTTPServerInvalidConfigJSONKeyCasingBody
+	}
+
+	if c.TLSEnabled.Value && !slices.Contains(strings.Split(ValidHTTPServerTLSProfiles, "|"), c.TLSProfile.Value) {
+		return ErrHTTPServerInvalidConfigTLSProfile
+	}
+
+	if c.TLSEnabled.Value {
+		validVersions := strings.Split(ValidHTTPServerTLSVersions, "|")
+		for _, version := range []string{c.TLSMinVersion.Value, c.TLSMaxVersion.Value} {
+			if version != "" && !slices.Contains(validVersions, version) {
+				return ErrHTTPServerInvalidConfigTLSVersion
+			}
+		}
+
+		// the versions are compared as strings, fine for 1.2 and 1.3
+		if c.TLSMinVersion.Value != "" && c.TLSMaxVersion.Value != "" && c.TLSMinVersion.Value > c.TLSMaxVersion.Value {
+			return ErrHTTPServerInvalidConfigTLSVersion
+		}
+	}
+
+	if c.APIVersionHeader.Value != "" && !headerNameRegexp.MatchString(c.APIVersionHeader.Value) {
+		return ErrHTTPServerInvalidConfigAPIVersionHeader
+	}
+
+	if c.SwaggerCacheMaxAge.Value < 0 || c.SwaggerCacheMaxAge.Value > 168*time.Hour {
+		return ErrHTTPServerInvalidConfigSwaggerCacheMaxAge
+	}
+
+	if c.ReadyTimeout.Value < 100*time.Millisecond || c.ReadyTimeout.Value > 30*time.Second {
+		return ErrHTTPServerInvalidConfigReadyTimeout
+	}
+
+	if c.FilterMaxPredicates.Value < 0 || c.FilterMaxPredicates.Value > 1000 {
+		return ErrHTTPServerInvalidConfigFilterMaxPreds
+	}
+
+	if c.TokenMaxLength.Value < 80 || c.TokenMaxLength.Value > 4096 {
+		return ErrHTTPServerInvalidConfigTokenMaxLength
+	}
+
+	if c.SortDefaultDirection.Value != "" && !slices.Contains(strings.Split(ValidHTTPServerSortDirections, "|"), c.SortDefaultDirection.Value) {
+		return ErrHTTPServerInvalidConfigSortDirection
+	}
+
+	if c.SortDefaultNulls.Value != "" && !slices.Contains(strings.Split(ValidHTTPServerSortNulls, "|"), c.SortDefaultNulls.Value) {
+		return ErrHTTPServerInvalidConfigSortNulls
+	}
+
+	if c.BodyLoggingEnabled.Value {
+		if c.BodyLoggingMaxSize.Value < 1 || c.BodyLoggingMaxSize.Value > 1<<20 {
+			return ErrHTTPServerInvalidConfigBodyLoggingMaxSize
+		}
+	}
+
 	if c.CorsEnabled.Value {
 		if c.CorsAllowedOrigins.Value == "" {
 			return ErrHTTPServerInvalidConfigCorsAllowedOrigins
@@ -154,7 +510,97 @@ func (c *HTTPServerConfig) Validate() error {
 			return ErrHTTPServerInvalidConfigCorsAllowedHeaders
 		}
 
+		if _, err := ParseCorsRouteGroups(c.CorsRouteGroups.Value); err != nil {
+			return err
+		}
+	}
+
+	if _, err := ParseContentTypes(c.ContentTypes.Value); err != nil {
+		return err
+	}
+
+	if _, err := ParseContentTypeRouteGroups(c.ContentTypeGroups.Value); err != nil {
+		return err
 	}
 
 	return nil
 }
+
+// ParseCorsRouteGroups parses the CORS route groups value into a map of
+// path prefix to allowed origins.
+// Example: "/admin/=https://admin.example.com; /public/=*"
+func ParseCorsRouteGroups(value string) (map[string][]string, error) {
+	return parseRouteGroups(value, ErrHTTPServerInvalidConfigCorsRouteGroups)
+}
+
+// ParseContentTypes parses the comma separated content types value into a list of media types.
+// Example: "application/json, text/csv"
+func ParseContentTypes(value string) ([]string, error) {
+	var contentTypes []string
+
+	for _, contentType := range strings.Split(value, ",") {
+		contentType = strings.TrimSpace(contentType)
+		if contentType == "" {
+			continue
+		}
+
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return nil, ErrHTTPServerInvalidConfigContentTypes
+		}
+
+		contentTypes = append(contentTypes, mediaType)
+	}
+
+	return contentTypes, nil
+}
+
+// ParseContentTypeRouteGroups parses the content type route groups value into a map of
+// path prefix to accepted content types.
+// Example: "/users/import=text/csv; /files/=application/octet-stream"
+func ParseContentTypeRouteGroups(value string) (map[string][]string, error) {
+	groups, err := parseRouteGroups(value, ErrHTTPServerInvalidConfigContentTypeGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	for prefix, contentTypes := range groups {
+		groups[prefix], err = ParseContentTypes(strings.Join(contentTypes, ","))
+		if err != nil {
+			return nil, ErrHTTPServerInvalidConfigContentTypeGroups
+		}
+	}
+
+	return groups, nil
+}
+
+// parseRouteGroups parses a semicolon separated list of path prefix and
+// comma separated values into a map of path prefix to values.
+// errInvalid is returned when the value is malformed.
+func parseRouteGroups(value string, errInvalid error) (map[string][]string, error) {
+	groups := make(map[string][]string)
+
+	for _, group := range strings.Split(value, ";") {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			continue
+		}
+
+		prefix, values, found := strings.Cut(group, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !found || !strings.HasPrefix(prefix, "/") {
+			return nil, errInvalid
+		}
+
+		for _, v := range strings.Split(values, ",") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return nil, errInvalid
+			}
+
+			groups[prefix] = append(groups[prefix], v)
+		}
+	}
+
+	return groups, nil
+}
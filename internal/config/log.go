@@ -10,6 +10,9 @@ import (
 var (
 	ErrLogInvalidLevel  = errors.New("invalid log level, must be one of [" + ValidLogLevel + "]")
 	ErrLogInvalidFormat = errors.New("invalid log format, must be one of [" + ValidLogFormat + "]")
+	ErrLogInvalidSample = errors.New("invalid log sample rate, must be between 0 and 1")
+
+	ErrLogInvalidServiceName = errors.New("invalid log service name, must not be empty")
 )
 
 const (
@@ -21,6 +24,14 @@ const (
 
 	// DefaultLogFormat is the default log format
 	DefaultLogFormat = "text"
+
+	// DefaultLogSampleRate is the default fraction of requests
+	// that get verbose logging, like the request and response bodies
+	DefaultLogSampleRate = 1.0
+
+	// DefaultLogEnvironment is the default environment attached to every log line,
+	// like production or staging. Empty leaves it out
+	DefaultLogEnvironment = ""
 )
 
 // DefaultLogOutput is the default log output destination
@@ -28,17 +39,27 @@ var DefaultLogOutput = FileVar{os.Stdout, os.O_APPEND | os.O_CREATE | os.O_WRONL
 
 // LogConfig is the configuration for the logger
 type LogConfig struct {
-	Level  Field[string]
-	Format Field[string]
-	Output Field[FileVar]
+	Level      Field[string]
+	Format     Field[string]
+	Output     Field[FileVar]
+	SampleRate Field[float64]
+
+	ServiceName Field[string]
+	Environment Field[string]
 }
 
-// NewLogConfig creates a new logger configuration
-func NewLogConfig() *LogConfig {
+// NewLogConfig creates a new logger configuration,
+// the service name attached to every log line defaults to appName
+func NewLogConfig(appName string) *LogConfig {
 	return &LogConfig{
 		Level:  NewField("log.level", "LOG_LEVEL", "Log Level. Possible values ["+ValidLogLevel+"]", DefaultLogLevel),
 		Format: NewField("log.format", "LOG_FORMAT", "Log Format. Possible values ["+ValidLogFormat+"]", DefaultLogFormat),
 		Output: NewField("log.output", "LOG_OUTPUT", "Log Output", DefaultLogOutput),
+
+		SampleRate: NewField("log.sample.rate", "LOG_SAMPLE_RATE", "Fraction of requests, between 0 and 1, that get verbose logging", DefaultLogSampleRate),
+
+		ServiceName: NewField("log.service.name", "LOG_SERVICE_NAME", "Service name attached to every log line as the service attribute", appName),
+		Environment: NewField("log.environment", "LOG_ENVIRONMENT", "Environment attached to every log line as the env attribute, empty to leave it out", DefaultLogEnvironment),
 	}
 }
 
@@ -48,6 +69,9 @@ func (c *LogConfig) ParseEnvVars() {
 	c.Level.Value = GetEnv(c.Level.EnVarName, c.Level.Value)
 	c.Format.Value = GetEnv(c.Format.EnVarName, c.Format.Value)
 	c.Output.Value = GetEnv(c.Output.EnVarName, c.Output.Value)
+	c.SampleRate.Value = GetEnv(c.SampleRate.EnVarName, c.SampleRate.Value)
+	c.ServiceName.Value = GetEnv(c.ServiceName.EnVarName, c.ServiceName.Value)
+	c.Environment.Value = GetEnv(c.Environment.EnVarName, c.Environment.Value)
 }
 
 // Validate validates the logger configuration values
@@ -60,5 +84,13 @@ func (c *LogConfig) Validate() error {
 		return ErrLogInvalidFormat
 	}
 
+	if c.SampleRate.Value < 0 || c.SampleRate.Value > 1 {
+		return ErrLogInvalidSample
+	}
+
+	if c.ServiceName.Value == "" {
+		return ErrLogInvalidServiceName
+	}
+
 	return nil
 }
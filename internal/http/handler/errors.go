@@ -16,4 +16,14 @@ var (
 	ErrInvalidLimit                 = errors.New("invalid limit field")
 	ErrInvalidNextToken             = errors.New("invalid nextToken field")
 	ErrInvalidPrevToken             = errors.New("invalid prevToken field")
+	ErrTokenTooLong                 = errors.New("pagination token too long")
+	ErrInvalidBool                  = errors.New("invalid boolean value")
+	ErrLeadingWildcardFilter        = errors.New("invalid filter field, LIKE values cannot start with a wildcard")
+	ErrTooManyFilterPredicates      = errors.New("invalid filter field, too many predicates")
+	ErrInvalidEventBroker           = errors.New("invalid event broker")
+	ErrInvalidReadinessChecker      = errors.New("invalid readiness checker")
+	ErrEmptyBody                    = errors.New("request body is empty")
+	ErrMalformedJSON                = errors.New("malformed JSON")
+	ErrUnknownField                 = errors.New("unknown field")
+	ErrInvalidFieldType             = errors.New("invalid field type")
 )
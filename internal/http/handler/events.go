@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+)
+
+// DefaultEventsHeartbeatInterval is the default interval between the heartbeat
+// comments sent to keep the idle event streams alive.
+const DefaultEventsHeartbeatInterval = 15 * time.Second
+
+// EventsHandlerConf represents the configuration of the EventsHandler.
+type EventsHandlerConf struct {
+	Events *service.EventBroker
+
+	// HeartbeatInterval is the interval between the heartbeat comments.
+	// If zero, DefaultEventsHeartbeatInterval is used.
+	HeartbeatInterval time.Duration
+}
+
+// EventsHandler represents the handler streaming the changes of the resources.
+type EventsHandler struct {
+	events            *service.EventBroker
+	heartbeatInterval time.Duration
+}
+
+// NewEventsHandler creates a new EventsHandler.
+func NewEventsHandler(conf EventsHandlerConf) (*EventsHandler, error) {
+	if conf.Events == nil {
+		slog.Error("event broker is required")
+		return nil, ErrInvalidEventBroker
+	}
+
+	if conf.HeartbeatInterval <= 0 {
+		conf.HeartbeatInterval = DefaultEventsHeartbeatInterval
+	}
+
+	return &EventsHandler{
+		events:            conf.Events,
+		heartbeatInterval: conf.HeartbeatInterval,
+	}, nil
+}
+
+// RegisterRoutes registers the routes on the mux.
+func (ref *EventsHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /events", ref.stream)
+}
+
+// stream Stream the changes of the resources
+//
+//	@Id				c4a1e7d2-6b3f-4e8a-9c05-1d7f2b8e3a64
+//	@Summary		Stream the changes of the resources
+//	@Description	Server-Sent Events stream of the changes of the resources, like user.created,
+//	@Description	user.updated and user.deleted. Heartbeat comments keep the idle connection alive
+//	@Tags			Events
+//	@Produce		text/event-stream
+//	@Success		200	{object}	service.Event
+//	@Router			/events [get]
+func (ref *EventsHandler) stream(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// the stream is long-lived, so it can't have a write deadline
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("handler.Events.stream", "message", "could not clear write deadline", "error", err)
+	}
+
+	events, cancel := ref.events.Subscribe()
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// the headers are sent, so a client can tell the subscription is active
+	if err := rc.Flush(); err != nil {
+		slog.Error("handler.Events.stream", "error", err)
+		return
+	}
+
+	slog.Debug("handler.Events.stream", "message", "client subscribed", "address", r.RemoteAddr)
+
+	heartbeat := time.NewTicker(ref.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			slog.Debug("handler.Events.stream", "message", "client unsubscribed", "address", r.RemoteAddr)
+			return
+
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				slog.Debug("handler.Events.stream", "error", err)
+				return
+			}
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				slog.Error("handler.Events.stream", "error", err)
+				continue
+			}
+
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				slog.Debug("handler.Events.stream", "error", err)
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			slog.Debug("handler.Events.stream", "error", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+	mocksRepository "github.com/p2p-b2b/go-rest-api-service-template/mocks/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestEvents_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := mocksRepository.NewMockUsersRepository(ctrl)
+	ctx := context.TODO()
+
+	telemetry := newTestTelemetry(t)
+
+	broker := service.NewEventBroker(service.DefaultEventBufferSize)
+
+	userService, err := service.NewUsersService(service.UsersServiceConf{
+		Repository: mockRepository,
+		OT:         telemetry,
+		Events:     broker,
+	})
+	if err != nil {
+		t.Fatalf("could not create user service: %v", err)
+	}
+
+	usersHandler := newTestUsersHandler(t, UsersHandlerConf{
+		Service: userService,
+		OT:      telemetry,
+	})
+
+	eventsHandler, err := NewEventsHandler(EventsHandlerConf{
+		Events:            broker,
+		HeartbeatInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("could not create events handler: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	usersHandler.RegisterRoutes(mux)
+	eventsHandler.RegisterRoutes(mux)
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	streamCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, srv.URL+"/events", nil)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+
+	res, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("could not subscribe: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected content type text/event-stream, got %q", ct)
+	}
+
+	mockRepository.
+		EXPECT().
+		Insert(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	id := uuid.New()
+	body := `{"id":"` + id.String() + `","first_name":"John","last_name":"Doe","email":"john@doe.com","password":"ThisIs4Passw0rd"}`
+
+	createRes, err := srv.Client().Post(srv.URL+"/users", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	createRes.Body.Close()
+
+	if createRes.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d", http.StatusCreated, createRes.StatusCode)
+	}
+
+	var eventType string
+	var received, heartbeat bool
+	scanner := bufio.NewScanner(res.Body)
+	for !received || !heartbeat {
+		if !scanner.Scan() {
+			t.Fatalf("the stream ended before the event and a heartbeat arrived: %v", scanner.Err())
+		}
+
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, ": heartbeat"):
+			heartbeat = true
+
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+
+		case strings.HasPrefix(line, "data: "):
+			var event service.Event
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event); err != nil {
+				t.Fatalf("could not decode event: %v", err)
+			}
+
+			if eventType != service.EventUserCreated || event.Type != service.EventUserCreated {
+				t.Errorf("expected event %s, got %s and %s", service.EventUserCreated, eventType, event.Type)
+			}
+
+			if event.ResourceID != id {
+				t.Errorf("expected resource id %s, got %s", id, event.ResourceID)
+			}
+
+			received = true
+		}
+	}
+}
@@ -1,5 +1,14 @@
 package handler
 
+import (
+	"time"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+)
+
+// processStartTime is the time the process started, used to report its uptime.
+var processStartTime = time.Now()
+
 // Check represents a health check.
 //
 // @Description Health check of the service
@@ -17,3 +26,33 @@ type Health struct {
 	Status string  `json:"status" example:"true" format:"boolean"`
 	Checks []Check `json:"checks" format:"array"`
 }
+
+// HealthStatus represents the health of the service with its version and uptime.
+//
+// @Description Health status of the service
+type HealthStatus struct {
+	Status        string    `json:"status" example:"UP" format:"string"`
+	Version       string    `json:"version" example:"0.0.1" format:"string"`
+	StartTime     time.Time `json:"start_time" example:"2021-01-01T00:00:00Z" format:"date-time"`
+	UptimeSeconds float64   `json:"uptime_seconds" example:"3600.5" format:"float"`
+	Checks        []Check   `json:"checks" format:"array"`
+}
+
+// newHealth returns the Health of the service health.
+func newHealth(sHealth service.Health) *Health {
+	health := &Health{
+		Status: sHealth.Status.String(),
+		Checks: make([]Check, len(sHealth.Checks)),
+	}
+
+	for i, sCheck := range sHealth.Checks {
+		health.Checks[i] = Check{
+			Name:   sCheck.Name,
+			Kind:   sCheck.Kind,
+			Status: sCheck.Status.String(),
+			Data:   sCheck.Data,
+		}
+	}
+
+	return health
+}
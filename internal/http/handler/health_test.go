@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+	mocksService "github.com/p2p-b2b/go-rest-api-service-template/mocks/handler"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestHealth_Status(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	mockService.
+		EXPECT().
+		HealthCheck(gomock.Any()).
+		Return(service.Health{
+			Status: service.StatusUp,
+			Checks: []service.Check{{Name: "database", Kind: "database", Status: service.StatusUp}},
+		}, nil).
+		Times(2)
+
+	getStatus := func() HealthStatus {
+		r, err := http.NewRequest(http.MethodGet, "/health/status", nil)
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+
+		var status HealthStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatalf("could not decode response: %v", err)
+		}
+
+		return status
+	}
+
+	first := getStatus()
+	time.Sleep(10 * time.Millisecond)
+	second := getStatus()
+
+	if second.UptimeSeconds <= first.UptimeSeconds {
+		t.Errorf("expected the uptime to increase, got %v then %v", first.UptimeSeconds, second.UptimeSeconds)
+	}
+
+	if !second.StartTime.Equal(first.StartTime) {
+		t.Errorf("expected the same start time, got %v then %v", first.StartTime, second.StartTime)
+	}
+
+	if first.Version == "" || first.Status != "UP" || len(first.Checks) != 1 {
+		t.Errorf("unexpected health status %+v", first)
+	}
+}
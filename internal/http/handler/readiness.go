@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+)
+
+// ReadinessChecker probes the dependencies of the service.
+type ReadinessChecker interface {
+	Check(ctx context.Context) service.Readiness
+}
+
+// ReadinessHandlerConf represents the configuration of the ReadinessHandler.
+type ReadinessHandlerConf struct {
+	Checker ReadinessChecker
+}
+
+// ReadinessHandler represents the handler reporting if the service is ready to serve requests.
+type ReadinessHandler struct {
+	checker ReadinessChecker
+}
+
+// NewReadinessHandler creates a new ReadinessHandler.
+func NewReadinessHandler(conf ReadinessHandlerConf) (*ReadinessHandler, error) {
+	if conf.Checker == nil {
+		slog.Error("readiness checker is required")
+		return nil, ErrInvalidReadinessChecker
+	}
+
+	return &ReadinessHandler{
+		checker: conf.Checker,
+	}, nil
+}
+
+// RegisterRoutes registers the routes on the mux.
+func (ref *ReadinessHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /ready", ref.getReady)
+}
+
+// getReady returns the readiness of the service
+//
+//	@Id				7e2d9a41-5c8b-4f36-a0d1-9b4e6c3f2a85
+//	@Summary		Retrieve the readiness of the service
+//	@Description	This endpoint probes the dependencies of the service, like the database,
+//	@Description	and returns the status of each one. The service is not ready while
+//	@Description	a required dependency is down
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	Readiness
+//	@Failure		503	{object}	Readiness
+//	@Router			/ready [get]
+func (ref *ReadinessHandler) getReady(w http.ResponseWriter, r *http.Request) {
+	readiness := newReadiness(ref.checker.Check(r.Context()))
+
+	statusCode := http.StatusOK
+	if readiness.Status != service.StatusUp.String() {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	if err := respond.WriteJSON(w, statusCode, readiness); err != nil {
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	slog.Debug("handler.Readiness.getReady: called", "status", readiness.Status)
+}
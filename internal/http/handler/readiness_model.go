@@ -0,0 +1,36 @@
+package handler
+
+import "github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+
+// DependencyStatus represents the status of a dependency of the service.
+//
+// @Description Status of a dependency of the service
+type DependencyStatus struct {
+	Status   string `json:"status" example:"UP" format:"string"`
+	Required bool   `json:"required" example:"true" format:"boolean"`
+}
+
+// Readiness represents the readiness of the service and the status of its dependencies.
+//
+// @Description Readiness of the service and the status of its dependencies, by name
+type Readiness struct {
+	Status       string                      `json:"status" example:"UP" format:"string"`
+	Dependencies map[string]DependencyStatus `json:"dependencies" format:"map"`
+}
+
+// newReadiness returns the Readiness of the service readiness.
+func newReadiness(sReadiness service.Readiness) *Readiness {
+	readiness := &Readiness{
+		Status:       sReadiness.Status.String(),
+		Dependencies: make(map[string]DependencyStatus, len(sReadiness.Dependencies)),
+	}
+
+	for name, sStatus := range sReadiness.Dependencies {
+		readiness.Dependencies[name] = DependencyStatus{
+			Status:   sStatus.Status.String(),
+			Required: sStatus.Required,
+		}
+	}
+
+	return readiness
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+)
+
+func TestReadiness_GetReady(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hanging := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name         string
+		dependencies []service.Dependency
+		statusCode   int
+		want         *Readiness
+	}{
+		{
+			name: "all dependencies up",
+			dependencies: []service.Dependency{
+				{Name: "database", Required: true, Probe: up},
+				{Name: "smtp", Required: false, Probe: up},
+			},
+			statusCode: http.StatusOK,
+			want: &Readiness{
+				Status: "UP",
+				Dependencies: map[string]DependencyStatus{
+					"database": {Status: "UP", Required: true},
+					"smtp":     {Status: "UP", Required: false},
+				},
+			},
+		},
+		{
+			name: "required dependency down",
+			dependencies: []service.Dependency{
+				{Name: "database", Required: true, Probe: down},
+				{Name: "smtp", Required: false, Probe: up},
+			},
+			statusCode: http.StatusServiceUnavailable,
+			want: &Readiness{
+				Status: "DOWN",
+				Dependencies: map[string]DependencyStatus{
+					"database": {Status: "DOWN", Required: true},
+					"smtp":     {Status: "UP", Required: false},
+				},
+			},
+		},
+		{
+			name: "not required dependency down",
+			dependencies: []service.Dependency{
+				{Name: "database", Required: true, Probe: up},
+				{Name: "smtp", Required: false, Probe: down},
+			},
+			statusCode: http.StatusOK,
+			want: &Readiness{
+				Status: "UP",
+				Dependencies: map[string]DependencyStatus{
+					"database": {Status: "UP", Required: true},
+					"smtp":     {Status: "DOWN", Required: false},
+				},
+			},
+		},
+		{
+			name: "required dependency timing out",
+			dependencies: []service.Dependency{
+				{Name: "database", Required: true, Probe: hanging},
+			},
+			statusCode: http.StatusServiceUnavailable,
+			want: &Readiness{
+				Status: "DOWN",
+				Dependencies: map[string]DependencyStatus{
+					"database": {Status: "DOWN", Required: true},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			h, err := NewReadinessHandler(ReadinessHandlerConf{
+				Checker: service.NewReadinessChecker(50*time.Millisecond, tc.dependencies...),
+			})
+			if err != nil {
+				t.Fatalf("could not create readiness handler: %v", err)
+			}
+
+			mux := http.NewServeMux()
+			h.RegisterRoutes(mux)
+
+			r := httptest.NewRequest(http.MethodGet, "/ready", nil)
+			w := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+
+			var got Readiness
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, &got); diff != "" {
+				t.Errorf("unexpected readiness (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,23 +2,26 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/middleware"
 	httpSwagger "github.com/swaggo/http-swagger/v2"
 )
 
 // SwaggerHandler handles the swagger UI
 type SwaggerHandler struct {
-	hf http.HandlerFunc
+	h http.Handler
 }
 
-// NewSwaggerHandler creates a new SwaggerHandler
-func NewSwaggerHandler(url string) *SwaggerHandler {
+// NewSwaggerHandler creates a new SwaggerHandler.
+// The browsers cache the UI and the documentation for cacheMaxAge
+func NewSwaggerHandler(url string, cacheMaxAge time.Duration) *SwaggerHandler {
 	return &SwaggerHandler{
-		hf: httpSwagger.Handler(httpSwagger.URL(url)),
+		h: middleware.CacheControl(cacheMaxAge)(httpSwagger.Handler(httpSwagger.URL(url))),
 	}
 }
 
 // RegisterRoutes registers the routes for the handler
 func (ref *SwaggerHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /swagger/", ref.hf)
+	mux.Handle("GET /swagger/", ref.h)
 }
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/p2p-b2b/go-rest-api-service-template/docs"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/config"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+	mocksService "github.com/p2p-b2b/go-rest-api-service-template/mocks/handler"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func TestSwagger_CacheHeaders(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+	ctx := context.TODO()
+
+	otConfig := config.NewOpenTelemetryConfig("test", "1.0.0")
+	otConfig.TraceExporter.Value = "console"
+	otConfig.MetricExporter.Value = "console"
+
+	telemetry, err := o11y.New(ctx, otConfig)
+	if err != nil {
+		t.Fatalf("could not create telemetry: %v", err)
+	}
+
+	if err := telemetry.Start(); err != nil {
+		t.Fatalf("could not start telemetry: %v", err)
+	}
+
+	mockService.
+		EXPECT().
+		List(gomock.Any(), gomock.Any()).
+		Return(&service.ListUsersOutput{}, nil).
+		Times(1)
+
+	usersHandler, err := NewUsersHandler(UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+	if err != nil {
+		t.Fatalf("could not create user handler: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	NewSwaggerHandler("/swagger/doc.json", 10*time.Minute).RegisterRoutes(mux)
+	usersHandler.RegisterRoutes(mux)
+
+	tests := []struct {
+		name         string
+		target       string
+		cacheControl string
+		etag         bool
+	}{
+		{
+			name:         "swagger documentation is cached",
+			target:       "/swagger/doc.json",
+			cacheControl: "public, max-age=600",
+			etag:         true,
+		},
+		{
+			name:         "swagger UI is cached",
+			target:       "/swagger/index.html",
+			cacheControl: "public, max-age=600",
+			etag:         true,
+		},
+		{
+			name:         "API responses are not cached",
+			target:       "/users",
+			cacheControl: "",
+			etag:         false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			w := httptest.NewRecorder()
+
+			// When
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			if got := w.Header().Get("Cache-Control"); got != tc.cacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tc.cacheControl, got)
+			}
+
+			if got := w.Header().Get("ETag") != ""; got != tc.etag {
+				t.Errorf("expected ETag present %t, got %t", tc.etag, got)
+			}
+		})
+	}
+}
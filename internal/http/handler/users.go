@@ -2,7 +2,6 @@ package handler
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -14,8 +13,10 @@ import (
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/query"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/repository"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/version"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/metric"
@@ -30,8 +31,12 @@ type UsersService interface {
 	GetByEmail(ctx context.Context, email string) (*service.User, error)
 	Create(ctx context.Context, input *service.CreateUserInput) error
 	Update(ctx context.Context, input *service.UpdateUserInput) error
+	Upsert(ctx context.Context, input *service.UpsertUserInput) (bool, error)
 	Delete(ctx context.Context, input *service.DeleteUserInput) error
+	Anonymize(ctx context.Context, id uuid.UUID) error
+	BulkDelete(ctx context.Context, input *service.BulkDeleteUsersInput) (*service.BulkDeleteUsersOutput, error)
 	List(ctx context.Context, input *service.ListUsersInput) (*service.ListUsersOutput, error)
+	Count(ctx context.Context, input *service.CountUsersInput) (*service.CountUsersOutput, error)
 }
 
 // UsersHandler represents the http handler for the user.
@@ -39,6 +44,26 @@ type UsersHandlerConf struct {
 	Service       UsersService
 	OT            *o11y.OpenTelemetry
 	MetricsPrefix string
+
+	// RejectLeadingWildcard rejects the filters with a LIKE value starting with a wildcard,
+	// which cannot use an index. If false, these filters are only logged as a warning.
+	RejectLeadingWildcard bool
+
+	// FilterMaxPredicates is the maximum number of predicates in a filter,
+	// the filters with more are rejected. If zero, there is no limit.
+	FilterMaxPredicates int
+
+	// TokenMaxLength is the maximum length of the next_token and prev_token,
+	// the longer ones are rejected. If zero, paginator.DefaultMaxTokenLength is used.
+	TokenMaxLength int
+
+	// SortDefaultDirection is the direction, ASC or DESC, of the sort columns
+	// without one. If empty, every sort column must have a direction.
+	SortDefaultDirection string
+
+	// SortDefaultNulls is the placement of the null values, FIRST or LAST, of the
+	// sort columns without one. If empty, the database default placement is used.
+	SortDefaultNulls string
 }
 
 type usersHandlerMetrics struct {
@@ -47,10 +72,15 @@ type usersHandlerMetrics struct {
 
 // UsersHandler represents the handler for the user.
 type UsersHandler struct {
-	service       UsersService
-	ot            *o11y.OpenTelemetry
-	metricsPrefix string
-	metrics       usersHandlerMetrics
+	service               UsersService
+	ot                    *o11y.OpenTelemetry
+	metricsPrefix         string
+	metrics               usersHandlerMetrics
+	rejectLeadingWildcard bool
+	filterMaxPredicates   int
+	tokenMaxLength        int
+	sortDefaultDirection  string
+	sortDefaultNulls      string
 }
 
 // NewUsersHandler creates a new UsersHandler.
@@ -66,8 +96,17 @@ func NewUsersHandler(conf UsersHandlerConf) (*UsersHandler, error) {
 	}
 
 	uh := &UsersHandler{
-		service: conf.Service,
-		ot:      conf.OT,
+		service:               conf.Service,
+		ot:                    conf.OT,
+		rejectLeadingWildcard: conf.RejectLeadingWildcard,
+		filterMaxPredicates:   conf.FilterMaxPredicates,
+		tokenMaxLength:        conf.TokenMaxLength,
+		sortDefaultDirection:  conf.SortDefaultDirection,
+		sortDefaultNulls:      conf.SortDefaultNulls,
+	}
+
+	if uh.tokenMaxLength <= 0 {
+		uh.tokenMaxLength = paginator.DefaultMaxTokenLength
 	}
 
 	if conf.MetricsPrefix != "" {
@@ -91,11 +130,33 @@ func NewUsersHandler(conf UsersHandlerConf) (*UsersHandler, error) {
 // RegisterRoutes registers the routes on the mux.
 func (ref *UsersHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /users/health", ref.getHealth)
+	mux.HandleFunc("GET /health/status", ref.getHealthStatus)
 	mux.HandleFunc("GET /users", ref.listUsers)
+	mux.HandleFunc("GET /users/lookup", ref.getByEmail)
 	mux.HandleFunc("GET /users/{user_id}", ref.getByID)
-	mux.HandleFunc("PUT /users/{user_id}", ref.updateUser)
+	mux.HandleFunc("GET /users/{user_id}/export", ref.exportUser)
+	mux.HandleFunc("PUT /users/{user_id}", ref.upsertUser)
+	mux.HandleFunc("PATCH /users/{user_id}", ref.updateUser)
 	mux.HandleFunc("POST /users", ref.createUser)
 	mux.HandleFunc("DELETE /users/{user_id}", ref.deleteUser)
+	mux.HandleFunc("POST /users/{user_id}/anonymize", ref.anonymizeUser)
+	mux.HandleFunc("POST /users/{user_id}/disable", ref.disableUser)
+	mux.HandleFunc("POST /users/{user_id}/enable", ref.enableUser)
+	mux.HandleFunc("POST /users/bulk-delete", ref.bulkDeleteUsers)
+}
+
+// userLocation returns the path of the user with the given ID, used as the
+// Location header of the responses creating or updating it. The path keeps the
+// prefix the handlers are mounted under, like /api/v1, found by comparing the
+// request URI, left untouched by http.StripPrefix, with the path of the request.
+func userLocation(r *http.Request, id uuid.UUID) string {
+	requestPath, _, _ := strings.Cut(r.RequestURI, "?")
+	prefix, ok := strings.CutSuffix(requestPath, r.URL.EscapedPath())
+	if !ok {
+		prefix = ""
+	}
+
+	return prefix + "/users/" + id.String()
 }
 
 // getHealth returns the health of the service
@@ -114,30 +175,56 @@ func (ref *UsersHandler) getHealth(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 	sHealth, err := ref.service.HealthCheck(ctx)
 	if err != nil {
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, ErrInternalServerError.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
 		return
 	}
 
-	health := &Health{
-		Status: sHealth.Status.String(),
-		Checks: make([]Check, len(sHealth.Checks)),
+	health := newHealth(sHealth)
+
+	if err := respond.WriteJSON(w, http.StatusOK, health); err != nil {
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
 	}
 
-	for i, sCheck := range sHealth.Checks {
-		health.Checks[i] = Check{
-			Name:   sCheck.Name,
-			Kind:   sCheck.Kind,
-			Status: sCheck.Status.String(),
-			Data:   sCheck.Data,
-		}
+	slog.Debug("handler.Users.getHealth: called")
+}
+
+// getHealthStatus returns the health of the service with its version and uptime
+//
+//	@Id				0f6f1c52-3f0e-4a8e-9d55-6b1d2c7e8a41
+//	@Summary		Retrieve the health status of the service
+//	@Description	This endpoint returns the health of the service, validating the
+//	@Description	connection to the database, together with the version of the service,
+//	@Description	the start time of the process and its uptime
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	HealthStatus
+//	@Failure		500	{object}	respond.HTTPMessage
+//	@Router			/health/status [get]
+func (ref *UsersHandler) getHealthStatus(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	sHealth, err := ref.service.HealthCheck(ctx)
+	if err != nil {
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	health := newHealth(sHealth)
+	status := &HealthStatus{
+		Status:        health.Status,
+		Version:       version.Version,
+		StartTime:     processStartTime.UTC(),
+		UptimeSeconds: time.Since(processStartTime).Seconds(),
+		Checks:        health.Checks,
 	}
 
-	if err := respond.WriteJSONData(w, http.StatusOK, health); err != nil {
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, ErrInternalServerError.Error())
+	if err := respond.WriteJSON(w, http.StatusOK, status); err != nil {
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
 		return
 	}
 
-	slog.Debug("handler.Users.getHealth: called")
+	slog.Debug("handler.Users.getHealthStatus: called")
 }
 
 // getByID Get a user by ID
@@ -180,7 +267,7 @@ func (ref *UsersHandler) getByID(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
 		return
 	}
 
@@ -197,7 +284,7 @@ func (ref *UsersHandler) getByID(w http.ResponseWriter, r *http.Request) {
 				),
 			)
 
-			respond.WriteJSONMessage(w, r, http.StatusNotFound, err.Error())
+			respond.WriteError(w, r, http.StatusNotFound, respond.CodeNotFound, err.Error())
 			return
 		}
 
@@ -207,7 +294,7 @@ func (ref *UsersHandler) getByID(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -222,7 +309,7 @@ func (ref *UsersHandler) getByID(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt: sUser.UpdatedAt,
 	}
 
-	if err := respond.WriteJSONData(w, http.StatusOK, user); err != nil {
+	if err := respond.WriteJSON(w, http.StatusOK, user); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		span.RecordError(err)
 		slog.Error("handler.Users.getByID", "error", err.Error())
@@ -232,7 +319,7 @@ func (ref *UsersHandler) getByID(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -246,18 +333,250 @@ func (ref *UsersHandler) getByID(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// getByEmail Get a user by email
+//
+//	@Id				3e8b5d1f-7a42-4c9e-b6d0-8f2a1c7e5b39
+//	@Summary		Get a user by email
+//	@Description	Get the user with exactly the given email, compared in lower case without the surrounding spaces
+//	@Tags			Users
+//	@Produce		json
+//	@Param			email	query		string	true	"The user email"	Format(email)
+//	@Success		200		{object}	User
+//	@Failure		400		{object}	respond.HTTPMessage
+//	@Failure		404		{object}	respond.HTTPMessage
+//	@Failure		500		{object}	respond.HTTPMessage
+//	@Router			/users/lookup [get]
+func (ref *UsersHandler) getByEmail(w http.ResponseWriter, r *http.Request) {
+	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), "handler.Users.getByEmail")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "handler.Users.getByEmail"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", r.URL.Path),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "handler.Users.getByEmail"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", r.URL.Path),
+	}
+
+	email := r.URL.Query().Get("email")
+	if strings.TrimSpace(email) == "" {
+		span.SetStatus(codes.Error, ErrUserInvalidEmail.Error())
+		span.RecordError(ErrUserInvalidEmail)
+		slog.Error("handler.Users.getByEmail", "error", ErrUserInvalidEmail.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, ErrUserInvalidEmail.Error())
+		return
+	}
+
+	sUser, err := ref.service.GetByEmail(ctx, email)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.getByEmail", "error", err.Error())
+
+		if errors.Is(err, service.ErrUserNotFound) {
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusNotFound)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusNotFound, respond.CodeNotFound, err.Error())
+			return
+		}
+
+		if errors.Is(err, service.ErrUserInvalidEmail) {
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+			return
+		}
+
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	user := &User{
+		ID:        sUser.ID,
+		FirstName: sUser.FirstName,
+		LastName:  sUser.LastName,
+		Email:     sUser.Email,
+		Disabled:  sUser.Disabled,
+		CreatedAt: sUser.CreatedAt,
+		UpdatedAt: sUser.UpdatedAt,
+	}
+
+	if err := respond.WriteJSON(w, http.StatusOK, user); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.getByEmail", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
+		return
+	}
+
+	slog.Debug("handler.Users.getByEmail", "user.id", user.ID)
+	span.SetStatus(codes.Ok, "User found")
+	span.SetAttributes(attribute.String("user.id", user.ID.String()))
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+}
+
+// exportUser Export the data held about a user
+//
+//	@Id				5f1c2a7d-8e3b-4c6a-9d0e-2b7f4a1c8e56
+//	@Summary		Export a user's data
+//	@Description	Export all the data held about a user as a single JSON document
+//	@Tags			Users
+//	@Produce		json
+//	@Param			user_id	path		string	true	"The user ID in UUID format"	Format(uuid)
+//	@Success		200		{object}	UserExport
+//	@Failure		400		{object}	respond.HTTPMessage
+//	@Failure		404		{object}	respond.HTTPMessage
+//	@Failure		500		{object}	respond.HTTPMessage
+//	@Router			/users/{user_id}/export [get]
+func (ref *UsersHandler) exportUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), "handler.Users.exportUser")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "handler.Users.exportUser"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", "/users/{user_id}/export"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "handler.Users.exportUser"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", "/users/{user_id}/export"),
+	}
+
+	id, err := parseUUIDQueryParams(r.PathValue("user_id"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.exportUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	sUser, err := ref.service.GetByID(ctx, id)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.exportUser", "error", err.Error())
+
+		if errors.Is(err, service.ErrUserNotFound) {
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusNotFound)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusNotFound, respond.CodeNotFound, err.Error())
+			return
+		}
+
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	export := UserExport{
+		ExportedAt: time.Now().UTC(),
+		User: User{
+			ID:        sUser.ID,
+			FirstName: sUser.FirstName,
+			LastName:  sUser.LastName,
+			Email:     sUser.Email,
+			Disabled:  sUser.Disabled,
+			CreatedAt: sUser.CreatedAt,
+			UpdatedAt: sUser.UpdatedAt,
+		},
+	}
+
+	// the export is meant to be saved by the requester
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"user-%s.json\"", id))
+
+	if err := respond.WriteJSON(w, http.StatusOK, export); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.exportUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	span.SetStatus(codes.Ok, "User exported")
+	span.SetAttributes(attribute.String("user.id", id.String()))
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+}
+
 // createUser Create a new user
 //
 //	@Id				f71e14db-fc77-4fb3-a21d-292eade431df
 //	@Summary		Create a new user
 //	@Description	Create a new user from scratch
 //	@Description	If the id is not provided, it will be generated automatically
+//	@Description	With upsert=true or the If-Not-Exists header, an existing user with the same id or email is returned instead of a conflict
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
-//	@Param			user	body		CreateUserRequest	true	"CreateUserRequest"	Format(json)
-//	@Success		201		{object}	respond.HTTPMessage
+//	@Param			user			body		CreateUserRequest	true	"CreateUserRequest"							Format(json)
+//	@Param			upsert			query		bool				false	"Return the existing user instead of 409"	Format(bool)
+//	@Param			If-Not-Exists	header		bool				false	"Return the existing user instead of 409"	Format(bool)
+//	@Success		200				{object}	User
+//	@Success		201				{object}	respond.HTTPMessage
+//	@Header			201				{string}	Location	"The path of the created user"
 //	@Failure		400		{object}	respond.HTTPMessage
+//	@Failure		422		{object}	respond.HTTPMessage
 //	@Failure		409		{object}	respond.HTTPMessage
 //	@Failure		500		{object}	respond.HTTPMessage
 //	@Router			/users [post]
@@ -277,8 +596,29 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 		attribute.String("http.path", r.URL.Path[:strings.LastIndex(r.URL.Path, "/")]),
 	}
 
+	// the header takes precedence over the query parameter
+	upsertHint := r.URL.Query().Get("upsert")
+	if h := r.Header.Get("If-Not-Exists"); h != "" {
+		upsertHint = h
+	}
+
+	upsert, err := parseBoolQueryParams(upsertHint)
+	if err != nil {
+		slog.Error("handler.Users.createUser", "error", err.Error())
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
 	var req CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if code, err := decodeJSONBody(r, &req); err != nil {
 		slog.Error("handler.Users.createUser", "error", err.Error())
 		span.SetStatus(codes.Error, err.Error())
 		span.RecordError(err)
@@ -288,7 +628,7 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusBadRequest, code, err.Error())
 		return
 	}
 
@@ -302,11 +642,11 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 		span.RecordError(err)
 		ref.metrics.handlerCalls.Add(ctx, 1,
 			metric.WithAttributes(
-				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusUnprocessableEntity)))...,
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusUnprocessableEntity, respond.CodeUnprocessableEntity, err.Error())
 		return
 	}
 
@@ -323,6 +663,13 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 		span.SetStatus(codes.Error, err.Error())
 		span.RecordError(err)
 
+		if upsert && (errors.Is(err, service.ErrUserIDAlreadyExists) ||
+			errors.Is(err, service.ErrUserEmailAlreadyExists)) {
+
+			ref.writeExistingUser(ctx, w, r, err, user, metricCommonAttributes)
+			return
+		}
+
 		if errors.Is(err, service.ErrUserIDAlreadyExists) ||
 			errors.Is(err, service.ErrUserEmailAlreadyExists) {
 
@@ -332,7 +679,7 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 				),
 			)
 
-			respond.WriteJSONMessage(w, r, http.StatusConflict, err.Error())
+			respond.WriteError(w, r, http.StatusConflict, respond.CodeConflict, err.Error())
 			return
 		}
 
@@ -342,7 +689,7 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -356,15 +703,61 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// Location header is required for RESTful APIs
-	w.Header().Set("Location", fmt.Sprintf("%s%s/%s", r.Header.Get("Origin"), r.RequestURI, user.ID.String()))
-	respond.WriteJSONMessage(w, r, http.StatusCreated, "User created")
+	w.Header().Set("Location", userLocation(r, user.ID))
+	respond.WriteJSONCreated(w, r, user.ID.String(), "User created")
+}
+
+// writeExistingUser writes the user that made the creation of the given one fail
+// with the conflict error, so a retried creation gets the existing user.
+func (ref *UsersHandler) writeExistingUser(ctx context.Context, w http.ResponseWriter, r *http.Request, conflict error, user *service.CreateUserInput, metricCommonAttributes []attribute.KeyValue) {
+	var sUser *service.User
+	var err error
+	if errors.Is(conflict, service.ErrUserIDAlreadyExists) {
+		sUser, err = ref.service.GetByID(ctx, user.ID)
+	} else {
+		sUser, err = ref.service.GetByEmail(ctx, user.Email)
+	}
+
+	if err != nil {
+		slog.Error("handler.Users.createUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
+		return
+	}
+
+	existing := &User{
+		ID:        sUser.ID,
+		FirstName: sUser.FirstName,
+		LastName:  sUser.LastName,
+		Email:     sUser.Email,
+		Disabled:  sUser.Disabled,
+		CreatedAt: sUser.CreatedAt,
+		UpdatedAt: sUser.UpdatedAt,
+	}
+
+	slog.Debug("handler.Users.createUser", "message", "user already exists", "user.id", existing.ID)
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+
+	w.Header().Set("Location", userLocation(r, existing.ID))
+	if err := respond.WriteJSON(w, http.StatusOK, existing); err != nil {
+		slog.Error("handler.Users.createUser", "error", err.Error())
+	}
 }
 
 // updateUser Update a user
 //
 //	@Id				75165751-045b-465d-ba93-c88a27b6a42e
 //	@Summary		Update a user
-//	@Description	Update a user
+//	@Description	Update the given fields of a user
 //	@Tags			Users
 //	@Accept			json
 //	@Produce		json
@@ -372,9 +765,10 @@ func (ref *UsersHandler) createUser(w http.ResponseWriter, r *http.Request) {
 //	@Param			user	body		UpdateUserRequest	true	"User"							Format(json)
 //	@Success		200		{object}	respond.HTTPMessage
 //	@Failure		400		{object}	respond.HTTPMessage
+//	@Failure		422		{object}	respond.HTTPMessage
 //	@Failure		409		{object}	respond.HTTPMessage
 //	@Failure		500		{object}	respond.HTTPMessage
-//	@Router			/users/{user_id} [put]
+//	@Router			/users/{user_id} [patch]
 func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), "handler.Users.updateUser")
 	defer span.End()
@@ -402,12 +796,12 @@ func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
 		return
 	}
 
 	var req UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if code, err := decodeJSONBody(r, &req); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		span.RecordError(err)
 		slog.Error("handler.Users.updateUser", "error", err.Error())
@@ -418,7 +812,7 @@ func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusBadRequest, code, err.Error())
 		return
 	}
 
@@ -428,11 +822,11 @@ func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 		slog.Error("handler.Users.updateUser", "error", err.Error())
 		ref.metrics.handlerCalls.Add(ctx, 1,
 			metric.WithAttributes(
-				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusUnprocessableEntity)))...,
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusUnprocessableEntity, respond.CodeUnprocessableEntity, err.Error())
 		return
 	}
 
@@ -458,7 +852,7 @@ func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 				),
 			)
 
-			respond.WriteJSONMessage(w, r, http.StatusConflict, err.Error())
+			respond.WriteError(w, r, http.StatusConflict, respond.CodeConflict, err.Error())
 			return
 		}
 
@@ -468,7 +862,7 @@ func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -482,15 +876,171 @@ func (ref *UsersHandler) updateUser(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// Location header is required for RESTful APIs
-	w.Header().Set("Location", fmt.Sprintf("%s%s", r.Header.Get("Origin"), r.RequestURI))
+	w.Header().Set("Location", userLocation(r, user.ID))
 	respond.WriteJSONMessage(w, r, http.StatusOK, "User updated")
 }
 
-// deleteUser Delete a user
+// upsertUser Create or replace a user
 //
-//	@Id				48e60e0a-ea1c-46d4-8729-c47dd82a4e93
-//	@Summary		Delete a user
-//	@Description	Delete a user
+//	@Id				0b6f7f4e-2f0a-4c55-9a43-2b8f0f3c1d7e
+//	@Summary		Create or replace a user
+//	@Description	Create the user with the given ID, or replace its data if it already exists
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			user_id	path		string				true	"The user ID in UUID format"	Format(uuid)
+//	@Param			user	body		UpsertUserRequest	true	"User"							Format(json)
+//	@Success		200		{object}	respond.HTTPMessage
+//	@Success		201		{object}	respond.HTTPMessage
+//	@Header			201		{string}	Location	"The path of the created user"
+//	@Failure		400		{object}	respond.HTTPMessage
+//	@Failure		422		{object}	respond.HTTPMessage
+//	@Failure		409		{object}	respond.HTTPMessage
+//	@Failure		500		{object}	respond.HTTPMessage
+//	@Router			/users/{user_id} [put]
+func (ref *UsersHandler) upsertUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), "handler.Users.upsertUser")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "handler.Users.upsertUser"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", r.URL.Path[:strings.LastIndex(r.URL.Path, "/")]),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "handler.Users.upsertUser"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", r.URL.Path[:strings.LastIndex(r.URL.Path, "/")]),
+	}
+
+	id, err := parseUUIDQueryParams(r.PathValue("user_id"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.upsertUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	var req UpsertUserRequest
+	if code, err := decodeJSONBody(r, &req); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.upsertUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, code, err.Error())
+		return
+	}
+
+	if req.ID == uuid.Nil {
+		req.ID = id
+	}
+
+	if req.ID != id {
+		span.SetStatus(codes.Error, ErrUserIDMismatch.Error())
+		span.RecordError(ErrUserIDMismatch)
+		slog.Error("handler.Users.upsertUser", "error", ErrUserIDMismatch.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusUnprocessableEntity)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusUnprocessableEntity, respond.CodeUnprocessableEntity, ErrUserIDMismatch.Error())
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.upsertUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusUnprocessableEntity)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusUnprocessableEntity, respond.CodeUnprocessableEntity, err.Error())
+		return
+	}
+
+	user := &service.UpsertUserInput{
+		ID:        req.ID,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+		Password:  req.Password,
+		Disabled:  req.Disabled,
+	}
+
+	created, err := ref.service.Upsert(ctx, user)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.upsertUser", "error", err.Error())
+
+		if errors.Is(err, service.ErrUserEmailAlreadyExists) {
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusConflict)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusConflict, respond.CodeConflict, err.Error())
+			return
+		}
+
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
+		return
+	}
+
+	statusCode, message := http.StatusOK, "User updated"
+	if created {
+		statusCode, message = http.StatusCreated, "User created"
+	}
+
+	slog.Debug("handler.Users.upsertUser", "user.id", user.ID, "created", created)
+	span.SetStatus(codes.Ok, message)
+	span.SetAttributes(attribute.String("user.id", user.ID.String()))
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", statusCode)))...,
+		),
+	)
+
+	// Location header is required for RESTful APIs
+	w.Header().Set("Location", userLocation(r, user.ID))
+	if created {
+		respond.WriteJSONCreated(w, r, user.ID.String(), message)
+		return
+	}
+
+	respond.WriteJSONMessage(w, r, statusCode, message)
+}
+
+// deleteUser Delete a user
+//
+//	@Id				48e60e0a-ea1c-46d4-8729-c47dd82a4e93
+//	@Summary		Delete a user
+//	@Description	Delete a user
 //	@Tags			Users
 //	@Param			user_id	path	string	true	"The user ID in UUID format"	Format(uuid)
 //	@Produce		json
@@ -525,7 +1075,7 @@ func (ref *UsersHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
 		return
 	}
 
@@ -543,7 +1093,7 @@ func (ref *UsersHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -559,6 +1109,333 @@ func (ref *UsersHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 	respond.WriteJSONMessage(w, r, http.StatusNoContent, "User deleted")
 }
 
+// anonymizeUser Anonymize a user
+//
+//	@Id				7a4e9c21-3f6b-4d8e-a1c5-0b9d2e6f4a37
+//	@Summary		Anonymize a user
+//	@Description	Irreversibly replace the personal data of a user with tombstone values and disable the user.
+//	@Description	The user is kept so references to it remain valid
+//	@Tags			Users
+//	@Param			user_id	path	string	true	"The user ID in UUID format"	Format(uuid)
+//	@Produce		json
+//	@Success		200	{object}	respond.HTTPMessage
+//	@Failure		400	{object}	respond.HTTPMessage
+//	@Failure		404	{object}	respond.HTTPMessage
+//	@Failure		500	{object}	respond.HTTPMessage
+//	@Router			/users/{user_id}/anonymize [post]
+func (ref *UsersHandler) anonymizeUser(w http.ResponseWriter, r *http.Request) {
+	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), "handler.Users.anonymizeUser")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "handler.Users.anonymizeUser"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", "/users/{user_id}/anonymize"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "handler.Users.anonymizeUser"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", "/users/{user_id}/anonymize"),
+	}
+
+	id, err := parseUUIDQueryParams(r.PathValue("user_id"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.anonymizeUser", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := ref.service.Anonymize(ctx, id); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.anonymizeUser", "error", err.Error())
+
+		if errors.Is(err, service.ErrUserNotFound) {
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusNotFound)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusNotFound, respond.CodeNotFound, err.Error())
+			return
+		}
+
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	span.SetStatus(codes.Ok, "User anonymized")
+	span.SetAttributes(attribute.String("user.id", id.String()))
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+
+	respond.WriteJSONMessage(w, r, http.StatusOK, "User anonymized")
+}
+
+// disableUser Disable a user
+//
+//	@Id				3e8d1b6a-7c2f-4a95-b0e4-9f6c2d1a8b57
+//	@Summary		Disable a user
+//	@Description	Disable a user, keeping its data. Disabling a disabled user succeeds
+//	@Tags			Users
+//	@Param			user_id	path	string	true	"The user ID in UUID format"	Format(uuid)
+//	@Produce		json
+//	@Success		200	{object}	respond.HTTPMessage
+//	@Failure		400	{object}	respond.HTTPMessage
+//	@Failure		404	{object}	respond.HTTPMessage
+//	@Failure		500	{object}	respond.HTTPMessage
+//	@Router			/users/{user_id}/disable [post]
+func (ref *UsersHandler) disableUser(w http.ResponseWriter, r *http.Request) {
+	ref.setUserDisabled(w, r, true)
+}
+
+// enableUser Enable a user
+//
+//	@Id				a95c4f27-1d8e-4b63-8e2a-5c7b0f9d3e14
+//	@Summary		Enable a user
+//	@Description	Enable a disabled user. Enabling an enabled user succeeds
+//	@Tags			Users
+//	@Param			user_id	path	string	true	"The user ID in UUID format"	Format(uuid)
+//	@Produce		json
+//	@Success		200	{object}	respond.HTTPMessage
+//	@Failure		400	{object}	respond.HTTPMessage
+//	@Failure		404	{object}	respond.HTTPMessage
+//	@Failure		500	{object}	respond.HTTPMessage
+//	@Router			/users/{user_id}/enable [post]
+func (ref *UsersHandler) enableUser(w http.ResponseWriter, r *http.Request) {
+	ref.setUserDisabled(w, r, false)
+}
+
+// setUserDisabled sets the disabled flag of the user in the path.
+func (ref *UsersHandler) setUserDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	component, path, message := "handler.Users.enableUser", "/users/{user_id}/enable", "User enabled"
+	if disabled {
+		component, path, message = "handler.Users.disableUser", "/users/{user_id}/disable", "User disabled"
+	}
+
+	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), component)
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", component),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", path),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", component),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", path),
+	}
+
+	id, err := parseUUIDQueryParams(r.PathValue("user_id"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error(component, "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := ref.service.Update(ctx, &service.UpdateUserInput{ID: id, Disabled: &disabled}); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error(component, "error", err.Error())
+
+		if errors.Is(err, service.ErrUserNotFound) {
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusNotFound)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusNotFound, respond.CodeNotFound, err.Error())
+			return
+		}
+
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	span.SetStatus(codes.Ok, message)
+	span.SetAttributes(attribute.String("user.id", id.String()))
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+
+	w.Header().Set("Location", userLocation(r, id))
+	respond.WriteJSONMessage(w, r, http.StatusOK, message)
+}
+
+// bulkDeleteUsers Delete a list of users
+//
+//	@Id				0d3b7f4e-5c1a-4f0e-9d2b-6a8e4c1f7b93
+//	@Summary		Delete a list of users
+//	@Description	Delete a list of users in a single transaction and report the result for every ID
+//	@Tags			Users
+//	@Accept			json
+//	@Produce		json
+//	@Param			dry_run	query		bool					false	"Report what would be deleted without deleting anything"	Format(boolean)
+//	@Param			ids		body		BulkDeleteUsersRequest	true	"User IDs"													Format(json)
+//	@Success		200		{object}	BulkDeleteUsersResponse
+//	@Failure		400		{object}	respond.HTTPMessage
+//	@Failure		422		{object}	respond.HTTPMessage
+//	@Failure		500		{object}	respond.HTTPMessage
+//	@Router			/users/bulk-delete [post]
+func (ref *UsersHandler) bulkDeleteUsers(w http.ResponseWriter, r *http.Request) {
+	ctx, span := ref.ot.Traces.Tracer.Start(r.Context(), "handler.Users.bulkDeleteUsers")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "handler.Users.bulkDeleteUsers"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", r.URL.Path),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "handler.Users.bulkDeleteUsers"),
+		attribute.String("http.method", r.Method),
+		attribute.String("http.path", r.URL.Path),
+	}
+
+	dryRun, err := parseBoolQueryParams(r.URL.Query().Get("dry_run"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.bulkDeleteUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	var req BulkDeleteUsersRequest
+	if code, err := decodeJSONBody(r, &req); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.bulkDeleteUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, code, err.Error())
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.bulkDeleteUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusUnprocessableEntity)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusUnprocessableEntity, respond.CodeUnprocessableEntity, err.Error())
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int("users.count", len(req.IDs)),
+		attribute.Bool("dry_run", dryRun),
+	)
+
+	out, err := ref.service.BulkDelete(ctx, &service.BulkDeleteUsersInput{
+		IDs:    req.IDs,
+		DryRun: dryRun,
+	})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.bulkDeleteUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	res := BulkDeleteUsersResponse{
+		DryRun: out.DryRun,
+		Items:  make([]BulkDeleteUserResult, len(out.Items)),
+	}
+
+	for i, item := range out.Items {
+		res.Items[i] = BulkDeleteUserResult{
+			ID:     item.ID,
+			Status: "not_found",
+		}
+
+		if item.Deleted {
+			res.Items[i].Status = "deleted"
+		}
+	}
+
+	if err := respond.WriteJSON(w, http.StatusOK, res); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.bulkDeleteUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	span.SetStatus(codes.Ok, "Users deleted")
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+}
+
 // listUsers Return a paginated list of users
 //
 //	@Id				1213ffb2-b9f3-4134-923e-13bb777da62b
@@ -566,13 +1443,15 @@ func (ref *UsersHandler) deleteUser(w http.ResponseWriter, r *http.Request) {
 //	@Description	List all users
 //	@Tags			Users
 //	@Produce		json
-//	@Param			sort		query		string	false	"Comma-separated list of fields to sort by. Example: first_name ASC, created_at DESC"	Format(string)
+//	@Param			sort		query		string	false	"Comma-separated list of fields to sort by, with optional NULLS FIRST or NULLS LAST. Example: first_name ASC, updated_at DESC NULLS LAST"	Format(string)
 //	@Param			filter		query		string	false	"Filter field. Example: id=1 AND first_name='John'"										Format(string)
 //	@Param			fields		query		string	false	"Fields to return. Example: id,first_name,last_name"									Format(string)
 //	@Param			next_token	query		string	false	"Next cursor"																			Format(string)
 //	@Param			prev_token	query		string	false	"Previous cursor"																		Format(string)
 //	@Param			limit		query		int		false	"Limit"																					Format(int)
+//	@Param			explain		query		bool	false	"Return the normalized filter and sort and the number of matching users, without the users"	Format(boolean)
 //	@Success		200			{object}	ListUsersResponse
+//	@Success		200			{object}	ListUsersExplainResponse
 //	@Failure		400			{object}	respond.HTTPMessage
 //	@Failure		500			{object}	respond.HTTPMessage
 //	@Router			/users [get]
@@ -594,7 +1473,7 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 
 	// parse the query parameters
 	params := map[string]any{
-		"sort":      r.URL.Query().Get("sort"),
+		"sort":      query.ApplySortDefaults(r.URL.Query().Get("sort"), ref.sortDefaultDirection, ref.sortDefaultNulls),
 		"filter":    r.URL.Query().Get("filter"),
 		"fields":    r.URL.Query().Get("fields"),
 		"nextToken": r.URL.Query().Get("next_token"),
@@ -607,6 +1486,7 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 		repository.UserPartialFields,
 		repository.UserFilterFields,
 		repository.UserSortFields,
+		ref.tokenMaxLength,
 	)
 	if err != nil {
 		span.SetStatus(codes.Error, err.Error())
@@ -618,7 +1498,69 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusBadRequest, err.Error())
+		code := respond.CodeBadRequest
+		switch {
+		case errors.Is(err, ErrInvalidLimit):
+			code = respond.CodeInvalidLimit
+		case errors.Is(err, ErrTokenTooLong):
+			code = respond.CodeTokenTooLong
+		}
+
+		respond.WriteError(w, r, http.StatusBadRequest, code, err.Error())
+		return
+	}
+
+	if ref.filterMaxPredicates > 0 && query.CountFilterPredicates(filter) > ref.filterMaxPredicates {
+		err := fmt.Errorf("%w, the maximum is %d", ErrTooManyFilterPredicates, ref.filterMaxPredicates)
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.listUsers", "error", err.Error(), "filter", filter)
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	if query.HasLeadingWildcard(filter) {
+		if ref.rejectLeadingWildcard {
+			span.SetStatus(codes.Error, ErrLeadingWildcardFilter.Error())
+			span.RecordError(ErrLeadingWildcardFilter)
+			slog.Error("handler.Users.listUsers", "error", ErrLeadingWildcardFilter.Error(), "filter", filter)
+			ref.metrics.handlerCalls.Add(ctx, 1,
+				metric.WithAttributes(
+					append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+				),
+			)
+
+			respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, ErrLeadingWildcardFilter.Error())
+			return
+		}
+
+		slog.Warn("handler.Users.listUsers", "message", "filter with leading wildcard cannot use an index", "filter", filter)
+	}
+
+	explain, err := parseBoolQueryParams(r.URL.Query().Get("explain"))
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("handler.Users.listUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusBadRequest)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, err.Error())
+		return
+	}
+
+	// explain reports what the list would match without fetching the users
+	if explain {
+		ref.writeListUsersExplain(ctx, w, r, sort, filter, fields, limit, metricCommonAttributes)
 		return
 	}
 
@@ -644,7 +1586,7 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -669,7 +1611,7 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 	location := fmt.Sprintf("http://%s%s", r.Host, r.URL.Path)
 	users.Paginator.GeneratePages(location)
 
-	if err := respond.WriteJSONData(w, http.StatusOK, users); err != nil {
+	if err := respond.WriteJSON(w, http.StatusOK, users); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		span.RecordError(err)
 		slog.Error("handler.Users.listUsers", "error", err.Error())
@@ -679,7 +1621,7 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 			),
 		)
 
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, err.Error())
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, err.Error())
 		return
 	}
 
@@ -692,3 +1634,46 @@ func (ref *UsersHandler) listUsers(w http.ResponseWriter, r *http.Request) {
 		),
 	)
 }
+
+// writeListUsersExplain writes the normalized list parameters and the number of users they match.
+func (ref *UsersHandler) writeListUsersExplain(ctx context.Context, w http.ResponseWriter, r *http.Request, sort, filter string, fields []string, limit int, metricCommonAttributes []attribute.KeyValue) {
+	count, err := ref.service.Count(ctx, &service.CountUsersInput{Filter: filter})
+	if err != nil {
+		slog.Error("handler.Users.listUsers", "error", err.Error())
+		ref.metrics.handlerCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusInternalServerError)))...,
+			),
+		)
+
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
+		return
+	}
+
+	explain := &ListUsersExplainResponse{
+		Filter: query.NormalizeFilter(filter),
+		Sort:   sort,
+		Fields: make([]string, 0, len(fields)),
+		Limit:  limit,
+
+		Count:           count.Count,
+		CountIsEstimate: count.Estimate,
+	}
+
+	for _, field := range fields {
+		if field != "" {
+			explain.Fields = append(explain.Fields, field)
+		}
+	}
+
+	slog.Debug("handler.Users.listUsers", "message", "explain", "users.count", count.Count, "users.count.estimate", count.Estimate)
+	ref.metrics.handlerCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("code", fmt.Sprintf("%d", http.StatusOK)))...,
+		),
+	)
+
+	if err := respond.WriteJSON(w, http.StatusOK, explain); err != nil {
+		slog.Error("handler.Users.listUsers", "error", err.Error())
+	}
+}
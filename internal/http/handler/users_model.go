@@ -9,28 +9,23 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/model"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
 )
 
-const (
-	UserFirstNameMinLength = 2
-	UserFirstNameMaxLength = 25
-	UserLastNameMinLength  = 2
-	UserLastNameMaxLength  = 25
-	UserEmailMinLength     = 6
-	UserEmailMaxLength     = 50
-	UserPasswordMinLength  = 6
-	UserPasswordMaxLength  = 255
-)
-
+// The errors returned by the Validate methods of the requests are semantic
+// validation errors, reported as 422 Unprocessable Entity. Malformed JSON
+// bodies and path or query parameters are reported as 400 Bad Request.
 var (
 	ErrUserInvalidID            = errors.New("invalid user ID, this must be a valid UUID")
-	ErrUserInvalidFirstName     = errors.New("invalid user first name. Must be between" + fmt.Sprintf("%d and %d", UserFirstNameMinLength, UserFirstNameMaxLength) + "characters long")
-	ErrUserInvalidLastName      = errors.New("invalid user last name. Must be between" + fmt.Sprintf("%d and %d", UserLastNameMinLength, UserLastNameMaxLength) + "characters long")
-	ErrUserInvalidEmail         = errors.New("invalid user email. Must be between" + fmt.Sprintf("%d and %d", UserEmailMinLength, UserEmailMaxLength) + "characters long")
-	ErrUserInvalidPassword      = errors.New("invalid user password. Must be at least" + fmt.Sprintf("%d characters long", UserPasswordMinLength) + "characters long")
+	ErrUserInvalidFirstName     = errors.New("invalid user first name. Must be between " + fmt.Sprintf("%d and %d", model.UserFirstNameMinLength, model.UserFirstNameMaxLength) + " characters long")
+	ErrUserInvalidLastName      = errors.New("invalid user last name. Must be between " + fmt.Sprintf("%d and %d", model.UserLastNameMinLength, model.UserLastNameMaxLength) + " characters long")
+	ErrUserInvalidEmail         = errors.New("invalid user email. Must be between " + fmt.Sprintf("%d and %d", model.UserEmailMinLength, model.UserEmailMaxLength) + " characters long")
+	ErrUserInvalidPassword      = errors.New("invalid user password. Must be between " + fmt.Sprintf("%d and %d", model.UserPasswordMinLength, model.UserPasswordMaxLength) + " characters long")
 	ErrUserInvalidService       = errors.New("invalid service")
 	ErrUserInvalidOpenTelemetry = errors.New("invalid open telemetry")
+	ErrUserInvalidIDs           = errors.New("invalid user IDs. Must be between 1 and " + fmt.Sprintf("%d", model.UserBulkDeleteMaxItems) + " valid UUIDs")
+	ErrUserIDMismatch           = errors.New("invalid user ID, the ID in the body must match the ID in the path")
 )
 
 // User represents a user entity used to model the data stored in the database.
@@ -83,6 +78,14 @@ func (ref User) MarshalJSON() ([]byte, error) {
 	return json.Marshal(omitted)
 }
 
+// UserExport represents the data held about a user, exported for data subject access requests.
+//
+// @Description UserExport represents the data held about a user
+type UserExport struct {
+	ExportedAt time.Time `json:"exported_at" example:"2021-01-01T00:00:00Z" format:"date-time"`
+	User       User      `json:"user"`
+}
+
 // CreateUserRequest represents the input for the CreateUser method.
 //
 // @Description CreateUserRequest represents the input for the CreateUser method
@@ -100,16 +103,16 @@ func (req *CreateUserRequest) Validate() error {
 		return ErrUserInvalidID
 	}
 
-	if len(req.FirstName) < UserFirstNameMinLength || len(req.FirstName) > UserFirstNameMaxLength {
+	if len(req.FirstName) < model.UserFirstNameMinLength || len(req.FirstName) > model.UserFirstNameMaxLength {
 		return ErrUserInvalidFirstName
 	}
 
-	if len(req.LastName) < UserLastNameMinLength || len(req.LastName) > UserLastNameMaxLength {
+	if len(req.LastName) < model.UserLastNameMinLength || len(req.LastName) > model.UserLastNameMaxLength {
 		return ErrUserInvalidLastName
 	}
 
 	// minimal email validation
-	if len(req.Email) < UserEmailMinLength || len(req.Email) > UserEmailMaxLength {
+	if len(req.Email) < model.UserEmailMinLength || len(req.Email) > model.UserEmailMaxLength {
 		return ErrUserInvalidEmail
 	}
 
@@ -118,7 +121,51 @@ func (req *CreateUserRequest) Validate() error {
 		return ErrUserInvalidEmail
 	}
 
-	if len(req.Password) < UserPasswordMinLength {
+	if len(req.Password) < model.UserPasswordMinLength || len(req.Password) > model.UserPasswordMaxLength {
+		return ErrUserInvalidPassword
+	}
+
+	return nil
+}
+
+// UpsertUserRequest represents the input for the UpsertUser method.
+// The ID is optional, and must match the ID in the path when provided.
+//
+// @Description UpsertUserRequest represents the input for the UpsertUser method
+type UpsertUserRequest struct {
+	ID        uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	FirstName string    `json:"first_name" example:"John" format:"string"`
+	LastName  string    `json:"last_name" example:"Doe" format:"string"`
+	Email     string    `json:"email" example:"my@email.com" format:"email"`
+	Password  string    `json:"password" example:"ThisIs4Passw0rd" format:"string"`
+	Disabled  bool      `json:"disabled" example:"false" format:"boolean"`
+}
+
+// Validate validates the UpsertUserRequest.
+func (req *UpsertUserRequest) Validate() error {
+	if req.ID == uuid.Nil {
+		return ErrUserInvalidID
+	}
+
+	if len(req.FirstName) < model.UserFirstNameMinLength || len(req.FirstName) > model.UserFirstNameMaxLength {
+		return ErrUserInvalidFirstName
+	}
+
+	if len(req.LastName) < model.UserLastNameMinLength || len(req.LastName) > model.UserLastNameMaxLength {
+		return ErrUserInvalidLastName
+	}
+
+	// minimal email validation
+	if len(req.Email) < model.UserEmailMinLength || len(req.Email) > model.UserEmailMaxLength {
+		return ErrUserInvalidEmail
+	}
+
+	_, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		return ErrUserInvalidEmail
+	}
+
+	if len(req.Password) < model.UserPasswordMinLength || len(req.Password) > model.UserPasswordMaxLength {
 		return ErrUserInvalidPassword
 	}
 
@@ -142,26 +189,26 @@ func (req *UpdateUserRequest) Validate() error {
 	}
 
 	if req.FirstName != nil {
-		if len(*req.FirstName) < UserFirstNameMinLength || len(*req.FirstName) > UserFirstNameMaxLength {
+		if len(*req.FirstName) < model.UserFirstNameMinLength || len(*req.FirstName) > model.UserFirstNameMaxLength {
 			return ErrUserInvalidFirstName
 		}
 	}
 
 	if req.LastName != nil {
-		if len(*req.LastName) < UserLastNameMinLength || len(*req.LastName) > UserLastNameMaxLength {
+		if len(*req.LastName) < model.UserLastNameMinLength || len(*req.LastName) > model.UserLastNameMaxLength {
 			return ErrUserInvalidLastName
 		}
 	}
 
 	// minimal email validation
 	if req.Email != nil {
-		if len(*req.Email) < UserEmailMinLength || len(*req.Email) > UserEmailMaxLength {
+		if len(*req.Email) < model.UserEmailMinLength || len(*req.Email) > model.UserEmailMaxLength {
 			return ErrUserInvalidEmail
 		}
 	}
 
 	if req.Email != nil {
-		if len(*req.Email) >= UserEmailMinLength && len(*req.Email) <= UserEmailMaxLength {
+		if len(*req.Email) >= model.UserEmailMinLength && len(*req.Email) <= model.UserEmailMaxLength {
 			_, err := mail.ParseAddress(*req.Email)
 			if err != nil {
 				return ErrUserInvalidEmail
@@ -169,9 +216,69 @@ func (req *UpdateUserRequest) Validate() error {
 		}
 	}
 
+	if req.Password != nil {
+		if len(*req.Password) < model.UserPasswordMinLength || len(*req.Password) > model.UserPasswordMaxLength {
+			return ErrUserInvalidPassword
+		}
+	}
+
 	return nil
 }
 
+// BulkDeleteUsersRequest represents the input for the BulkDeleteUsers method.
+//
+// @Description BulkDeleteUsersRequest represents the input for the BulkDeleteUsers method
+type BulkDeleteUsersRequest struct {
+	IDs []uuid.UUID `json:"ids" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+}
+
+// Validate validates the BulkDeleteUsersRequest.
+func (req *BulkDeleteUsersRequest) Validate() error {
+	if len(req.IDs) == 0 || len(req.IDs) > model.UserBulkDeleteMaxItems {
+		return ErrUserInvalidIDs
+	}
+
+	for _, id := range req.IDs {
+		if id == uuid.Nil {
+			return ErrUserInvalidIDs
+		}
+	}
+
+	return nil
+}
+
+// BulkDeleteUserResult represents the result of deleting a single user.
+//
+// @Description BulkDeleteUserResult represents the result of deleting a single user
+type BulkDeleteUserResult struct {
+	ID     uuid.UUID `json:"id" example:"550e8400-e29b-41d4-a716-446655440000" format:"uuid"`
+	Status string    `json:"status" example:"deleted" enums:"deleted,not_found"`
+}
+
+// BulkDeleteUsersResponse represents the result of a bulk delete.
+//
+// @Description BulkDeleteUsersResponse represents the result of a bulk delete
+type BulkDeleteUsersResponse struct {
+	DryRun bool                   `json:"dry_run" example:"false" format:"boolean"`
+	Items  []BulkDeleteUserResult `json:"items"`
+}
+
+// ListUsersExplainResponse represents the effect of the list parameters,
+// without the users.
+//
+// @Description ListUsersExplainResponse represents the effect of the list parameters, without the users
+type ListUsersExplainResponse struct {
+	Filter string   `json:"filter" example:"first_name = 'Alice' AND disabled = 0"`
+	Sort   string   `json:"sort" example:"first_name ASC, updated_at DESC NULLS LAST"`
+	Fields []string `json:"fields" example:"id,first_name"`
+	Limit  int      `json:"limit" example:"10"`
+
+	// Count is estimated from the table statistics, ignoring the filter,
+	// when CountIsEstimate is true because the exact count took too long
+	Count           int64 `json:"count" example:"42"`
+	CountIsEstimate bool  `json:"count_is_estimate" example:"false"`
+}
+
 // ListUsersResponse represents a list of users.
 //
 // @Description ListUsersResponse represents a list of users
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/model"
+)
+
+// emailOfLength returns a valid email address with exactly n characters.
+func emailOfLength(n int) string {
+	const domain = "@a.io"
+	return strings.Repeat("a", n-len(domain)) + domain
+}
+
+func TestCreateUserRequest_ValidateBoundaries(t *testing.T) {
+	valid := func() CreateUserRequest {
+		return CreateUserRequest{
+			ID:        uuid.New(),
+			FirstName: "John",
+			LastName:  "Doe",
+			Email:     "john.doe@mail.com",
+			Password:  "ThisIs4Passw0rd",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(req *CreateUserRequest)
+		want   error
+	}{
+		{"first name min", func(req *CreateUserRequest) { req.FirstName = strings.Repeat("a", model.UserFirstNameMinLength) }, nil},
+		{"first name below min", func(req *CreateUserRequest) { req.FirstName = strings.Repeat("a", model.UserFirstNameMinLength-1) }, ErrUserInvalidFirstName},
+		{"first name max", func(req *CreateUserRequest) { req.FirstName = strings.Repeat("a", model.UserFirstNameMaxLength) }, nil},
+		{"first name above max", func(req *CreateUserRequest) { req.FirstName = strings.Repeat("a", model.UserFirstNameMaxLength+1) }, ErrUserInvalidFirstName},
+		{"last name min", func(req *CreateUserRequest) { req.LastName = strings.Repeat("a", model.UserLastNameMinLength) }, nil},
+		{"last name below min", func(req *CreateUserRequest) { req.LastName = strings.Repeat("a", model.UserLastNameMinLength-1) }, ErrUserInvalidLastName},
+		{"last name max", func(req *CreateUserRequest) { req.LastName = strings.Repeat("a", model.UserLastNameMaxLength) }, nil},
+		{"last name above max", func(req *CreateUserRequest) { req.LastName = strings.Repeat("a", model.UserLastNameMaxLength+1) }, ErrUserInvalidLastName},
+		{"email min", func(req *CreateUserRequest) { req.Email = emailOfLength(model.UserEmailMinLength) }, nil},
+		{"email below min", func(req *CreateUserRequest) { req.Email = emailOfLength(model.UserEmailMinLength - 1) }, ErrUserInvalidEmail},
+		{"email max", func(req *CreateUserRequest) { req.Email = emailOfLength(model.UserEmailMaxLength) }, nil},
+		{"email above max", func(req *CreateUserRequest) { req.Email = emailOfLength(model.UserEmailMaxLength + 1) }, ErrUserInvalidEmail},
+		{"password min", func(req *CreateUserRequest) { req.Password = strings.Repeat("a", model.UserPasswordMinLength) }, nil},
+		{"password below min", func(req *CreateUserRequest) { req.Password = strings.Repeat("a", model.UserPasswordMinLength-1) }, ErrUserInvalidPassword},
+		{"password max", func(req *CreateUserRequest) { req.Password = strings.Repeat("a", model.UserPasswordMaxLength) }, nil},
+		{"password above max", func(req *CreateUserRequest) { req.Password = strings.Repeat("a", model.UserPasswordMaxLength+1) }, ErrUserInvalidPassword},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid()
+			tc.modify(&req)
+
+			if err := req.Validate(); !errors.Is(err, tc.want) {
+				t.Errorf("expected error %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserRequest_ValidateBoundaries(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name string
+		req  UpdateUserRequest
+		want error
+	}{
+		{"first name min", UpdateUserRequest{FirstName: str(strings.Repeat("a", model.UserFirstNameMinLength))}, nil},
+		{"first name below min", UpdateUserRequest{FirstName: str(strings.Repeat("a", model.UserFirstNameMinLength-1))}, ErrUserInvalidFirstName},
+		{"first name max", UpdateUserRequest{FirstName: str(strings.Repeat("a", model.UserFirstNameMaxLength))}, nil},
+		{"first name above max", UpdateUserRequest{FirstName: str(strings.Repeat("a", model.UserFirstNameMaxLength+1))}, ErrUserInvalidFirstName},
+		{"last name min", UpdateUserRequest{LastName: str(strings.Repeat("a", model.UserLastNameMinLength))}, nil},
+		{"last name below min", UpdateUserRequest{LastName: str(strings.Repeat("a", model.UserLastNameMinLength-1))}, ErrUserInvalidLastName},
+		{"last name max", UpdateUserRequest{LastName: str(strings.Repeat("a", model.UserLastNameMaxLength))}, nil},
+		{"last name above max", UpdateUserRequest{LastName: str(strings.Repeat("a", model.UserLastNameMaxLength+1))}, ErrUserInvalidLastName},
+		{"email min", UpdateUserRequest{Email: str(emailOfLength(model.UserEmailMinLength))}, nil},
+		{"email below min", UpdateUserRequest{Email: str(emailOfLength(model.UserEmailMinLength - 1))}, ErrUserInvalidEmail},
+		{"email max", UpdateUserRequest{Email: str(emailOfLength(model.UserEmailMaxLength))}, nil},
+		{"email above max", UpdateUserRequest{Email: str(emailOfLength(model.UserEmailMaxLength + 1))}, ErrUserInvalidEmail},
+		{"password min", UpdateUserRequest{Password: str(strings.Repeat("a", model.UserPasswordMinLength))}, nil},
+		{"password below min", UpdateUserRequest{Password: str(strings.Repeat("a", model.UserPasswordMinLength-1))}, ErrUserInvalidPassword},
+		{"password max", UpdateUserRequest{Password: str(strings.Repeat("a", model.UserPasswordMaxLength))}, nil},
+		{"password above max", UpdateUserRequest{Password: str(strings.Repeat("a", model.UserPasswordMaxLength+1))}, ErrUserInvalidPassword},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.req.Validate(); !errors.Is(err, tc.want) {
+				t.Errorf("expected error %v, got %v", tc.want, err)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strconv"
 	"strings"
 	"testing"
@@ -15,7 +16,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/config"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/model"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/service"
 	mocksService "github.com/p2p-b2b/go-rest-api-service-template/mocks/handler"
 	gomock "go.uber.org/mock/gomock"
@@ -28,17 +31,15 @@ func startsWith(value int, start int) bool {
 	return len(numStr) > 0 && strings.HasPrefix(numStr, startStr)
 }
 
-func TestUser_GetByID(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-	mockService := mocksService.NewMockUsersService(ctrl)
-	ctx := context.TODO()
+// newTestTelemetry returns a started telemetry exporting to the console.
+func newTestTelemetry(t *testing.T) *o11y.OpenTelemetry {
+	t.Helper()
 
 	otConfig := config.NewOpenTelemetryConfig("test", "1.0.0")
 	otConfig.TraceExporter.Value = "console"
 	otConfig.MetricExporter.Value = "console"
 
-	telemetry, err := o11y.New(ctx, otConfig)
+	telemetry, err := o11y.New(context.TODO(), otConfig)
 	if err != nil {
 		t.Fatalf("could not create telemetry: %v", err)
 	}
@@ -47,6 +48,38 @@ func TestUser_GetByID(t *testing.T) {
 		t.Fatalf("could not start telemetry: %v", err)
 	}
 
+	return telemetry
+}
+
+// newTestUsersHandler returns a users handler built from conf.
+func newTestUsersHandler(t *testing.T, conf UsersHandlerConf) *UsersHandler {
+	t.Helper()
+
+	h, err := NewUsersHandler(conf)
+	if err != nil {
+		t.Fatalf("could not create user handler: %v", err)
+	}
+
+	return h
+}
+
+// newTestUsersMux returns a mux with the routes of a users handler built from conf.
+func newTestUsersMux(t *testing.T, conf UsersHandlerConf) *http.ServeMux {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	newTestUsersHandler(t, conf).RegisterRoutes(mux)
+
+	return mux
+}
+
+func TestUser_GetByID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
 	t.Run("GetByID", func(t *testing.T) {
 		type test struct {
 			name         string
@@ -186,10 +219,7 @@ func TestUser_GetByID(t *testing.T) {
 
 				// When
 				mux := http.NewServeMux()
-				h, err := NewUsersHandler(userHandlerConf)
-				if err != nil {
-					t.Fatalf("could not create user handler: %v", err)
-				}
+				h := newTestUsersHandler(t, userHandlerConf)
 				mux.HandleFunc(handlerPattern, h.getByID)
 				mux.ServeHTTP(w, r)
 
@@ -261,3 +291,1468 @@ func TestUser_GetByID(t *testing.T) {
 		}
 	})
 }
+
+func TestUser_BulkDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	existingID := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+	missingID := uuid.Must(uuid.Parse("9a0f2c6e-3b1d-4e8a-8c5f-7d2e1b4a6c90"))
+
+	tooManyIDs := make([]string, model.UserBulkDeleteMaxItems+1)
+	for i := range tooManyIDs {
+		tooManyIDs[i] = fmt.Sprintf("%q", uuid.New().String())
+	}
+
+	type test struct {
+		name        string
+		target      string
+		body        string
+		statusCode  int
+		apiError    respond.HTTPMessage
+		apiResponse BulkDeleteUsersResponse
+		mockCall    *gomock.Call
+	}
+
+	tests := []test{
+		{
+			name:       "empty ids, unprocessable entity",
+			target:     "/users/bulk-delete",
+			body:       `{"ids":[]}`,
+			statusCode: http.StatusUnprocessableEntity,
+			apiError: respond.HTTPMessage{
+				Method:     "POST",
+				Path:       "/users/bulk-delete",
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    ErrUserInvalidIDs.Error(),
+			},
+		},
+		{
+			name:       "too many ids, unprocessable entity",
+			target:     "/users/bulk-delete",
+			body:       `{"ids":[` + strings.Join(tooManyIDs, ",") + `]}`,
+			statusCode: http.StatusUnprocessableEntity,
+			apiError: respond.HTTPMessage{
+				Method:     "POST",
+				Path:       "/users/bulk-delete",
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    ErrUserInvalidIDs.Error(),
+			},
+		},
+		{
+			name:       "invalid dry_run, bad request",
+			target:     "/users/bulk-delete?dry_run=maybe",
+			body:       `{"ids":["` + existingID.String() + `"]}`,
+			statusCode: http.StatusBadRequest,
+			apiError: respond.HTTPMessage{
+				Method:     "POST",
+				Path:       "/users/bulk-delete",
+				StatusCode: http.StatusBadRequest,
+				Message:    "invalid boolean value: maybe",
+			},
+		},
+		{
+			name:       "mix of existing and nonexistent ids",
+			target:     "/users/bulk-delete",
+			body:       `{"ids":["` + existingID.String() + `","` + missingID.String() + `"]}`,
+			statusCode: http.StatusOK,
+			apiResponse: BulkDeleteUsersResponse{
+				DryRun: false,
+				Items: []BulkDeleteUserResult{
+					{ID: existingID, Status: "deleted"},
+					{ID: missingID, Status: "not_found"},
+				},
+			},
+			mockCall: mockService.
+				EXPECT().
+				BulkDelete(
+					gomock.Any(),
+					&service.BulkDeleteUsersInput{IDs: []uuid.UUID{existingID, missingID}},
+				).
+				Return(&service.BulkDeleteUsersOutput{
+					Items: []service.BulkDeleteUserResult{
+						{ID: existingID, Deleted: true},
+						{ID: missingID, Deleted: false},
+					},
+				}, nil).
+				Times(1),
+		},
+		{
+			name:       "mix of existing and nonexistent ids, dry run",
+			target:     "/users/bulk-delete?dry_run=true",
+			body:       `{"ids":["` + existingID.String() + `","` + missingID.String() + `"]}`,
+			statusCode: http.StatusOK,
+			apiResponse: BulkDeleteUsersResponse{
+				DryRun: true,
+				Items: []BulkDeleteUserResult{
+					{ID: existingID, Status: "deleted"},
+					{ID: missingID, Status: "not_found"},
+				},
+			},
+			mockCall: mockService.
+				EXPECT().
+				BulkDelete(
+					gomock.Any(),
+					&service.BulkDeleteUsersInput{IDs: []uuid.UUID{existingID, missingID}, DryRun: true},
+				).
+				Return(&service.BulkDeleteUsersOutput{
+					DryRun: true,
+					Items: []service.BulkDeleteUserResult{
+						{ID: existingID, Deleted: true},
+						{ID: missingID, Deleted: false},
+					},
+				}, nil).
+				Times(1),
+		},
+		{
+			name:       "service fail with error, return internal server error",
+			target:     "/users/bulk-delete",
+			body:       `{"ids":["` + existingID.String() + `"]}`,
+			statusCode: http.StatusInternalServerError,
+			apiError: respond.HTTPMessage{
+				Method:     "POST",
+				Path:       "/users/bulk-delete",
+				StatusCode: http.StatusInternalServerError,
+				Message:    "internal server error",
+			},
+			mockCall: mockService.
+				EXPECT().
+				BulkDelete(
+					gomock.Any(),
+					&service.BulkDeleteUsersInput{IDs: []uuid.UUID{existingID}},
+				).
+				Return(nil, ErrInternalServerError).
+				Times(1),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r, err := http.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			if tc.mockCall != nil {
+				gomock.InOrder(tc.mockCall)
+			}
+
+			// When
+			mux := http.NewServeMux()
+			h := newTestUsersHandler(t, UsersHandlerConf{
+				Service: mockService,
+				OT:      telemetry,
+			})
+			mux.HandleFunc("POST /users/bulk-delete", h.bulkDeleteUsers)
+			mux.ServeHTTP(w, r)
+
+			// Then
+			t.Logf("body = %s", w.Body.String())
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, w.Code)
+			}
+
+			if !startsWith(w.Code, 2) {
+				var apiError respond.HTTPMessage
+				if err := json.Unmarshal(w.Body.Bytes(), &apiError); err != nil {
+					t.Fatalf("could not decode response: %v", err)
+				}
+
+				if apiError.Message != tc.apiError.Message {
+					t.Errorf("expected message %q, got %q", tc.apiError.Message, apiError.Message)
+				}
+
+				if apiError.Method != tc.apiError.Method {
+					t.Errorf("expected method %q, got %q", tc.apiError.Method, apiError.Method)
+				}
+
+				if apiError.Path != tc.apiError.Path {
+					t.Errorf("expected path %q, got %q", tc.apiError.Path, apiError.Path)
+				}
+
+				return
+			}
+
+			var res BulkDeleteUsersResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if diff := cmp.Diff(tc.apiResponse, res); diff != "" {
+				t.Errorf("unexpected response (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUser_Export(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+
+	tests := []struct {
+		name       string
+		statusCode int
+		want       User
+		mockCall   *gomock.Call
+	}{
+		{
+			name:       "user not found",
+			statusCode: http.StatusNotFound,
+			mockCall: mockService.
+				EXPECT().
+				GetByID(gomock.Any(), id).
+				Return(nil, service.ErrUserNotFound).
+				Times(1),
+		},
+		{
+			name:       "export includes the user profile",
+			statusCode: http.StatusOK,
+			want: User{
+				ID:        id,
+				FirstName: "John",
+				LastName:  "Doe",
+				Email:     "jonh.doe@mail.com",
+				CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+				UpdatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			mockCall: mockService.
+				EXPECT().
+				GetByID(gomock.Any(), id).
+				Return(&service.User{
+					ID:        id,
+					FirstName: "John",
+					LastName:  "Doe",
+					Email:     "jonh.doe@mail.com",
+					CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+					UpdatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+				}, nil).
+				Times(1),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r, err := http.NewRequest(http.MethodGet, "/users/"+id.String()+"/export", nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			gomock.InOrder(tc.mockCall)
+
+			// When
+			mux := http.NewServeMux()
+			h := newTestUsersHandler(t, UsersHandlerConf{
+				Service: mockService,
+				OT:      telemetry,
+			})
+			mux.HandleFunc("GET /users/{user_id}/export", h.exportUser)
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, w.Code)
+			}
+
+			if !startsWith(w.Code, 2) {
+				return
+			}
+
+			var export UserExport
+			if err := json.Unmarshal(w.Body.Bytes(), &export); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if export.ExportedAt.IsZero() {
+				t.Error("expected exported_at to be set")
+			}
+
+			if diff := cmp.Diff(tc.want, export.User); diff != "" {
+				t.Errorf("unexpected user (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUser_ListSort(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	tests := []struct {
+		name             string
+		sort             string
+		defaultDirection string
+		defaultNulls     string
+		statusCode       int
+		wantSort         string
+	}{
+		{
+			name:       "lower case direction is normalized",
+			sort:       "first_name desc",
+			statusCode: http.StatusOK,
+			wantSort:   "first_name DESC",
+		},
+		{
+			name:       "extra spaces are trimmed",
+			sort:       "first_name  DESC,  id asc",
+			statusCode: http.StatusOK,
+			wantSort:   "first_name DESC, id ASC",
+		},
+		{
+			name:       "long direction is rejected",
+			sort:       "first_name DESCENDING",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown direction is rejected",
+			sort:       "first_name UP",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "requested nulls placement of a nullable column",
+			sort:       "updated_at desc nulls first",
+			statusCode: http.StatusOK,
+			wantSort:   "updated_at DESC NULLS FIRST",
+		},
+		{
+			name:         "configured nulls placement of a nullable column",
+			sort:         "updated_at DESC",
+			defaultNulls: "LAST",
+			statusCode:   http.StatusOK,
+			wantSort:     "updated_at DESC NULLS LAST",
+		},
+		{
+			name:         "requested nulls placement wins over the configured one",
+			sort:         "updated_at ASC NULLS FIRST, id DESC",
+			defaultNulls: "LAST",
+			statusCode:   http.StatusOK,
+			wantSort:     "updated_at ASC NULLS FIRST, id DESC NULLS LAST",
+		},
+		{
+			name:             "configured direction",
+			sort:             "updated_at, id ASC",
+			defaultDirection: "DESC",
+			statusCode:       http.StatusOK,
+			wantSort:         "updated_at DESC, id ASC",
+		},
+		{
+			name:       "missing direction without a configured one is rejected",
+			sort:       "updated_at",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown nulls placement is rejected",
+			sort:       "updated_at ASC NULLS MIDDLE",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			q := url.Values{"sort": {tc.sort}}
+			r, err := http.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			if tc.wantSort != "" {
+				mockService.
+					EXPECT().
+					List(gomock.Any(), gomock.Cond(func(input *service.ListUsersInput) bool {
+						return input.Sort == tc.wantSort
+					})).
+					Return(&service.ListUsersOutput{}, nil).
+					Times(1)
+			}
+
+			// When
+			mux := http.NewServeMux()
+			h := newTestUsersHandler(t, UsersHandlerConf{
+				Service:              mockService,
+				OT:                   telemetry,
+				SortDefaultDirection: tc.defaultDirection,
+				SortDefaultNulls:     tc.defaultNulls,
+			})
+			mux.HandleFunc("GET /users", h.listUsers)
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ListLeadingWildcard(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	tests := []struct {
+		name       string
+		filter     string
+		reject     bool
+		statusCode int
+	}{
+		{
+			name:       "leading wildcard is rejected when the guard is enabled",
+			filter:     "first_name LIKE '%a%'",
+			reject:     true,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "leading wildcard is allowed when the guard is disabled",
+			filter:     "first_name LIKE '%a%'",
+			reject:     false,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "escaped literal percent is allowed when the guard is enabled",
+			filter:     `first_name LIKE '\%off%'`,
+			reject:     true,
+			statusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			q := url.Values{"filter": {tc.filter}}
+			r, err := http.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			if tc.statusCode == http.StatusOK {
+				mockService.
+					EXPECT().
+					List(gomock.Any(), gomock.Cond(func(input *service.ListUsersInput) bool {
+						return input.Filter == tc.filter
+					})).
+					Return(&service.ListUsersOutput{}, nil).
+					Times(1)
+			}
+
+			// When
+			mux := http.NewServeMux()
+			h := newTestUsersHandler(t, UsersHandlerConf{
+				Service:               mockService,
+				OT:                    telemetry,
+				RejectLeadingWildcard: tc.reject,
+			})
+			mux.HandleFunc("GET /users", h.listUsers)
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ValidationStatus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	id := uuid.New().String()
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		statusCode int
+		code       string
+	}{
+		{
+			name:       "create with malformed JSON, bad request",
+			method:     http.MethodPost,
+			target:     "/users",
+			body:       `{"first_name":`,
+			statusCode: http.StatusBadRequest,
+			code:       respond.CodeMalformedJSON,
+		},
+		{
+			name:       "create with too short password, unprocessable entity",
+			method:     http.MethodPost,
+			target:     "/users",
+			body:       `{"first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"12345"}`,
+			statusCode: http.StatusUnprocessableEntity,
+			code:       respond.CodeUnprocessableEntity,
+		},
+		{
+			name:       "update with invalid UUID, bad request",
+			method:     http.MethodPatch,
+			target:     "/users/not-a-uuid",
+			body:       `{"first_name":"John"}`,
+			statusCode: http.StatusBadRequest,
+			code:       respond.CodeBadRequest,
+		},
+		{
+			name:       "update with malformed JSON, bad request",
+			method:     http.MethodPatch,
+			target:     "/users/" + id,
+			body:       `{"first_name":`,
+			statusCode: http.StatusBadRequest,
+			code:       respond.CodeMalformedJSON,
+		},
+		{
+			name:       "update with too short first name, unprocessable entity",
+			method:     http.MethodPatch,
+			target:     "/users/" + id,
+			body:       `{"first_name":"J"}`,
+			statusCode: http.StatusUnprocessableEntity,
+			code:       respond.CodeUnprocessableEntity,
+		},
+		{
+			name:       "upsert with a different ID in the body, unprocessable entity",
+			method:     http.MethodPut,
+			target:     "/users/" + id,
+			body:       `{"id":"` + uuid.New().String() + `","first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"secret123"}`,
+			statusCode: http.StatusUnprocessableEntity,
+			code:       respond.CodeUnprocessableEntity,
+		},
+		{
+			name:       "bulk delete with malformed JSON, bad request",
+			method:     http.MethodPost,
+			target:     "/users/bulk-delete",
+			body:       `{"ids":[`,
+			statusCode: http.StatusBadRequest,
+			code:       respond.CodeMalformedJSON,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r, err := http.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			// When
+			mux := newTestUsersMux(t, UsersHandlerConf{
+				Service: mockService,
+				OT:      telemetry,
+			})
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if msg.Code != tc.code {
+				t.Errorf("expected code %q, got %q", tc.code, msg.Code)
+			}
+		})
+	}
+}
+
+func TestUser_CreateUpsert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+	body := `{"first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"secret123"}`
+	existing := &service.User{
+		ID:        id,
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john.doe@mail.com",
+		CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	gomock.InOrder(
+		mockService.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1),
+		mockService.EXPECT().Create(gomock.Any(), gomock.Any()).Return(service.ErrUserEmailAlreadyExists).Times(1),
+		mockService.EXPECT().GetByEmail(gomock.Any(), "john.doe@mail.com").Return(existing, nil).Times(1),
+		mockService.EXPECT().Create(gomock.Any(), gomock.Any()).Return(service.ErrUserEmailAlreadyExists).Times(1),
+		mockService.EXPECT().GetByEmail(gomock.Any(), "john.doe@mail.com").Return(existing, nil).Times(1),
+		mockService.EXPECT().Create(gomock.Any(), gomock.Any()).Return(service.ErrUserEmailAlreadyExists).Times(1),
+	)
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		statusCode int
+	}{
+		{
+			name:       "first creation",
+			target:     "/users?upsert=true",
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "second creation with upsert returns the existing user",
+			target:     "/users?upsert=true",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "second creation with If-Not-Exists returns the existing user",
+			target:     "/users",
+			header:     "true",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "second creation without hint is a conflict",
+			target:     "/users",
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "invalid upsert hint",
+			target:     "/users?upsert=maybe",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, tc.target, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			if tc.header != "" {
+				r.Header.Set("If-Not-Exists", tc.header)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var user User
+			if err := json.Unmarshal(w.Body.Bytes(), &user); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if user.ID != id {
+				t.Errorf("expected the existing user ID %s, got %s", id, user.ID)
+			}
+
+			if !strings.HasSuffix(w.Header().Get("Location"), "/users/"+id.String()) {
+				t.Errorf("unexpected Location header %q", w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestUser_Upsert(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+	body := `{"first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"secret123"}`
+
+	isUser := gomock.Cond(func(input *service.UpsertUserInput) bool {
+		return input.ID == id && input.Email == "john.doe@mail.com"
+	})
+
+	gomock.InOrder(
+		mockService.EXPECT().Upsert(gomock.Any(), isUser).Return(true, nil).Times(1),
+		mockService.EXPECT().Upsert(gomock.Any(), isUser).Return(false, nil).Times(1),
+		mockService.EXPECT().Upsert(gomock.Any(), isUser).Return(false, service.ErrUserEmailAlreadyExists).Times(1),
+	)
+
+	tests := []struct {
+		name       string
+		statusCode int
+	}{
+		{
+			name:       "upsert a new ID creates the user",
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "upsert an existing ID updates the user",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "upsert with the email of another user is a conflict",
+			statusCode: http.StatusConflict,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPut, "/users/"+id.String(), strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+
+			if tc.statusCode != http.StatusConflict && w.Header().Get("Location") != "/users/"+id.String() {
+				t.Errorf("expected location /users/%s, got %q", id, w.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestUser_CreateLocation(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	// the handlers are mounted under the API prefix, as in main
+	router := http.NewServeMux()
+	router.Handle("/api/v1/", http.StripPrefix("/api/v1", mux))
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+
+	mockService.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mockService.EXPECT().Upsert(gomock.Any(), gomock.Any()).Return(true, nil).Times(1)
+	mockService.EXPECT().GetByID(gomock.Any(), id).Return(&service.User{ID: id}, nil).Times(2)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+	}{
+		{
+			name:   "create",
+			method: http.MethodPost,
+			target: "/api/v1/users?upsert=false",
+			body:   `{"id":"` + id.String() + `","first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"secret123"}`,
+		},
+		{
+			name:   "upsert of a new user",
+			method: http.MethodPut,
+			target: "/api/v1/users/" + id.String(),
+			body:   `{"first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"secret123"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			r.Header.Set("Origin", "https://client.example.com")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+			}
+
+			location := w.Header().Get("Location")
+			if location != "/api/v1/users/"+id.String() {
+				t.Fatalf("expected location /api/v1/users/%s, got %q", id, location)
+			}
+
+			// the location points to the created user through the prefixed router
+			w = httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, location, nil))
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status code %d getting the location, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ListOversizedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	testCases := []struct {
+		name           string
+		tokenMaxLength int
+		tokenLength    int
+		code           string
+	}{
+		{
+			name:        "huge token with the default bound",
+			tokenLength: 1 << 20,
+			code:        respond.CodeTokenTooLong,
+		},
+		{
+			name:        "token just above the default bound",
+			tokenLength: paginator.DefaultMaxTokenLength + 4,
+			code:        respond.CodeTokenTooLong,
+		},
+		{
+			name:           "token below a configured bound is decoded",
+			tokenMaxLength: 256,
+			tokenLength:    paginator.DefaultMaxTokenLength + 4,
+			code:           respond.CodeBadRequest,
+		},
+		{
+			name:           "token above a configured bound",
+			tokenMaxLength: 256,
+			tokenLength:    260,
+			code:           respond.CodeTokenTooLong,
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, param := range []string{"next_token", "prev_token"} {
+			t.Run(tc.name+"/"+param, func(t *testing.T) {
+				// Given
+				mux := newTestUsersMux(t, UsersHandlerConf{
+					Service:        mockService,
+					OT:             telemetry,
+					TokenMaxLength: tc.tokenMaxLength,
+				})
+
+				q := url.Values{param: {strings.Repeat("A", tc.tokenLength)}}
+				r, err := http.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+				if err != nil {
+					t.Fatalf("could not create request: %v", err)
+				}
+
+				// When
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, r)
+
+				// Then
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+				}
+
+				var msg respond.HTTPMessage
+				if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+					t.Fatalf("could not decode response: %v", err)
+				}
+
+				if msg.Code != tc.code {
+					t.Errorf("expected code %q, got %q: %s", tc.code, msg.Code, msg.Message)
+				}
+			})
+		}
+	}
+}
+
+func TestUser_CreateReturnsID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	var created uuid.UUID
+	mockService.
+		EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, input *service.CreateUserInput) error {
+			created = input.ID
+			return nil
+		}).
+		Times(1)
+
+	// the client does not generate the ID
+	body := `{"first_name":"John","last_name":"Doe","email":"john.doe@mail.com","password":"secret123"}`
+	r, err := http.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var msg respond.HTTPMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+
+	id, err := uuid.Parse(msg.ID)
+	if err != nil || id == uuid.Nil {
+		t.Fatalf("expected a valid UUID, got %q", msg.ID)
+	}
+
+	if id != created {
+		t.Errorf("expected the created user ID %s, got %s", created, id)
+	}
+}
+
+func TestUser_DisableEnable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+
+	setsDisabled := func(disabled bool) gomock.Matcher {
+		return gomock.Cond(func(input *service.UpdateUserInput) bool {
+			return input.ID == id && input.Disabled != nil && *input.Disabled == disabled &&
+				input.FirstName == nil && input.LastName == nil && input.Email == nil && input.Password == nil
+		})
+	}
+
+	gomock.InOrder(
+		mockService.EXPECT().Update(gomock.Any(), setsDisabled(true)).Return(nil).Times(1),
+		mockService.EXPECT().Update(gomock.Any(), setsDisabled(false)).Return(nil).Times(1),
+		mockService.EXPECT().Update(gomock.Any(), setsDisabled(true)).Return(service.ErrUserNotFound).Times(1),
+	)
+
+	tests := []struct {
+		name       string
+		path       string
+		statusCode int
+	}{
+		{
+			name:       "disable the user",
+			path:       "/users/" + id.String() + "/disable",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "enable the user again",
+			path:       "/users/" + id.String() + "/enable",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "disable a user that does not exist",
+			path:       "/users/" + id.String() + "/disable",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "invalid user id",
+			path:       "/users/not-a-uuid/disable",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodPost, tc.path, nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_GetByEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	existing := &service.User{
+		ID:        uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9")),
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@doe.com",
+	}
+
+	mockService.
+		EXPECT().
+		GetByEmail(gomock.Any(), "john@doe.com").
+		Return(existing, nil).
+		Times(1)
+
+	mockService.
+		EXPECT().
+		GetByEmail(gomock.Any(), "nobody@doe.com").
+		Return(nil, service.ErrUserNotFound).
+		Times(1)
+
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+	}{
+		{
+			name:       "existing email",
+			query:      "?email=john@doe.com",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "nonexistent email",
+			query:      "?email=nobody@doe.com",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "missing email",
+			query:      "",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := http.NewRequest(http.MethodGet, "/users/lookup"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+
+			if tc.statusCode != http.StatusOK {
+				return
+			}
+
+			var user User
+			if err := json.NewDecoder(w.Body).Decode(&user); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if user.ID != existing.ID || user.Email != existing.Email {
+				t.Errorf("expected user %s %s, got %s %s", existing.ID, existing.Email, user.ID, user.Email)
+			}
+		})
+	}
+}
+
+func TestUser_ListExplain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	mux := newTestUsersMux(t, UsersHandlerConf{
+		Service: mockService,
+		OT:      telemetry,
+	})
+
+	filter := "first_name='Alice'  and disabled=0"
+
+	// the users are counted, never listed
+	mockService.
+		EXPECT().
+		Count(gomock.Any(), &service.CountUsersInput{Filter: filter}).
+		Return(&service.CountUsersOutput{Count: 3}, nil).
+		Times(1)
+
+	q := url.Values{
+		"filter":  {filter},
+		"sort":    {"first_name desc"},
+		"fields":  {"id,first_name"},
+		"explain": {"true"},
+	}
+
+	r, err := http.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var got ListUsersExplainResponse
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+
+	want := ListUsersExplainResponse{
+		Filter: "first_name = 'Alice' AND disabled = 0",
+		Sort:   "first_name DESC",
+		Fields: []string{"id", "first_name"},
+		Limit:  paginator.DefaultLimit,
+		Count:  3,
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected explain response (-want +got):\n%s", diff)
+	}
+
+	t.Run("invalid explain value", func(t *testing.T) {
+		r, err := http.NewRequest(http.MethodGet, "/users?explain=maybe", nil)
+		if err != nil {
+			t.Fatalf("could not create request: %v", err)
+		}
+
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected status code %d, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+}
+
+func TestUser_ListFilterMaxPredicates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	tests := []struct {
+		name          string
+		filter        string
+		maxPredicates int
+		statusCode    int
+	}{
+		{
+			name:          "filter within the limit",
+			filter:        "first_name='Alice' AND last_name='Doe'",
+			maxPredicates: 2,
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "filter with too many predicates is rejected",
+			filter:        "first_name='Alice' AND last_name='Doe' AND disabled=0",
+			maxPredicates: 2,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "no limit",
+			filter:        "first_name='Alice' AND last_name='Doe' AND disabled=0",
+			maxPredicates: 0,
+			statusCode:    http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			q := url.Values{"filter": {tc.filter}}
+			r, err := http.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			if tc.statusCode == http.StatusOK {
+				mockService.
+					EXPECT().
+					List(gomock.Any(), gomock.Cond(func(input *service.ListUsersInput) bool {
+						return input.Filter == tc.filter
+					})).
+					Return(&service.ListUsersOutput{}, nil).
+					Times(1)
+			}
+
+			// When
+			mux := http.NewServeMux()
+			h := newTestUsersHandler(t, UsersHandlerConf{
+				Service:             mockService,
+				OT:                  telemetry,
+				FilterMaxPredicates: tc.maxPredicates,
+			})
+			mux.HandleFunc("GET /users", h.listUsers)
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUser_ListInvalidLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	tests := []struct {
+		name       string
+		limit      string
+		statusCode int
+		code       string
+		wantLimit  int
+	}{
+		{
+			name:       "non numeric limit is rejected",
+			limit:      "abc",
+			statusCode: http.StatusBadRequest,
+			code:       respond.CodeInvalidLimit,
+		},
+		{
+			name:       "negative limit is rejected",
+			limit:      "-5",
+			statusCode: http.StatusBadRequest,
+			code:       respond.CodeInvalidLimit,
+		},
+		{
+			name:       "over-large limit is clamped",
+			limit:      "1000",
+			statusCode: http.StatusOK,
+			wantLimit:  paginator.MaxLimit,
+		},
+		{
+			name:       "missing limit is defaulted",
+			statusCode: http.StatusOK,
+			wantLimit:  paginator.DefaultLimit,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			q := url.Values{}
+			if tc.limit != "" {
+				q.Set("limit", tc.limit)
+			}
+
+			r, err := http.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			if tc.statusCode == http.StatusOK {
+				mockService.
+					EXPECT().
+					List(gomock.Any(), gomock.Cond(func(input *service.ListUsersInput) bool {
+						return input.Paginator.Limit == tc.wantLimit
+					})).
+					Return(&service.ListUsersOutput{}, nil).
+					Times(1)
+			}
+
+			// When
+			mux := http.NewServeMux()
+			h := newTestUsersHandler(t, UsersHandlerConf{
+				Service: mockService,
+				OT:      telemetry,
+			})
+			mux.HandleFunc("GET /users", h.listUsers)
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d: %s", tc.statusCode, w.Code, w.Body.String())
+			}
+
+			if tc.statusCode != http.StatusBadRequest {
+				return
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+
+			if msg.Code != tc.code {
+				t.Errorf("expected code %s, got %s", tc.code, msg.Code)
+			}
+
+			if !strings.Contains(msg.Message, tc.limit) {
+				t.Errorf("expected the message to mention the limit %s, got %s", tc.limit, msg.Message)
+			}
+		})
+	}
+}
+
+func TestUser_MalformedBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockService := mocksService.NewMockUsersService(ctrl)
+
+	telemetry := newTestTelemetry(t)
+
+	id := uuid.New().String()
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		code    string
+		message string
+	}{
+		{
+			name:    "empty body",
+			method:  http.MethodPost,
+			target:  "/users",
+			body:    ``,
+			code:    respond.CodeMalformedJSON,
+			message: "request body is empty",
+		},
+		{
+			name:    "syntax error reports the offset",
+			method:  http.MethodPost,
+			target:  "/users",
+			body:    `{"first_name":"John",}`,
+			code:    respond.CodeMalformedJSON,
+			message: "malformed JSON: syntax error at offset 22",
+		},
+		{
+			name:    "truncated body",
+			method:  http.MethodPatch,
+			target:  "/users/" + id,
+			body:    `{"first_name":"Jo`,
+			code:    respond.CodeMalformedJSON,
+			message: "malformed JSON: unexpected end of the body",
+		},
+		{
+			name:    "more than one JSON value",
+			method:  http.MethodPatch,
+			target:  "/users/" + id,
+			body:    `{"first_name":"John"}{"last_name":"Doe"}`,
+			code:    respond.CodeMalformedJSON,
+			message: "malformed JSON: the body must contain a single JSON value",
+		},
+		{
+			name:    "unknown field",
+			method:  http.MethodPut,
+			target:  "/users/" + id,
+			body:    `{"first_name":"John","nickname":"JD"}`,
+			code:    respond.CodeUnknownField,
+			message: `unknown field: "nickname"`,
+		},
+		{
+			name:    "wrong type for a field",
+			method:  http.MethodPost,
+			target:  "/users",
+			body:    `{"first_name":42}`,
+			code:    respond.CodeInvalidFieldType,
+			message: "invalid field type: first_name must be string, got number",
+		},
+		{
+			name:    "wrong type for the whole body",
+			method:  http.MethodPost,
+			target:  "/users/bulk-delete",
+			body:    `["` + id + `"]`,
+			code:    respond.CodeInvalidFieldType,
+			message: "invalid field type: expected handler.BulkDeleteUsersRequest, got array",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r, err := http.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+
+			// When
+			mux := newTestUsersMux(t, UsersHandlerConf{
+				Service: mockService,
+				OT:      telemetry,
+			})
+			mux.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status code %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if msg.Code != tc.code {
+				t.Errorf("expected code %q, got %q", tc.code, msg.Code)
+			}
+
+			if msg.Message != tc.message {
+				t.Errorf("expected message %q, got %q", tc.message, msg.Message)
+			}
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/query"
 )
@@ -30,13 +36,13 @@ func parseUUIDQueryParams(input string) (uuid.UUID, error) {
 	return id, nil
 }
 
-// parseSortQueryParams parses a string into a sort field.
+// parseSortQueryParams parses a string into a normalized sort field.
 func parseSortQueryParams(sort string, allowedFields []string) (string, error) {
 	if !query.IsValidSort(allowedFields, sort) {
 		return "", ErrInvalidSort
 	}
 
-	return sort, nil
+	return query.NormalizeSort(sort), nil
 }
 
 // parseFilterQueryParams parses a string into a filter field.
@@ -58,9 +64,13 @@ func parseFieldsQueryParams(fields string, allowedFields []string) ([]string, er
 }
 
 // parseNextTokenQueryParams parses a string into a nextToken field.
-func parseNextTokenQueryParams(nextToken string) (string, error) {
+// A token longer than maxLength is rejected with ErrTokenTooLong.
+func parseNextTokenQueryParams(nextToken string, maxLength int) (string, error) {
 	if nextToken != "" {
-		_, _, err := paginator.DecodeToken(nextToken)
+		_, _, err := paginator.DecodeTokenMaxLength(nextToken, maxLength)
+		if errors.Is(err, paginator.ErrCursorTooLong) {
+			return "", fmt.Errorf("%w, next_token must be at most %d characters", ErrTokenTooLong, maxLength)
+		}
 		if err != nil {
 			return "", ErrInvalidNextToken
 		}
@@ -70,9 +80,13 @@ func parseNextTokenQueryParams(nextToken string) (string, error) {
 }
 
 // parsePrevTokenQueryParams parses a string into a prevToken field.
-func parsePrevTokenQueryParams(prevToken string) (string, error) {
+// A token longer than maxLength is rejected with ErrTokenTooLong.
+func parsePrevTokenQueryParams(prevToken string, maxLength int) (string, error) {
 	if prevToken != "" {
-		_, _, err := paginator.DecodeToken(prevToken)
+		_, _, err := paginator.DecodeTokenMaxLength(prevToken, maxLength)
+		if errors.Is(err, paginator.ErrCursorTooLong) {
+			return "", fmt.Errorf("%w, prev_token must be at most %d characters", ErrTokenTooLong, maxLength)
+		}
 		if err != nil {
 			return "", ErrInvalidPrevToken
 		}
@@ -82,6 +96,8 @@ func parsePrevTokenQueryParams(prevToken string) (string, error) {
 }
 
 // parseLimitQueryParams parses a string into a limit field.
+// An empty or zero limit is the default limit, and a limit above the maximum is clamped to it.
+// A limit that is not an integer or is below the minimum is rejected, instead of defaulted.
 func parseLimitQueryParams(limit string) (int, error) {
 	var limitInt int
 	var err error
@@ -92,7 +108,7 @@ func parseLimitQueryParams(limit string) (int, error) {
 
 	// check if this is a valid integer
 	if limitInt, err = strconv.Atoi(limit); err != nil {
-		return 0, ErrInvalidLimit
+		return 0, fmt.Errorf("%w, must be an integer, got %q", ErrInvalidLimit, limit)
 	}
 
 	if limitInt == 0 {
@@ -100,7 +116,7 @@ func parseLimitQueryParams(limit string) (int, error) {
 	}
 
 	if limitInt < paginator.MinLimit {
-		return 0, ErrInvalidLimit
+		return 0, fmt.Errorf("%w, must be between %d and %d, got %d", ErrInvalidLimit, paginator.MinLimit, paginator.MaxLimit, limitInt)
 	} else if limitInt > paginator.MaxLimit {
 		limitInt = paginator.MaxLimit
 	}
@@ -108,8 +124,23 @@ func parseLimitQueryParams(limit string) (int, error) {
 	return limitInt, nil
 }
 
+// parseBoolQueryParams parses a string into a bool field.
+// If the input is empty, it returns false.
+func parseBoolQueryParams(input string) (bool, error) {
+	if input == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(input)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", ErrInvalidBool, input)
+	}
+
+	return b, nil
+}
+
 // parseListQueryParams parses a list of strings into a list of UUIDs.
-func parseListQueryParams(params map[string]any, fieldsFields, filterFields, sortFields []string) (
+func parseListQueryParams(params map[string]any, fieldsFields, filterFields, sortFields []string, tokenMaxLength int) (
 	sort string,
 	filter string,
 	fields []string,
@@ -133,12 +164,12 @@ func parseListQueryParams(params map[string]any, fieldsFields, filterFields, sor
 		return "", "", nil, "", "", 0, err
 	}
 
-	nextToken, err = parseNextTokenQueryParams(params["nextToken"].(string))
+	nextToken, err = parseNextTokenQueryParams(params["nextToken"].(string), tokenMaxLength)
 	if err != nil {
 		return "", "", nil, "", "", 0, err
 	}
 
-	prevToken, err = parsePrevTokenQueryParams(params["prevToken"].(string))
+	prevToken, err = parsePrevTokenQueryParams(params["prevToken"].(string), tokenMaxLength)
 	if err != nil {
 		return "", "", nil, "", "", 0, err
 	}
@@ -150,3 +181,50 @@ func parseListQueryParams(params map[string]any, fieldsFields, filterFields, sor
 
 	return sort, filter, fields, nextToken, prevToken, limit, nil
 }
+
+// decodeJSONBody decodes the JSON body of the request into v, rejecting unknown fields.
+// On failure it returns the respond code of the error category along with an error
+// describing the problem: a syntax error with its offset, an unknown field or a field
+// with the wrong type.
+func decodeJSONBody(r *http.Request, v any) (string, error) {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(v)
+	if err == nil {
+		if dec.More() {
+			return respond.CodeMalformedJSON, fmt.Errorf("%w: the body must contain a single JSON value", ErrMalformedJSON)
+		}
+
+		return "", nil
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return respond.CodeMalformedJSON, ErrEmptyBody
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return respond.CodeMalformedJSON, fmt.Errorf("%w: unexpected end of the body", ErrMalformedJSON)
+
+	case errors.As(err, &syntaxErr):
+		return respond.CodeMalformedJSON, fmt.Errorf("%w: syntax error at offset %d", ErrMalformedJSON, syntaxErr.Offset)
+
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return respond.CodeInvalidFieldType, fmt.Errorf("%w: expected %s, got %s", ErrInvalidFieldType, typeErr.Type, typeErr.Value)
+		}
+
+		return respond.CodeInvalidFieldType, fmt.Errorf("%w: %s must be %s, got %s", ErrInvalidFieldType, typeErr.Field, typeErr.Type, typeErr.Value)
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// the decoder has no typed error for the unknown fields
+		field := strings.TrimPrefix(err.Error(), "json: unknown field ")
+		return respond.CodeUnknownField, fmt.Errorf("%w: %s", ErrUnknownField, field)
+	}
+
+	// errors from the fields unmarshaling themselves, like an invalid UUID
+	return respond.CodeBadRequest, err
+}
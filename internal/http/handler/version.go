@@ -31,8 +31,6 @@ func (ref *VersionHandler) RegisterRoutes(mux *http.ServeMux) {
 //	@Failure		500	{object}	respond.HTTPMessage
 //	@Router			/version [get]
 func (ref *VersionHandler) get(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-
 	v := Version{
 		Version:       version.Version,
 		BuildDate:     version.BuildDate,
@@ -43,8 +41,8 @@ func (ref *VersionHandler) get(w http.ResponseWriter, r *http.Request) {
 		GoVersionOS:   version.GoVersionOS,
 	}
 
-	if err := respond.WriteJSONData(w, http.StatusOK, v); err != nil {
-		respond.WriteJSONMessage(w, r, http.StatusInternalServerError, ErrInternalServerError.Error())
+	if err := respond.WriteJSON(w, http.StatusOK, v); err != nil {
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, ErrInternalServerError.Error())
 		return
 	}
 }
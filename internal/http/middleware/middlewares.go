@@ -1,24 +1,53 @@
 package middleware
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"log/slog"
+	"math"
+	"mime"
 	"net/http"
+	"runtime/debug"
 	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+	"unicode"
 
+	"github.com/google/uuid"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerName is the name of the tracer of the spans started by the middlewares.
+const tracerName = "github.com/p2p-b2b/go-rest-api-service-template/internal/http/middleware"
+
 type JWTClaimsName string
 
 const (
 	ClaimsName JWTClaimsName = "jwt_claims"
 )
 
+type requestIDKey struct{}
+
+// HeaderRequestID is the header used to receive and return the request ID.
+const HeaderRequestID = "X-Request-ID"
+
+// requestIDMaxLength is the maximum length accepted for a request ID sent by the client.
+const requestIDMaxLength = 128
+
 // Middleware is a function that wraps an http.Handler
 type Middleware func(http.Handler) http.Handler
 
@@ -38,20 +67,192 @@ func Append(m Middleware, mws ...Middleware) []Middleware {
 	return append(mws, m)
 }
 
-// HeaderAPIVersion adds the API version to the response headers
-func HeaderAPIVersion(version string) Middleware {
+// HeaderAPIVersion adds the version of the service to the response headers,
+// in the header named header, X-API-Version when empty
+func HeaderAPIVersion(header string, version string) Middleware {
+	if header == "" {
+		header = "X-API-Version"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(header, version)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// WriteTimeout sets a deadline for writing the response, so a client that
+// stops reading does not hold the handler goroutine forever.
+// A zero timeout leaves the deadline untouched.
+func WriteTimeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout > 0 {
+				rc := http.NewResponseController(w)
+				if err := rc.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+					slog.Debug("could not set write deadline", "error", err)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestTimeoutOpts represents the options for the RequestTimeout middleware.
+// Timeout is the time allowed to handle a request, zero disables it.
+// SkipRoutes are the path prefixes exempted from the timeout, like the long-lived
+// event streams, which would be closed at the deadline otherwise.
+type RequestTimeoutOpts struct {
+	Timeout    time.Duration
+	SkipRoutes []string
+}
+
+// RequestTimeout cancels the context of the requests after the timeout,
+// and maps the 500 responses written once the context of the request is done:
+// a client that closed the request is logged at info level and recorded with the
+// status 499, without a body nobody reads, and a request that exceeded the timeout
+// is responded 504 Gateway Timeout. A zero timeout only maps the client cancellations.
+func RequestTimeout(opts RequestTimeoutOpts) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Timeout > 0 && !skipsRoute(opts.SkipRoutes, r.URL.Path) {
+				ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+				defer cancel()
+
+				r = r.WithContext(ctx)
+			}
+
+			next.ServeHTTP(&contextErrorResponseWriter{ResponseWriter: w, r: r}, r)
+		})
+	}
+}
+
+// Ready responds 503 Service Unavailable until the ready flag is set,
+// so no request is served before the initialization is complete.
+func Ready(ready *atomic.Bool) Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if version == "" {
-				version = "v1"
+			if !ready.Load() {
+				w.Header().Set("Retry-After", "1")
+				respond.WriteError(w, r, http.StatusServiceUnavailable, respond.CodeServiceUnavailable, "service is starting, try again later")
+				return
 			}
 
-			w.Header().Set("X-API-Version", version)
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// ReadOnly responds 503 Service Unavailable to the requests with a mutating method,
+// POST, PUT, PATCH and DELETE, while the other requests continue to work.
+func ReadOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			w.Header().Set("Retry-After", "60")
+			respond.WriteError(w, r, http.StatusServiceUnavailable, respond.CodeServiceUnavailable, "service is in read-only mode, try again later")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AllowedMethods responds 405 Method Not Allowed to the requests with a method
+// other than the given ones, with the Allow header listing them.
+func AllowedMethods(methods ...string) Middleware {
+	allow := strings.Join(methods, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(methods, r.Method) {
+				w.Header().Set("Allow", allow)
+				respond.WriteError(w, r, http.StatusMethodNotAllowed, respond.CodeMethodNotAllowed, "method not allowed, must be one of ["+allow+"]")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Recover responds 500 Internal Server Error when a handler panics, instead of
+// dropping the connection. The panic is logged with the request ID and the stack trace,
+// the response doesn't expose them. When the response was already started,
+// the connection is aborted since the status code can't be changed anymore.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &recoverResponseWriter{ResponseWriter: w}
+
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+
+			// the handlers abort the response on purpose with ErrAbortHandler
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			slog.ErrorContext(r.Context(), "panic recovered",
+				"request_id", RequestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"panic", rec,
+				"stack", string(debug.Stack()),
+			)
+
+			if rw.started {
+				panic(http.ErrAbortHandler)
+			}
+
+			respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, "internal server error")
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// RequestID makes sure every request has an ID, taken from the X-Request-ID
+// header when the client sends a valid one or generated otherwise.
+// The ID is returned in the response headers and stored in the request context.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !isValidRequestID(id) {
+			id = uuid.New().String()
+		}
+
+		w.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID stored by the RequestID middleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// isValidRequestID returns true when the ID is not empty, not too long
+// and only contains printable ASCII characters, so it is safe to log.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Logging middleware logs the request and response
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -62,7 +263,62 @@ func Logging(next http.Handler) http.Handler {
 
 		next.ServeHTTP(wrapped, r)
 
-		slog.Info("request", "method", r.Method, "path", r.URL.Path, "address", r.RemoteAddr, "status", wrapped.status)
+		// the context has the span of the request, if any, to correlate the line with the trace
+		slog.InfoContext(r.Context(), "request", "method", r.Method, "path", r.URL.Path, "address", r.RemoteAddr, "status", wrapped.status)
+	})
+}
+
+// SlowRequest logs at warn level the requests whose handling takes longer
+// than the threshold, with their status and duration, to find the slow endpoints.
+// A zero threshold disables it.
+func SlowRequest(threshold time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		if threshold <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			wrapped := newWrappedResponseWriter(w)
+
+			next.ServeHTTP(wrapped, r)
+
+			if duration := time.Since(start); duration > threshold {
+				slog.WarnContext(r.Context(), "slow request",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", wrapped.status,
+					"duration", duration,
+					"threshold", threshold,
+				)
+			}
+		})
+	}
+}
+
+// Tracing starts a server span for each request, parent of the spans of the handlers,
+// so the lines logged with the request context can be correlated with the trace.
+// It must run after OtelTextMapPropagation to continue the trace of the client.
+func Tracing(next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracer.Start(r.Context(), r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		wrapped := newWrappedResponseWriter(w)
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrapped.status))
+		if wrapped.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.status))
+		}
 	})
 }
 
@@ -77,6 +333,306 @@ func OtelTextMapPropagation(next http.Handler) http.Handler {
 	})
 }
 
+// PrettyJSON middleware indents the JSON responses when the request
+// has the query parameter pretty=true. Responses are compact otherwise.
+func PrettyJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pretty, _ := strconv.ParseBool(r.URL.Query().Get("pretty")); pretty {
+			w = &prettyJSONResponseWriter{w}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CamelCaseJSONOpts represents the options for the CamelCaseJSON middleware.
+// Camel converts the keys to camel case by default, they are kept in snake case otherwise.
+// Negotiation lets the clients override it per request with the casing parameter
+// of the Accept header, like application/json; casing=camel.
+// MaxBodyBytes is the maximum size of the converted request bodies, the bigger ones respond 413.
+// If zero, DefaultCamelCaseJSONMaxBodyBytes is used.
+type CamelCaseJSONOpts struct {
+	Camel        bool
+	Negotiation  bool
+	MaxBodyBytes int64
+}
+
+// DefaultCamelCaseJSONMaxBodyBytes is the default maximum size of the request bodies
+// converted by the CamelCaseJSON middleware.
+const DefaultCamelCaseJSONMaxBodyBytes = 1 << 20
+
+// CamelCaseJSON converts the keys of the JSON responses from snake case to camel case,
+// like first_name to firstName, and the keys of the JSON request bodies from camel case
+// back to snake case, so the handlers keep decoding snake case. The order of the keys is kept.
+func CamelCaseJSON(opts CamelCaseJSONOpts) Middleware {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultCamelCaseJSONMaxBodyBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			camel := opts.Camel
+			if opts.Negotiation {
+				camel = acceptsCamelCase(r.Header.Get("Accept"), camel)
+			}
+
+			if !camel {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Body != nil && r.ContentLength != 0 && isJSONContentType(r.Header.Get("Content-Type")) {
+				tooLarge := "request body too large, must be at most " + strconv.FormatInt(opts.MaxBodyBytes, 10) + " bytes"
+				if r.ContentLength > opts.MaxBodyBytes {
+					respond.WriteError(w, r, http.StatusRequestEntityTooLarge, respond.CodeRequestTooLarge, tooLarge)
+					return
+				}
+
+				body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, opts.MaxBodyBytes))
+				r.Body.Close()
+				if err != nil {
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						respond.WriteError(w, r, http.StatusRequestEntityTooLarge, respond.CodeRequestTooLarge, tooLarge)
+						return
+					}
+
+					respond.WriteError(w, r, http.StatusBadRequest, respond.CodeBadRequest, "could not read the request body")
+					return
+				}
+
+				if converted, err := convertJSONKeys(body, camelToSnake); err == nil {
+					body = converted
+				}
+
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				r.ContentLength = int64(len(body))
+				r.Header.Set("Content-Length", strconv.Itoa(len(body)))
+			}
+
+			next.ServeHTTP(&camelCaseJSONResponseWriter{w}, r)
+		})
+	}
+}
+
+// acceptsCamelCase returns the casing asked by the casing parameter of the JSON media type
+// of the Accept header, or byDefault when there is none.
+func acceptsCamelCase(accept string, byDefault bool) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != "application/json" {
+			continue
+		}
+
+		switch params["casing"] {
+		case "camel":
+			return true
+		case "snake":
+			return false
+		}
+	}
+
+	return byDefault
+}
+
+// isJSONContentType returns true when the content type is application/json.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// convertJSONKeys returns the JSON document with the keys of all its objects converted
+// by convert, in their original order. The document is compacted.
+func convertJSONKeys(data []byte, convert func(string) string) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	// the number of tokens written in each open object or array,
+	// the even ones of an object being its keys
+	type container struct {
+		object bool
+		tokens int
+	}
+
+	var (
+		buf   bytes.Buffer
+		stack []container
+		done  bool
+	)
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			return nil, errors.New("more than one JSON document")
+		}
+
+		if delim, ok := tok.(json.Delim); ok && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			buf.WriteByte(byte(delim))
+			done = len(stack) == 0
+			continue
+		}
+
+		isKey := false
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			switch {
+			case top.object && top.tokens%2 == 1:
+				buf.WriteByte(':')
+			case top.tokens > 0:
+				buf.WriteByte(',')
+			}
+			isKey = top.object && top.tokens%2 == 0
+			top.tokens++
+		}
+
+		switch v := tok.(type) {
+		case json.Delim:
+			buf.WriteByte(byte(v))
+			stack = append(stack, container{object: v == '{'})
+		case string:
+			if isKey {
+				v = convert(v)
+			}
+			if err := writeJSONString(&buf, v); err != nil {
+				return nil, err
+			}
+		case json.Number:
+			buf.WriteString(v.String())
+		case bool:
+			buf.WriteString(strconv.FormatBool(v))
+		case nil:
+			buf.WriteString("null")
+		}
+
+		done = len(stack) == 0
+	}
+
+	if !done {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	// keep the trailing newline written by the json.Encoder
+	if bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeJSONString writes s as a JSON string, without escaping the HTML characters.
+func writeJSONString(buf *bytes.Buffer, s string) error {
+	var str bytes.Buffer
+	enc := json.NewEncoder(&str)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+
+	buf.Write(bytes.TrimSuffix(str.Bytes(), []byte("\n")))
+
+	return nil
+}
+
+// snakeToCamel converts a snake case key to camel case, like first_name to firstName.
+func snakeToCamel(key string) string {
+	parts := strings.Split(key, "_")
+	if len(parts) == 1 || parts[0] == "" {
+		return key
+	}
+
+	var b strings.Builder
+	b.WriteString(parts[0])
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return b.String()
+}
+
+// camelToSnake converts a camel case key to snake case, like firstName to first_name
+// and requestID to request_id. The snake case keys are kept as they are.
+func camelToSnake(key string) string {
+	var b strings.Builder
+	runes := []rune(key)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && !unicode.IsUpper(runes[i-1]) && runes[i-1] != '_'
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// CacheControl lets the browsers cache the successful GET responses for maxAge,
+// with an ETag computed from the body so they can revalidate them afterwards.
+// A zero maxAge makes the browsers revalidate the responses on every use.
+// The responses are buffered, so it is meant for small and static documents.
+func CacheControl(maxAge time.Duration) Middleware {
+	cacheControl := "no-cache"
+	if maxAge > 0 {
+		cacheControl = fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds()))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := newBufferedResponseWriter(w)
+			next.ServeHTTP(bw, r)
+
+			if bw.status == http.StatusOK {
+				sum := sha256.Sum256(bw.body.Bytes())
+				etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+				w.Header().Set("Cache-Control", cacheControl)
+				w.Header().Set("ETag", etag)
+
+				if etagMatches(r.Header.Get("If-None-Match"), etag) {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+			}
+
+			if err := bw.flush(); err != nil {
+				slog.Debug("middleware.CacheControl", "error", err)
+			}
+		})
+	}
+}
+
+// etagMatches returns true when the If-None-Match header value
+// contains the ETag, compared weakly, or is a wildcard.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
 // customResponseWriter is a custom response writer that handles custom error responses.
 type customResponseWriter struct {
 	*wrappedResponseWriter
@@ -95,6 +651,7 @@ func (w *customResponseWriter) Write(data []byte) (n int, err error) {
 				respond.HTTPMessage{
 					Timestamp:  time.Now().UTC(),
 					StatusCode: http.StatusNotFound,
+					Code:       respond.CodeNotFound,
 					Message:    "Not Found",
 					Method:     w.method,
 					Path:       w.path,
@@ -111,6 +668,7 @@ func (w *customResponseWriter) Write(data []byte) (n int, err error) {
 				respond.HTTPMessage{
 					Timestamp:  time.Now().UTC(),
 					StatusCode: http.StatusMethodNotAllowed,
+					Code:       respond.CodeMethodNotAllowed,
 					Message:    "Method Not Allowed",
 					Method:     w.method,
 					Path:       w.path,
@@ -139,42 +697,56 @@ func RewriteStandardErrorsAsJSON(h http.Handler) http.Handler {
 }
 
 // CorsOpts represents the options for the CORS middleware.
-// If AllowedOrigins is empty, the default value is ["*"].
+// If AllowedOrigins is empty, the default value is ["*"]. The "*" origin allows any origin,
+// echoed back when AllowCredentials is true, as the browsers reject "*" for the requests with credentials.
 // If AllowedMethods is empty, the default value is [GET, POST, PUT, DELETE, OPTIONS].
 // If AllowedHeaders is empty, the default value is [Accept, Content-Type, Content-Length, Accept-Encoding, Authorization].
+// If ExposedHeaders is empty, the default value is [Retry-After], so the browsers let the
+// clients read when to retry the 503 responses.
 // If AllowCredentials is false, the default value is false.
+// RouteGroups overrides the options for the routes starting with the given path prefix,
+// the longest matching prefix wins.
 type CorsOpts struct {
 	AllowedOrigins   []string
 	AllowedMethods   []string
 	AllowedHeaders   []string
+	ExposedHeaders   []string
 	AllowCredentials bool
+	RouteGroups      map[string]CorsOpts
 }
 
 // Cors is a middleware that adds CORS headers to the response.
 func Cors(opts CorsOpts) Middleware {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			origin := r.Header.Get("Origin")
-			if slices.Contains(opts.AllowedOrigins, origin) {
-				w.Header().Set("Access-Control-Allow-Origin", origin)
-			}
+	opts = corsDefaults(opts)
 
-			if len(opts.AllowedOrigins) == 0 {
-				opts.AllowedOrigins = []string{"*"}
-			}
+	groups := make(map[string]CorsOpts, len(opts.RouteGroups))
+	for prefix, group := range opts.RouteGroups {
+		groups[prefix] = corsDefaults(group)
+	}
+	prefixes := routePrefixes(groups)
 
-			if len(opts.AllowedMethods) == 0 {
-				opts.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			routeOpts := opts
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					routeOpts = groups[prefix]
+					break
+				}
 			}
 
-			if len(opts.AllowedHeaders) == 0 {
-				opts.AllowedHeaders = []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"}
+			if allowed := allowedOrigin(routeOpts, r.Header.Get("Origin")); allowed != "" {
+				w.Header().Set("Access-Control-Allow-Origin", allowed)
+				if allowed != "*" {
+					w.Header().Add("Vary", "Origin")
+				}
 			}
 
-			w.Header().Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
-			w.Header().Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(routeOpts.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(routeOpts.AllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(routeOpts.ExposedHeaders, ", "))
 
-			if opts.AllowCredentials {
+			if routeOpts.AllowCredentials {
 				w.Header().Set("Access-Control-Allow-Credentials", "true")
 			} else {
 				w.Header().Set("Access-Control-Allow-Credentials", "false")
@@ -191,3 +763,260 @@ func Cors(opts CorsOpts) Middleware {
 		})
 	}
 }
+
+// allowedOrigin returns the value of the Access-Control-Allow-Origin header for the
+// origin of the request, or empty when the origin is not allowed.
+func allowedOrigin(opts CorsOpts, origin string) string {
+	if origin == "" {
+		return ""
+	}
+
+	if slices.Contains(opts.AllowedOrigins, "*") {
+		if opts.AllowCredentials {
+			return origin
+		}
+
+		return "*"
+	}
+
+	if slices.Contains(opts.AllowedOrigins, origin) {
+		return origin
+	}
+
+	return ""
+}
+
+// routePrefixes returns the path prefixes of the route groups,
+// longest first, so the most specific group wins.
+func routePrefixes[T any](groups map[string]T) []string {
+	prefixes := make([]string, 0, len(groups))
+	for prefix := range groups {
+		prefixes = append(prefixes, prefix)
+	}
+
+	slices.SortFunc(prefixes, func(a, b string) int {
+		return len(b) - len(a)
+	})
+
+	return prefixes
+}
+
+// ContentTypeOpts represents the options for the ContentType middleware.
+// Allowed are the media types accepted in the request bodies, if empty any is accepted.
+// RouteGroups overrides Allowed for the routes starting with the given path prefix,
+// the longest matching prefix wins.
+type ContentTypeOpts struct {
+	Allowed     []string
+	RouteGroups map[string][]string
+}
+
+// ContentType responds 415 Unsupported Media Type to the requests with a body
+// whose content type is not allowed for their route, before their body is decoded.
+// The requests without body are not checked.
+func ContentType(opts ContentTypeOpts) Middleware {
+	prefixes := routePrefixes(opts.RouteGroups)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			allowed := opts.Allowed
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(r.URL.Path, prefix) {
+					allowed = opts.RouteGroups[prefix]
+					break
+				}
+			}
+
+			if len(allowed) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || !slices.Contains(allowed, mediaType) {
+				respond.WriteError(w, r, http.StatusUnsupportedMediaType, respond.CodeUnsupportedMedia,
+					"unsupported content type, must be one of ["+strings.Join(allowed, ", ")+"]")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// corsDefaults returns the options with the default values set for the empty ones.
+func corsDefaults(opts CorsOpts) CorsOpts {
+	if len(opts.AllowedOrigins) == 0 {
+		opts.AllowedOrigins = []string{"*"}
+	}
+
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions}
+	}
+
+	if len(opts.AllowedHeaders) == 0 {
+		opts.AllowedHeaders = []string{"Accept", "Content-Type", "Content-Length", "Accept-Encoding", "Authorization"}
+	}
+
+	if len(opts.ExposedHeaders) == 0 {
+		opts.ExposedHeaders = []string{"Retry-After"}
+	}
+
+	return opts
+}
+
+// DefaultBodyLoggingRedactKeys are the JSON keys always redacted by the BodyLogging middleware.
+var DefaultBodyLoggingRedactKeys = []string{"password", "token", "access_token", "refresh_token", "id_token", "secret", "client_secret", "api_key"}
+
+// BodyLoggingOpts represents the options for the BodyLogging middleware.
+// If Routes is empty, the bodies of every route are logged.
+// RedactKeys are added to DefaultBodyLoggingRedactKeys.
+// MaxSize is the maximum number of bytes logged for each body.
+// SampleRate is the fraction of requests logged, chosen by the hash of the request ID.
+type BodyLoggingOpts struct {
+	Routes     []string
+	RedactKeys []string
+	MaxSize    int
+	SampleRate float64
+}
+
+// BodyLogging is a middleware that logs the request and response bodies at debug level.
+// The values of sensitive JSON keys are redacted, and bodies that are not JSON
+// or are larger than MaxSize are not logged, only their size.
+func BodyLogging(opts BodyLoggingOpts) Middleware {
+	redactKeys := make(map[string]struct{})
+	for _, key := range append(slices.Clone(DefaultBodyLoggingRedactKeys), opts.RedactKeys...) {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			redactKeys[key] = struct{}{}
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slog.Default().Enabled(r.Context(), slog.LevelDebug) ||
+				!matchesRoute(opts.Routes, r.URL.Path) ||
+				!isSampled(RequestIDFromContext(r.Context()), opts.SampleRate) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var reqBody []byte
+			if r.Body != nil {
+				// read one byte over the limit to know if the body was truncated,
+				// and put back what was read so the handler sees the whole body
+				reqBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(opts.MaxSize)+1))
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
+
+			captured := &bodyCaptureResponseWriter{ResponseWriter: w, maxSize: opts.MaxSize + 1}
+
+			next.ServeHTTP(captured, r)
+
+			slog.Debug("request body",
+				"request_id", RequestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"request_body", redactBody(reqBody, opts.MaxSize, redactKeys),
+				"response_body", redactBody(captured.body.Bytes(), opts.MaxSize, redactKeys),
+				"response_size", captured.size,
+			)
+		})
+	}
+}
+
+// isSampled deterministically decides if the request with the given ID is sampled,
+// so the same request ID is always either sampled or not.
+// Requests without ID are only sampled when every request is.
+func isSampled(requestID string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+
+	if rate <= 0 || requestID == "" {
+		return false
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(requestID))
+
+	return float64(h.Sum32()) < rate*float64(math.MaxUint32)
+}
+
+// matchesRoute returns true when routes is empty or the path starts with any of the routes.
+func matchesRoute(routes []string, path string) bool {
+	if len(routes) == 0 {
+		return true
+	}
+
+	for _, route := range routes {
+		if route = strings.TrimSpace(route); route != "" && strings.HasPrefix(path, route) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipsRoute returns true when the path starts with one of the routes.
+// Unlike matchesRoute, an empty list skips no route.
+func skipsRoute(routes []string, path string) bool {
+	for _, route := range routes {
+		if route = strings.TrimSpace(route); route != "" && strings.HasPrefix(path, route) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactBody returns the JSON body with the values of the redact keys replaced.
+// Bodies that can't be safely redacted are replaced by a placeholder.
+func redactBody(body []byte, maxSize int, redactKeys map[string]struct{}) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if len(body) > maxSize {
+		return "[truncated]"
+	}
+
+	var v any
+	if err := json.Unmarshal(body, &v); err != nil {
+		return "[not json]"
+	}
+
+	redacted, err := json.Marshal(redactValue(v, redactKeys))
+	if err != nil {
+		return "[not json]"
+	}
+
+	return string(redacted)
+}
+
+// redactValue walks the decoded JSON value replacing the values of the redact keys.
+func redactValue(v any, redactKeys map[string]struct{}) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			if _, ok := redactKeys[strings.ToLower(k)]; ok {
+				val[k] = "[redacted]"
+				continue
+			}
+
+			val[k] = redactValue(item, redactKeys)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = redactValue(item, redactKeys)
+		}
+	}
+
+	return v
+}
@@ -0,0 +1,1277 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/version"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestPrettyJSON(t *testing.T) {
+	data := map[string]any{"id": "1", "first_name": "John"}
+
+	h := PrettyJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := respond.WriteJSON(w, http.StatusOK, data); err != nil {
+			t.Fatalf("could not write response: %v", err)
+		}
+	}))
+
+	tests := []struct {
+		name   string
+		target string
+		want   string
+	}{
+		{
+			name:   "compact by default",
+			target: "/users",
+			want:   "{\"first_name\":\"John\",\"id\":\"1\"}\n",
+		},
+		{
+			name:   "compact when pretty is false",
+			target: "/users?pretty=false",
+			want:   "{\"first_name\":\"John\",\"id\":\"1\"}\n",
+		},
+		{
+			name:   "indented when pretty is true",
+			target: "/users?pretty=true",
+			want:   "{\n  \"first_name\": \"John\",\n  \"id\": \"1\"\n}\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+
+			h.ServeHTTP(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			if w.Body.String() != tc.want {
+				t.Errorf("expected body %q, got %q", tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestWriteTimeout(t *testing.T) {
+	writeErr := make(chan error, 1)
+
+	h := WriteTimeout(100 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("a"), 32*1024)
+
+		// write far more than the socket buffers can hold, so the
+		// writes block on the client that never reads
+		for range 4096 {
+			if _, err := w.Write(chunk); err != nil {
+				writeErr <- err
+				return
+			}
+		}
+
+		writeErr <- nil
+	}))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("could not connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\n\r\n", srv.Listener.Addr()); err != nil {
+		t.Fatalf("could not write request: %v", err)
+	}
+
+	select {
+	case err := <-writeErr:
+		if err == nil {
+			t.Fatal("expected write to time out, got nil")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("write did not time out")
+	}
+}
+
+func TestBodyLogging(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(defaultLogger)
+
+	var handlerBody string
+	h := BodyLogging(BodyLoggingOpts{
+		Routes:     []string{"/users"},
+		RedactKeys: []string{"Email"},
+		MaxSize:    1024,
+		SampleRate: 1,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		handlerBody = string(b)
+
+		if err := respond.WriteJSON(w, http.StatusCreated, map[string]any{"id": "1", "access_token": "abc.def"}); err != nil {
+			t.Fatalf("could not write response: %v", err)
+		}
+	}))
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		contains   []string
+		notContain []string
+	}{
+		{
+			name:       "password, tokens and configured keys are redacted",
+			target:     "/users",
+			body:       `{"first_name":"John","email":"john@mail.com","password":"ThisIs4Passw0rd","nested":[{"Password":"other"}]}`,
+			contains:   []string{"first_name", "John", "[redacted]", "response_size="},
+			notContain: []string{"ThisIs4Passw0rd", "other", "john@mail.com", "abc.def"},
+		},
+		{
+			name:       "bodies over the size cap are not logged",
+			target:     "/users",
+			body:       `{"password":"` + strings.Repeat("a", 2048) + `"}`,
+			contains:   []string{"[truncated]"},
+			notContain: []string{"aaaa"},
+		},
+		{
+			name:       "routes not selected are not logged",
+			target:     "/version",
+			body:       `{"password":"ThisIs4Passw0rd"}`,
+			notContain: []string{"request body"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader(tc.body))
+
+			h.ServeHTTP(w, r)
+
+			if handlerBody != tc.body {
+				t.Errorf("expected handler to read the whole body %q, got %q", tc.body, handlerBody)
+			}
+
+			for _, s := range tc.contains {
+				if !strings.Contains(logs.String(), s) {
+					t.Errorf("expected log to contain %q, got %s", s, logs.String())
+				}
+			}
+
+			for _, s := range tc.notContain {
+				if strings.Contains(logs.String(), s) {
+					t.Errorf("expected log not to contain %q, got %s", s, logs.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var got string
+	handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = RequestIDFromContext(r.Context())
+	}))
+
+	t.Run("keeps the client request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/users", nil)
+		req.Header.Set(HeaderRequestID, "abc-123")
+		w := httptest.NewRecorder()
+
+		handler.ServeHTTP(w, req)
+
+		if got != "abc-123" {
+			t.Errorf("expected request ID abc-123, got %q", got)
+		}
+
+		if w.Header().Get(HeaderRequestID) != "abc-123" {
+			t.Errorf("expected response header abc-123, got %q", w.Header().Get(HeaderRequestID))
+		}
+	})
+
+	t.Run("generates a request ID when missing or invalid", func(t *testing.T) {
+		for _, id := range []string{"", "bad id", strings.Repeat("a", requestIDMaxLength+1)} {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set(HeaderRequestID, id)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if _, err := uuid.Parse(got); err != nil {
+				t.Errorf("expected a generated UUID for %q, got %q", id, got)
+			}
+
+			if w.Header().Get(HeaderRequestID) != got {
+				t.Errorf("expected response header %q, got %q", got, w.Header().Get(HeaderRequestID))
+			}
+		}
+	})
+}
+
+func TestIsSampled(t *testing.T) {
+	const (
+		total = 10000
+		rate  = 0.25
+	)
+
+	sampled := 0
+	for range total {
+		id := uuid.New().String()
+
+		s := isSampled(id, rate)
+		if s != isSampled(id, rate) {
+			t.Fatalf("expected the same sampling decision for request ID %s", id)
+		}
+
+		if s {
+			sampled++
+		}
+	}
+
+	fraction := float64(sampled) / total
+	if fraction < rate-0.03 || fraction > rate+0.03 {
+		t.Errorf("expected roughly %.2f of the requests sampled, got %.4f", rate, fraction)
+	}
+
+	if !isSampled("", 1) || isSampled("", 0.5) {
+		t.Error("expected requests without ID to be sampled only when the rate is 1")
+	}
+
+	if isSampled(uuid.New().String(), 0) {
+		t.Error("expected no request sampled when the rate is 0")
+	}
+}
+
+func TestCorsRouteGroups(t *testing.T) {
+	h := Cors(CorsOpts{
+		AllowedOrigins: []string{"https://app.example.com", "https://admin.example.com"},
+		RouteGroups: map[string]CorsOpts{
+			"/admin/":  {AllowedOrigins: []string{"https://admin.example.com"}},
+			"/public/": {AllowedOrigins: []string{"*"}},
+			"/shared/": {AllowedOrigins: []string{"*"}, AllowCredentials: true},
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		origin string
+		want   string
+	}{
+		{
+			name:   "public route allows the app origin",
+			path:   "/users",
+			origin: "https://app.example.com",
+			want:   "https://app.example.com",
+		},
+		{
+			name:   "admin route rejects the app origin",
+			path:   "/admin/users",
+			origin: "https://app.example.com",
+			want:   "",
+		},
+		{
+			name:   "admin route allows the admin origin",
+			path:   "/admin/users",
+			origin: "https://admin.example.com",
+			want:   "https://admin.example.com",
+		},
+		{
+			name:   "wildcard route allows any origin",
+			path:   "/public/docs",
+			origin: "https://other.example.com",
+			want:   "*",
+		},
+		{
+			name:   "wildcard route with credentials echoes the origin",
+			path:   "/shared/docs",
+			origin: "https://other.example.com",
+			want:   "https://other.example.com",
+		},
+		{
+			name:   "wildcard route without origin",
+			path:   "/public/docs",
+			origin: "",
+			want:   "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("Origin", tc.origin)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.want {
+				t.Errorf("expected Access-Control-Allow-Origin %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReady(t *testing.T) {
+	var ready atomic.Bool
+
+	srv := httptest.NewServer(Ready(&ready)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/users")
+	if err != nil {
+		t.Fatalf("could not send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected status code %d before ready, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("expected Retry-After header before ready")
+	}
+
+	ready.Store(true)
+
+	resp, err = http.Get(srv.URL + "/users")
+	if err != nil {
+		t.Fatalf("could not send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status code %d after ready, got %d", http.StatusOK, resp.StatusCode)
+	}
+}
+
+func TestReady_Cors(t *testing.T) {
+	var ready atomic.Bool
+
+	// Cors runs before Ready, as in main, so the browsers can read the 503
+	h := Chain(
+		Cors(CorsOpts{AllowedOrigins: []string{"https://app.example.com"}}),
+		Ready(&ready),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status code %d before ready, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected Access-Control-Allow-Origin https://app.example.com, got %q", got)
+	}
+
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header before ready")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	h := ReadOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method     string
+		statusCode int
+	}{
+		{method: http.MethodGet, statusCode: http.StatusOK},
+		{method: http.MethodHead, statusCode: http.StatusOK},
+		{method: http.MethodPost, statusCode: http.StatusServiceUnavailable},
+		{method: http.MethodPut, statusCode: http.StatusServiceUnavailable},
+		{method: http.MethodPatch, statusCode: http.StatusServiceUnavailable},
+		{method: http.MethodDelete, statusCode: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.method, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/users", nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, w.Code)
+			}
+
+			if tc.statusCode == http.StatusServiceUnavailable && w.Header().Get("Retry-After") == "" {
+				t.Error("expected Retry-After header")
+			}
+		})
+	}
+}
+
+func TestHeaderAPIVersion(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "default header",
+			header: "",
+			want:   "X-API-Version",
+		},
+		{
+			name:   "configured header",
+			header: "X-Service-Version",
+			want:   "X-Service-Version",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/not-found", nil)
+
+			HeaderAPIVersion(tc.header, version.Version)(next).ServeHTTP(w, r)
+
+			if got := w.Header().Get(tc.want); got != version.Version {
+				t.Errorf("expected header %s %q, got %q", tc.want, version.Version, got)
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"swagger":"2.0"}`))
+	})
+
+	h := CacheControl(time.Hour)(next)
+
+	// the first request gets the document and its validators
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doc.json", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("expected Cache-Control %q, got %q", "public, max-age=3600", got)
+	}
+
+	if got := w.Body.String(); got != `{"swagger":"2.0"}` {
+		t.Errorf("expected the body to be written, got %q", got)
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag")
+	}
+
+	// the revalidation with the same ETag gets no body
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/doc.json", nil)
+	r.Header.Set("If-None-Match", "W/"+etag)
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified {
+		t.Fatalf("expected status code %d, got %d", http.StatusNotModified, w.Code)
+	}
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected an empty body, got %q", w.Body.String())
+	}
+
+	// the errors are not cached
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status code %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	if got := w.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("expected no Cache-Control, got %q", got)
+	}
+
+	// a zero max age makes the browsers revalidate
+	w = httptest.NewRecorder()
+	CacheControl(0)(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/doc.json", nil))
+
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("expected Cache-Control %q, got %q", "no-cache", got)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("secret internal state")
+	})
+	mux.HandleFunc("GET /ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	srv := httptest.NewServer(Chain(RequestID, Recover)(mux))
+	defer srv.Close()
+
+	tests := []struct {
+		path       string
+		statusCode int
+	}{
+		{path: "/panic", statusCode: http.StatusInternalServerError},
+		// the server stays up after the panic
+		{path: "/ok", statusCode: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			resp, err := srv.Client().Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, resp.StatusCode)
+			}
+
+			if tc.statusCode != http.StatusInternalServerError {
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("could not read body: %v", err)
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.Unmarshal(body, &msg); err != nil {
+				t.Fatalf("could not decode body %q: %v", body, err)
+			}
+
+			if msg.Code != respond.CodeInternalServerError {
+				t.Errorf("expected code %s, got %s", respond.CodeInternalServerError, msg.Code)
+			}
+
+			if strings.Contains(string(body), "secret") {
+				t.Errorf("expected the panic not to be exposed, got %s", body)
+			}
+		})
+	}
+}
+
+func TestContentType(t *testing.T) {
+	h := ContentType(ContentTypeOpts{
+		Allowed:     []string{"application/json"},
+		RouteGroups: map[string][]string{"/users/import": {"text/csv"}},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		statusCode  int
+	}{
+		{
+			name:        "allowed content type",
+			method:      http.MethodPost,
+			target:      "/users",
+			contentType: "application/json",
+			body:        `{"first_name":"John"}`,
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:        "allowed content type with parameters",
+			method:      http.MethodPut,
+			target:      "/users/1",
+			contentType: "Application/JSON; charset=utf-8",
+			body:        `{"first_name":"John"}`,
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:        "disallowed content type",
+			method:      http.MethodPost,
+			target:      "/users",
+			contentType: "text/csv",
+			body:        "first_name\nJohn\n",
+			statusCode:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "missing content type",
+			method:     http.MethodPost,
+			target:     "/users",
+			body:       `{"first_name":"John"}`,
+			statusCode: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "allowed content type of the route group",
+			method:      http.MethodPost,
+			target:      "/users/import",
+			contentType: "text/csv",
+			body:        "first_name\nJohn\n",
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:        "disallowed content type of the route group",
+			method:      http.MethodPost,
+			target:      "/users/import",
+			contentType: "application/json",
+			body:        `{"first_name":"John"}`,
+			statusCode:  http.StatusUnsupportedMediaType,
+		},
+		{
+			name:       "request without body",
+			method:     http.MethodDelete,
+			target:     "/users/1",
+			statusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+
+			req := httptest.NewRequest(tc.method, tc.target, body)
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, w.Code)
+			}
+
+			if tc.statusCode != http.StatusUnsupportedMediaType {
+				return
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+				t.Fatalf("could not decode body: %v", err)
+			}
+
+			if msg.Code != respond.CodeUnsupportedMedia {
+				t.Errorf("expected code %s, got %s", respond.CodeUnsupportedMedia, msg.Code)
+			}
+		})
+	}
+}
+
+func TestContentType_Cors(t *testing.T) {
+	// Cors runs before ContentType, as in main, so the browsers can read the 415
+	h := Chain(
+		Cors(CorsOpts{AllowedOrigins: []string{"https://app.example.com"}}),
+		ContentType(ContentTypeOpts{Allowed: []string{"application/json"}}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader("first_name=John"))
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("expected status code %d, got %d", http.StatusUnsupportedMediaType, w.Code)
+	}
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected Access-Control-Allow-Origin https://app.example.com, got %q", got)
+	}
+}
+
+func TestTracing_LogsTraceID(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(o11y.NewLogger(slog.NewJSONHandler(&logs, nil), "test", ""))
+	defer slog.SetDefault(defaultLogger)
+
+	defaultProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	defer otel.SetTracerProvider(defaultProvider)
+
+	var handlerTraceID string
+	h := Chain(Tracing, Logging)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerTraceID = trace.SpanContextFromContext(r.Context()).TraceID().String()
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+
+	var line map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+		t.Fatalf("could not decode log line %q: %v", logs.String(), err)
+	}
+
+	if line["trace_id"] == nil || line["trace_id"] != handlerTraceID {
+		t.Errorf("expected the request log line to have the trace ID %s, got %v", handlerTraceID, line["trace_id"])
+	}
+
+	if line["span_id"] == nil {
+		t.Error("expected the request log line to have a span ID")
+	}
+}
+
+func TestSlowRequest(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	h := SlowRequest(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	tests := []struct {
+		name   string
+		target string
+		logged bool
+	}{
+		{name: "fast request is not logged", target: "/fast", logged: false},
+		{name: "slow request is logged", target: "/slow", logged: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			line := logs.String()
+			if !tc.logged {
+				if line != "" {
+					t.Errorf("expected no log, got %s", line)
+				}
+				return
+			}
+
+			for _, want := range []string{"level=WARN", `msg="slow request"`, "path=" + tc.target, "status=202", "duration="} {
+				if !strings.Contains(line, want) {
+					t.Errorf("expected the log to contain %s, got %s", want, line)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	h := AllowedMethods(http.MethodGet, http.MethodHead, http.MethodOptions)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method     string
+		statusCode int
+	}{
+		{method: http.MethodGet, statusCode: http.StatusOK},
+		{method: http.MethodHead, statusCode: http.StatusOK},
+		{method: http.MethodOptions, statusCode: http.StatusOK},
+		{method: http.MethodPost, statusCode: http.StatusMethodNotAllowed},
+		{method: http.MethodPut, statusCode: http.StatusMethodNotAllowed},
+		{method: http.MethodPatch, statusCode: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, statusCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.method, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/users", nil)
+			w := httptest.NewRecorder()
+
+			h.ServeHTTP(w, req)
+
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, w.Code)
+			}
+
+			if tc.statusCode == http.StatusMethodNotAllowed && w.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+				t.Errorf("expected Allow header GET, HEAD, OPTIONS, got %q", w.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+func TestAllowedMethods_Cors(t *testing.T) {
+	// Cors runs before AllowedMethods, as in main, so the browsers can read the 405
+	h := Chain(
+		Cors(CorsOpts{AllowedOrigins: []string{"https://app.example.com"}}),
+		AllowedMethods(http.MethodGet, http.MethodHead, http.MethodOptions),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status code %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected Access-Control-Allow-Origin https://app.example.com, got %q", got)
+	}
+}
+
+func TestReadOnly_CorsPreflight(t *testing.T) {
+	// Cors runs before ReadOnly, as in main, so the browsers can read the 503
+	h := Chain(
+		Cors(CorsOpts{AllowedOrigins: []string{"https://app.example.com"}}),
+		ReadOnly,
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	// Given the preflight of the cross-origin POST
+	preflight := httptest.NewRequest(http.MethodOptions, "/users", nil)
+	preflight.Header.Set("Origin", "https://app.example.com")
+	preflight.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, preflight)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected preflight status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	if !strings.Contains(w.Header().Get("Access-Control-Allow-Methods"), http.MethodPost) {
+		t.Fatalf("expected the preflight to allow POST, got %q", w.Header().Get("Access-Control-Allow-Methods"))
+	}
+
+	// When
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"first_name":"John"}`))
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	h.ServeHTTP(w, req)
+
+	// Then
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status code %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("expected Access-Control-Allow-Origin https://app.example.com, got %q", got)
+	}
+
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	if got := w.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "Retry-After") {
+		t.Errorf("expected Retry-After in Access-Control-Expose-Headers, got %q", got)
+	}
+}
+
+func TestCamelCaseJSON(t *testing.T) {
+	type user struct {
+		ID        string `json:"id"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+	}
+
+	// the handler echoes the user decoded from the request body
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var u user
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			t.Fatalf("could not decode request: %v", err)
+		}
+
+		if err := respond.WriteJSON(w, http.StatusOK, u); err != nil {
+			t.Fatalf("could not write response: %v", err)
+		}
+	})
+
+	snake := "{\"id\":\"1\",\"first_name\":\"John\",\"last_name\":\"Doe\"}\n"
+	camel := "{\"id\":\"1\",\"firstName\":\"John\",\"lastName\":\"Doe\"}\n"
+
+	tests := []struct {
+		name   string
+		opts   CamelCaseJSONOpts
+		accept string
+		body   string
+		want   string
+	}{
+		{
+			name: "snake case by default",
+			body: `{"id":"1","first_name":"John","last_name":"Doe"}`,
+			want: snake,
+		},
+		{
+			name: "camel case when configured",
+			opts: CamelCaseJSONOpts{Camel: true},
+			body: `{"id":"1","firstName":"John","lastName":"Doe"}`,
+			want: camel,
+		},
+		{
+			name: "snake case input accepted in camel case",
+			opts: CamelCaseJSONOpts{Camel: true},
+			body: `{"id":"1","first_name":"John","last_name":"Doe"}`,
+			want: camel,
+		},
+		{
+			name:   "camel case asked by the Accept header",
+			opts:   CamelCaseJSONOpts{Negotiation: true},
+			accept: "application/json; casing=camel",
+			body:   `{"id":"1","firstName":"John","lastName":"Doe"}`,
+			want:   camel,
+		},
+		{
+			name:   "Accept header ignored without negotiation",
+			accept: "application/json; casing=camel",
+			body:   `{"id":"1","first_name":"John","last_name":"Doe"}`,
+			want:   snake,
+		},
+		{
+			name:   "snake case asked by the Accept header",
+			opts:   CamelCaseJSONOpts{Camel: true, Negotiation: true},
+			accept: "text/html, application/json; casing=snake",
+			body:   `{"id":"1","first_name":"John","last_name":"Doe"}`,
+			want:   snake,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			h := CamelCaseJSON(tc.opts)(echo)
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", "application/json")
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+
+			// When
+			h.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			if w.Body.String() != tc.want {
+				t.Errorf("expected body %q, got %q", tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCamelCaseJSON_MaxBodyBytes(t *testing.T) {
+	h := CamelCaseJSON(CamelCaseJSONOpts{Camel: true, MaxBodyBytes: 32})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected the handler not to be called")
+	}))
+
+	tests := []struct {
+		name          string
+		contentLength int64
+	}{
+		{name: "declared length", contentLength: 64},
+		{name: "unknown length", contentLength: -1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"firstName":"`+strings.Repeat("a", 50)+`"}`))
+			r.Header.Set("Content-Type", "application/json")
+			r.ContentLength = tc.contentLength
+			w := httptest.NewRecorder()
+
+			// When
+			h.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("expected status code %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.NewDecoder(w.Body).Decode(&msg); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if msg.Code != respond.CodeRequestTooLarge {
+				t.Errorf("expected code %s, got %s", respond.CodeRequestTooLarge, msg.Code)
+			}
+		})
+	}
+}
+
+func TestConvertJSONKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "order kept in nested objects and arrays",
+			data: `{"user_id": 1, "items": [{"first_name": "<a>", "last_seen": null}, 2.50], "is_ok": true}` + "\n",
+			want: `{"userId":1,"items":[{"firstName":"<a>","lastSeen":null},2.50],"isOk":true}` + "\n",
+		},
+		{
+			name: "values are not converted",
+			data: `["first_name", {"first_name": "last_name"}]`,
+			want: `["first_name",{"firstName":"last_name"}]`,
+		},
+		{name: "invalid document", data: `{"first_name":`, wantErr: true},
+		{name: "more than one document", data: `{} {}`, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := convertJSONKeys([]byte(tc.data), snakeToCamel)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %t, got %v", tc.wantErr, err)
+			}
+
+			if !tc.wantErr && string(got) != tc.want {
+				t.Errorf("expected %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCamelToSnake(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "firstName", want: "first_name"},
+		{key: "first_name", want: "first_name"},
+		{key: "id", want: "id"},
+		{key: "requestID", want: "request_id"},
+		{key: "HTTPServer", want: "http_server"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := camelToSnake(tc.key); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout_ClientCanceled(t *testing.T) {
+	var logs bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	started := make(chan struct{})
+	done := make(chan int)
+
+	// the handler responds 500 to the error of its canceled context, as the users handlers do
+	h := RequestTimeout(RequestTimeoutOpts{Timeout: time.Minute})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, r.Context().Err().Error())
+	}))
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wrapped := newWrappedResponseWriter(w)
+		h.ServeHTTP(wrapped, r)
+		done <- wrapped.status
+	}))
+	defer srv.Close()
+
+	// Given
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/users", nil)
+	if err != nil {
+		t.Fatalf("could not create request: %v", err)
+	}
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	// When
+	if _, err := srv.Client().Do(req); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the request to be canceled, got %v", err)
+	}
+
+	// Then
+	select {
+	case status := <-done:
+		if status != respond.StatusClientClosedRequest {
+			t.Errorf("expected status %d, got %d", respond.StatusClientClosedRequest, status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handler to return once the client closed the request")
+	}
+
+	line := logs.String()
+	for _, want := range []string{"level=INFO", `msg="client closed request"`, "path=/users"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("expected the log to contain %s, got %s", want, line)
+		}
+	}
+}
+
+func TestRequestTimeout_ServerTimeout(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeout    time.Duration
+		statusCode int
+		code       string
+	}{
+		{name: "timed out", timeout: 20 * time.Millisecond, statusCode: http.StatusGatewayTimeout, code: respond.CodeGatewayTimeout},
+		{name: "handler error without timeout", timeout: 0, statusCode: http.StatusInternalServerError, code: respond.CodeInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			h := RequestTimeout(RequestTimeoutOpts{Timeout: tc.timeout})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(100 * time.Millisecond):
+				}
+				respond.WriteError(w, r, http.StatusInternalServerError, respond.CodeInternalServerError, "internal server error")
+			}))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/users", nil)
+
+			// When
+			h.ServeHTTP(w, r)
+
+			// Then
+			if w.Code != tc.statusCode {
+				t.Fatalf("expected status code %d, got %d", tc.statusCode, w.Code)
+			}
+
+			var msg respond.HTTPMessage
+			if err := json.NewDecoder(w.Body).Decode(&msg); err != nil {
+				t.Fatalf("could not decode response: %v", err)
+			}
+
+			if msg.Code != tc.code {
+				t.Errorf("expected code %s, got %s", tc.code, msg.Code)
+			}
+		})
+	}
+}
+
+func TestRequestTimeout_SkipsStreams(t *testing.T) {
+	timeout := 20 * time.Millisecond
+
+	// the stream sends an event every few milliseconds for several times the timeout
+	h := RequestTimeout(RequestTimeoutOpts{Timeout: timeout, SkipRoutes: []string{"/events"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		rc := http.NewResponseController(w)
+		for i := range 10 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(timeout / 2):
+			}
+
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			rc.Flush()
+		}
+	}))
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		complete bool
+	}{
+		{name: "stream exempted", path: "/events", complete: true},
+		{name: "other route timed out", path: "/users", complete: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			resp, err := http.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			// When
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("could not read stream: %v", err)
+			}
+
+			// Then
+			events := strings.Count(string(body), "data: ")
+			if tc.complete && events != 10 {
+				t.Errorf("expected the 10 events of the open stream, got %d", events)
+			}
+
+			if !tc.complete && events >= 10 {
+				t.Errorf("expected the stream to be closed at the timeout, got %d events", events)
+			}
+		})
+	}
+}
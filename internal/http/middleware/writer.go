@@ -1,6 +1,16 @@
 package middleware
 
-import "net/http"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/http/respond"
+)
 
 // Thanks to:
 // - https://github.com/denpeshkov/greenlight/blob/c68f5a2111adcd5b1a65a06595acc93a02b6380e/internal/http/middleware.go#L16-L71
@@ -28,3 +38,192 @@ func (w *wrappedResponseWriter) WriteHeader(status int) {
 func (w *wrappedResponseWriter) Unwrap() http.ResponseWriter {
 	return w.ResponseWriter
 }
+
+// prettyJSONResponseWriter indents the JSON documents written through it.
+// Writes that are not JSON documents are passed through untouched.
+type prettyJSONResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write indents the data when the response is JSON.
+func (w *prettyJSONResponseWriter) Write(data []byte) (int, error) {
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+
+	if _, err := w.ResponseWriter.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// Unwrap is used by a [http.ResponseController].
+func (w *prettyJSONResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// camelCaseJSONResponseWriter converts the keys of the JSON documents written through it
+// to camel case. Writes that are not JSON documents are passed through untouched.
+type camelCaseJSONResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write converts the keys of the data when the response is JSON.
+func (w *camelCaseJSONResponseWriter) Write(data []byte) (int, error) {
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+
+	converted, err := convertJSONKeys(data, snakeToCamel)
+	if err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+
+	if _, err := w.ResponseWriter.Write(converted); err != nil {
+		return 0, err
+	}
+
+	return len(data), nil
+}
+
+// Unwrap is used by a [http.ResponseController].
+func (w *camelCaseJSONResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bodyCaptureResponseWriter keeps a copy of the first bytes written to the response.
+type bodyCaptureResponseWriter struct {
+	http.ResponseWriter
+	body    bytes.Buffer
+	maxSize int
+	size    int
+}
+
+// Write writes the data and captures it up to maxSize bytes.
+func (w *bodyCaptureResponseWriter) Write(data []byte) (int, error) {
+	if remaining := w.maxSize - w.body.Len(); remaining > 0 {
+		w.body.Write(data[:min(len(data), remaining)])
+	}
+	w.size += len(data)
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap is used by a [http.ResponseController].
+func (w *bodyCaptureResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// bufferedResponseWriter holds the status and the body of the response,
+// so they can be inspected before being written to the client.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+// newBufferedResponseWriter creates a new bufferedResponseWriter.
+func newBufferedResponseWriter(w http.ResponseWriter) *bufferedResponseWriter {
+	return &bufferedResponseWriter{
+		ResponseWriter: w,
+		status:         http.StatusOK,
+	}
+}
+
+// WriteHeader holds the status code until the response is flushed.
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Write holds the data until the response is flushed.
+func (w *bufferedResponseWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+// flush writes the held status code and body to the client.
+func (w *bufferedResponseWriter) flush() error {
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+
+	return err
+}
+
+// recoverResponseWriter records whether the response was started.
+type recoverResponseWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+// WriteHeader records the response as started.
+func (w *recoverResponseWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response as started.
+func (w *recoverResponseWriter) Write(data []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap is used by a [http.ResponseController].
+func (w *recoverResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// contextErrorResponseWriter replaces the 500 responses written once the context
+// of the request is done with the outcome of the cancellation or the timeout.
+type contextErrorResponseWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	started bool
+	dropped bool
+}
+
+// WriteHeader maps the status 500 when the context of the request is done.
+func (w *contextErrorResponseWriter) WriteHeader(status int) {
+	if w.started {
+		return
+	}
+	w.started = true
+
+	err := w.r.Context().Err()
+	if status != http.StatusInternalServerError || err == nil {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+
+	w.dropped = true
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		respond.WriteError(w.ResponseWriter, w.r, http.StatusGatewayTimeout, respond.CodeGatewayTimeout, "request timed out")
+		return
+	}
+
+	slog.InfoContext(w.r.Context(), "client closed request", "method", w.r.Method, "path", w.r.URL.Path)
+	w.ResponseWriter.WriteHeader(respond.StatusClientClosedRequest)
+}
+
+// Write discards the body of the mapped responses.
+func (w *contextErrorResponseWriter) Write(data []byte) (int, error) {
+	if !w.started {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.dropped {
+		return len(data), nil
+	}
+
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap is used by a [http.ResponseController].
+func (w *contextErrorResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -7,9 +7,34 @@ import (
 	"time"
 )
 
+// Error codes returned in the code field of HTTPMessage.
+const (
+	CodeBadRequest          = "bad_request"
+	CodeMalformedJSON       = "malformed_json"
+	CodeUnknownField        = "unknown_field"
+	CodeInvalidFieldType    = "invalid_field_type"
+	CodeInvalidLimit        = "invalid_limit"
+	CodeTokenTooLong        = "token_too_long"
+	CodeNotFound            = "not_found"
+	CodeMethodNotAllowed    = "method_not_allowed"
+	CodeConflict            = "conflict"
+	CodeRequestTooLarge     = "request_entity_too_large"
+	CodeUnsupportedMedia    = "unsupported_media_type"
+	CodeUnprocessableEntity = "unprocessable_entity"
+	CodeInternalServerError = "internal_server_error"
+	CodeServiceUnavailable  = "service_unavailable"
+	CodeGatewayTimeout      = "gateway_timeout"
+)
+
+// StatusClientClosedRequest is the nonstandard status recorded for the requests
+// canceled by the client before the response was written, as nginx does.
+const StatusClientClosedRequest = 499
+
 type HTTPMessage struct {
 	Timestamp  time.Time `json:"timestamp"`
 	StatusCode int       `json:"status_code"`
+	Code       string    `json:"code,omitempty"`
+	ID         string    `json:"id,omitempty"`
 	Message    string    `json:"message"`
 	Method     string    `json:"method"`
 	Path       string    `json:"path"`
@@ -23,38 +48,57 @@ func (e *HTTPMessage) Error() string {
 	return e.Message
 }
 
-// WriteJSONData writes the given data to the client as a JSON response.
-func WriteJSONData(w http.ResponseWriter, statusCode int, data interface{}) error {
+// NewHTTPMessage returns a HTTPMessage populated with the method and path of the request.
+func NewHTTPMessage(r *http.Request, statusCode int, code, message string) HTTPMessage {
+	return HTTPMessage{
+		Timestamp:  time.Now().UTC(),
+		StatusCode: statusCode,
+		Code:       code,
+		Message:    message,
+		Method:     r.Method,
+		Path:       r.URL.Path,
+	}
+}
+
+// WriteJSON writes the given value to the client as a JSON response with the given status code.
+func WriteJSON(w http.ResponseWriter, statusCode int, v any) error {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// WriteError writes an error response to the client with the given status code, error code and message.
+func WriteError(w http.ResponseWriter, r *http.Request, statusCode int, code, message string) {
+	writeHTTPMessage(w, r, NewHTTPMessage(r, statusCode, code, message))
+}
+
 // WriteJSONMessage writes a success log and response to the client with the given status code and message.
 func WriteJSONMessage(w http.ResponseWriter, r *http.Request, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+	writeHTTPMessage(w, r, NewHTTPMessage(r, statusCode, "", message))
+}
 
-	var success HTTPMessage
-	success.Timestamp = time.Now()
-	success.StatusCode = statusCode
-	success.Message = message
-	success.Method = r.Method
-	success.Path = r.URL.Path
+// WriteJSONCreated writes a 201 Created response to the client with the ID of the created resource,
+// so the clients letting the server generate the ID can learn it.
+func WriteJSONCreated(w http.ResponseWriter, r *http.Request, id string, message string) {
+	msg := NewHTTPMessage(r, http.StatusCreated, "", message)
+	msg.ID = id
 
-	if err := json.NewEncoder(w).Encode(success); err != nil {
-		slog.Error("failed to write JSON response", "error", err)
+	writeHTTPMessage(w, r, msg)
+}
 
-		http.Error(w, "failed to write JSON response", http.StatusInternalServerError)
+func writeHTTPMessage(w http.ResponseWriter, r *http.Request, msg HTTPMessage) {
+	if err := WriteJSON(w, msg.StatusCode, msg); err != nil {
+		slog.Error("failed to write JSON response", "error", err)
 	}
 
-	slog.Debug(message,
-		"status_code", statusCode,
+	slog.Debug(msg.Message,
+		"status_code", msg.StatusCode,
+		"code", msg.Code,
 		"method", r.Method,
 		"url", r.URL.Path,
 		"query", r.URL.RawQuery,
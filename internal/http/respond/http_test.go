@@ -0,0 +1,132 @@
+package respond
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	if err := WriteJSON(w, http.StatusCreated, map[string]string{"id": "1"}); err != nil {
+		t.Fatalf("could not write response: %v", err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected content type %q, got %q", "application/json", ct)
+	}
+
+	if want := "{\"id\":\"1\"}\n"; w.Body.String() != want {
+		t.Errorf("expected body %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestWriteJSON_Unsupported(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	if err := WriteJSON(w, http.StatusOK, make(chan int)); err == nil {
+		t.Error("expected error encoding unsupported value, got nil")
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodDelete, "/users/123?force=true", nil)
+
+	WriteError(w, r, http.StatusConflict, CodeConflict, "user email already exists")
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status code %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected content type %q, got %q", "application/json", ct)
+	}
+
+	var msg HTTPMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+
+	if msg.StatusCode != http.StatusConflict {
+		t.Errorf("expected status code %d, got %d", http.StatusConflict, msg.StatusCode)
+	}
+
+	if msg.Code != CodeConflict {
+		t.Errorf("expected code %q, got %q", CodeConflict, msg.Code)
+	}
+
+	if msg.Message != "user email already exists" {
+		t.Errorf("expected message %q, got %q", "user email already exists", msg.Message)
+	}
+
+	if msg.Method != http.MethodDelete {
+		t.Errorf("expected method %q, got %q", http.MethodDelete, msg.Method)
+	}
+
+	if msg.Path != "/users/123" {
+		t.Errorf("expected path %q, got %q", "/users/123", msg.Path)
+	}
+
+	if msg.Timestamp.IsZero() {
+		t.Error("expected timestamp to be set")
+	}
+}
+
+func TestWriteJSONMessage(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+
+	WriteJSONMessage(w, r, http.StatusCreated, "User created")
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &raw); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+
+	if _, ok := raw["code"]; ok {
+		t.Errorf("expected code to be omitted, got %v", raw["code"])
+	}
+
+	if _, ok := raw["id"]; ok {
+		t.Errorf("expected id to be omitted, got %v", raw["id"])
+	}
+
+	if raw["message"] != "User created" {
+		t.Errorf("expected message %q, got %v", "User created", raw["message"])
+	}
+}
+
+func TestWriteJSONCreated(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/users", nil)
+
+	WriteJSONCreated(w, r, "e1cdf461-87c7-465f-a374-dc6bc7e962b9", "User created")
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, w.Code)
+	}
+
+	var msg HTTPMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &msg); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+
+	if msg.ID != "e1cdf461-87c7-465f-a374-dc6bc7e962b9" {
+		t.Errorf("expected id %q, got %q", "e1cdf461-87c7-465f-a374-dc6bc7e962b9", msg.ID)
+	}
+
+	if msg.StatusCode != http.StatusCreated {
+		t.Errorf("expected status code %d, got %d", http.StatusCreated, msg.StatusCode)
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateCheckInterval is the minimum time between the checks of the
+// modification times of the certificate files.
+const certificateCheckInterval = 10 * time.Second
+
+// CertificateReloader serves a certificate reloaded from its files when they change,
+// so the renewed certificates are served without restarting the server.
+// It is safe for concurrent use.
+type CertificateReloader struct {
+	certFile      string
+	keyFile       string
+	checkInterval time.Duration
+
+	mu          sync.RWMutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+// NewCertificateReloader creates a new CertificateReloader for the certificate and private key files.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certFile:      certFile,
+		keyFile:       keyFile,
+		checkInterval: certificateCheckInterval,
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload loads the certificate and private key files.
+// The current certificate is kept when they can't be loaded.
+func (r *CertificateReloader) Reload() error {
+	certModTime, keyModTime, err := r.modTimes()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.certModTime = certModTime
+	r.keyModTime = keyModTime
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+
+	slog.Info("tls certificate loaded", "file", r.certFile, "not_after", cert.Leaf.NotAfter)
+
+	return nil
+}
+
+// Certificate returns the current certificate, reloaded first
+// when the files changed since the last check.
+func (r *CertificateReloader) Certificate() *tls.Certificate {
+	if r.changed() {
+		if err := r.Reload(); err != nil {
+			slog.Error("tls certificate reload error", "file", r.certFile, "error", err)
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert
+}
+
+// GetCertificate returns the current certificate,
+// to be used as the GetCertificate function of a [tls.Config].
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// changed returns true when the modification time of a file changed since the last load.
+// The files are checked at most once per check interval.
+func (r *CertificateReloader) changed() bool {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < r.checkInterval {
+		r.mu.Unlock()
+		return false
+	}
+	r.lastCheck = time.Now()
+	certModTime, keyModTime := r.certModTime, r.keyModTime
+	r.mu.Unlock()
+
+	newCertModTime, newKeyModTime, err := r.modTimes()
+	if err != nil {
+		slog.Error("tls certificate check error", "file", r.certFile, "error", err)
+		return false
+	}
+
+	return !newCertModTime.Equal(certModTime) || !newKeyModTime.Equal(keyModTime)
+}
+
+// modTimes returns the modification times of the certificate and private key files.
+func (r *CertificateReloader) modTimes() (certModTime, keyModTime time.Time, err error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return certInfo.ModTime(), keyInfo.ModTime(), nil
+}
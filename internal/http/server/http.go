@@ -6,27 +6,37 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/config"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
 )
 
 type HTTPServerConfig struct {
 	Ctx         context.Context
 	HttpHandler http.Handler
 	Config      *config.HTTPServerConfig
+
+	// OnReload is called when the server receives SIGHUP
+	OnReload func()
 }
 
 type HTTPServer struct {
 	ctx        context.Context
 	httpServer *http.Server
 	conf       *config.HTTPServerConfig
+	onReload   func()
+
+	// certificates is the TLS certificate reloaded on SIGHUP and when its files change
+	certificates *CertificateReloader
 
-	osSigChan chan os.Signal
-	stopChan  chan struct{}
+	osSigChan     chan os.Signal
+	stopChan      chan struct{}
+	listeningChan chan struct{}
 }
 
 func NewHTTPServer(conf HTTPServerConfig) *HTTPServer {
@@ -39,12 +49,15 @@ func NewHTTPServer(conf HTTPServerConfig) *HTTPServer {
 	server := &HTTPServer{
 		ctx: conf.Ctx,
 		httpServer: &http.Server{
-			Addr:    addr,
-			Handler: conf.HttpHandler,
+			Addr:           addr,
+			Handler:        conf.HttpHandler,
+			MaxHeaderBytes: conf.Config.MaxHeaderBytes.Value,
 		},
-		conf:      conf.Config,
-		osSigChan: make(chan os.Signal, 1),
-		stopChan:  make(chan struct{}),
+		conf:          conf.Config,
+		onReload:      conf.OnReload,
+		osSigChan:     make(chan os.Signal, 1),
+		stopChan:      make(chan struct{}),
+		listeningChan: make(chan struct{}),
 	}
 
 	// notify the server to listen for OS signals
@@ -56,20 +69,38 @@ func NewHTTPServer(conf HTTPServerConfig) *HTTPServer {
 func (s *HTTPServer) Start() {
 	slog.Info("starting http server", "address", s.httpServer.Addr, "tls", s.conf.TLSEnabled.Value)
 
-	// Listen for OS signals
+	if s.conf.TLSEnabled.Value {
+		if err := s.setTLSConfig(); err != nil {
+			slog.Error("http server tls error", "error", err)
+
+			s.Stop()
+			return
+		}
+	}
+
+	// Listen for OS signals, after the TLS certificate reloaded on SIGHUP is set
 	s.listenOsSignals()
 
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		slog.Error("http server error", "error", err)
+
+		s.Stop()
+		return
+	}
+
+	// the connections are accepted from now on
+	close(s.listeningChan)
+
 	if s.conf.TLSEnabled.Value {
-		if err := s.httpServer.ListenAndServeTLS(
-			s.conf.CertificateFile.Value.Name(),
-			s.conf.PrivateKeyFile.Value.Name(),
-		); !errors.Is(err, http.ErrServerClosed) {
+		// the certificate is served by the GetCertificate function of the TLS configuration
+		if err := s.httpServer.ServeTLS(ln, "", ""); !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("http server error", "error", err)
 
 			s.Stop()
 		}
 	} else {
-		if err := s.httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		if err := s.httpServer.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("http server error", "error", err)
 
 			s.Stop()
@@ -77,6 +108,11 @@ func (s *HTTPServer) Start() {
 	}
 }
 
+// Listening returns a channel closed once the server listens for connections.
+func (s *HTTPServer) Listening() <-chan struct{} {
+	return s.listeningChan
+}
+
 func (s *HTTPServer) Wait() <-chan struct{} {
 	return s.stopChan
 }
@@ -101,18 +137,28 @@ func (s *HTTPServer) listenOsSignals() {
 				switch sig {
 				case os.Interrupt, syscall.SIGINT, syscall.SIGTERM:
 					slog.Warn("shutting down http server...")
+					shutdownDone := o11y.NewLifecycle("shutdown").Phase("http server")
 					if err := s.httpServer.Shutdown(ctx); err != nil {
 						slog.Error("http server shutdown with error", "error", err)
 						os.Exit(1)
 					}
+					shutdownDone()
 					close(s.stopChan)
 					return
 				case syscall.SIGHUP:
 					slog.Warn("reloading http server...")
-					// Reload the server
-					// This is where you would reload the server
+					if s.certificates != nil {
+						if err := s.certificates.Reload(); err != nil {
+							slog.Error("tls certificate reload error", "error", err)
+						}
+					}
 
-					return
+					if s.onReload != nil {
+						s.onReload()
+					}
+
+					// keep listening, the server continues to run
+					continue
 				default:
 					slog.Warn("unknown signal", "signal", sig)
 					return
@@ -125,10 +171,12 @@ func (s *HTTPServer) listenOsSignals() {
 }
 
 // setTLSConfig sets the TLS configuration for the server.
-//
-//lint:ignore U1000 This function is used depending on the configuration.
 func (s *HTTPServer) setTLSConfig() error {
-	slog.Info("configuring tls")
+	slog.Info("configuring tls",
+		"profile", s.conf.TLSProfile.Value,
+		"min_version", s.conf.TLSMinVersion.Value,
+		"max_version", s.conf.TLSMaxVersion.Value,
+	)
 	if _, err := os.Stat(s.conf.CertificateFile.Value.Name()); os.IsNotExist(err) {
 		slog.Error(".crt file not found", "file", s.conf.CertificateFile.Value.Name(), "error", err)
 		return err
@@ -139,19 +187,88 @@ func (s *HTTPServer) setTLSConfig() error {
 		return err
 	}
 
-	tlsCfg := &tls.Config{
-		MinVersion:               tls.VersionTLS13,
-		CurvePreferences:         []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256},
-		PreferServerCipherSuites: true,
-		CipherSuites: []uint16{
-			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
-			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
-			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
-		},
+	tlsCfg, err := newTLSConfig(s.conf.TLSProfile.Value, s.conf.TLSMinVersion.Value, s.conf.TLSMaxVersion.Value)
+	if err != nil {
+		return err
+	}
+
+	certificates, err := NewCertificateReloader(s.conf.CertificateFile.Value.Name(), s.conf.PrivateKeyFile.Value.Name())
+	if err != nil {
+		slog.Error("tls certificate error", "error", err)
+		return err
 	}
+	s.certificates = certificates
+	tlsCfg.GetCertificate = certificates.GetCertificate
+
+	if s.conf.TLSOCSPStapling.Value {
+		stapler, err := NewOCSPStapler(certificates)
+		if err != nil {
+			slog.Error("ocsp stapling error", "error", err)
+			return err
+		}
+
+		// the handshakes continue without staple until the first refresh succeeds
+		go stapler.Run(s.ctx)
+		tlsCfg.GetCertificate = stapler.GetCertificate
+	}
+
 	s.httpServer.TLSConfig = tlsCfg
 	s.httpServer.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler), 0)
 
 	return nil
 }
+
+// tlsVersions are the TLS versions accepted as bounds, by name.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTLSConfig returns the TLS configuration of the profile.
+// The modern profile only accepts TLS 1.3, whose cipher suites are not configurable.
+// The intermediate profile also accepts TLS 1.2 with the forward secret AEAD cipher suites.
+// The min and max versions, when not empty, override the bounds of the profile.
+func newTLSConfig(profile, minVersion, maxVersion string) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		CurvePreferences: []tls.CurveID{tls.CurveP521, tls.CurveP384, tls.CurveP256},
+	}
+
+	switch profile {
+	case config.HTTPServerTLSProfileModern:
+		tlsCfg.MinVersion = tls.VersionTLS13
+	case config.HTTPServerTLSProfileIntermediate:
+		tlsCfg.MinVersion = tls.VersionTLS12
+		tlsCfg.CipherSuites = []uint16{
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		}
+	default:
+		return nil, config.ErrHTTPServerInvalidConfigTLSProfile
+	}
+
+	if minVersion != "" {
+		v, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, config.ErrHTTPServerInvalidConfigTLSVersion
+		}
+		tlsCfg.MinVersion = v
+	}
+
+	if maxVersion != "" {
+		v, ok := tlsVersions[maxVersion]
+		if !ok {
+			return nil, config.ErrHTTPServerInvalidConfigTLSVersion
+		}
+		tlsCfg.MaxVersion = v
+	}
+
+	if tlsCfg.MaxVersion != 0 && tlsCfg.MinVersion > tlsCfg.MaxVersion {
+		return nil, config.ErrHTTPServerInvalidConfigTLSVersion
+	}
+
+	return tlsCfg, nil
+}
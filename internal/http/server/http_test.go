@@ -0,0 +1,216 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/config"
+)
+
+func TestHTTPServer_MaxHeaderBytes(t *testing.T) {
+	conf := config.NewHTTPServerConfig()
+	conf.MaxHeaderBytes.Value = 1 << 10
+
+	s := NewHTTPServer(HTTPServerConfig{
+		HttpHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		Config: conf,
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+
+	go s.httpServer.Serve(ln)
+	defer s.httpServer.Close()
+
+	tests := []struct {
+		name       string
+		headerSize int
+		statusCode int
+	}{
+		{
+			name:       "small headers are accepted",
+			headerSize: 64,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "oversized headers are rejected",
+			headerSize: 64 << 10,
+			statusCode: http.StatusRequestHeaderFieldsTooLarge,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "http://"+ln.Addr().String()+"/", nil)
+			if err != nil {
+				t.Fatalf("could not create request: %v", err)
+			}
+			req.Header.Set("X-Padding", strings.Repeat("a", tc.headerSize))
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("could not send request: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.statusCode {
+				t.Errorf("expected status code %d, got %d", tc.statusCode, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestHTTPServer_Reload(t *testing.T) {
+	reloaded := make(chan struct{}, 2)
+
+	s := NewHTTPServer(HTTPServerConfig{
+		HttpHandler: http.NotFoundHandler(),
+		Config:      config.NewHTTPServerConfig(),
+		OnReload: func() {
+			reloaded <- struct{}{}
+		},
+	})
+
+	s.listenOsSignals()
+	defer close(s.stopChan)
+
+	// the server keeps listening for signals after a reload
+	for i := 0; i < 2; i++ {
+		s.osSigChan <- syscall.SIGHUP
+
+		select {
+		case <-reloaded:
+		case <-time.After(time.Second):
+			t.Fatalf("reload %d: expected OnReload to be called", i+1)
+		}
+	}
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		profile      string
+		minVersion   uint16
+		cipherSuites int
+		err          error
+	}{
+		{
+			name:       "modern profile only accepts TLS 1.3",
+			profile:    config.HTTPServerTLSProfileModern,
+			minVersion: tls.VersionTLS13,
+		},
+		{
+			name:         "intermediate profile accepts TLS 1.2 with AEAD cipher suites",
+			profile:      config.HTTPServerTLSProfileIntermediate,
+			minVersion:   tls.VersionTLS12,
+			cipherSuites: 6,
+		},
+		{
+			name:    "unknown profile",
+			profile: "old",
+			err:     config.ErrHTTPServerInvalidConfigTLSProfile,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := newTLSConfig(tc.profile, "", "")
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			if cfg.MinVersion != tc.minVersion {
+				t.Errorf("expected min version %s, got %s", tls.VersionName(tc.minVersion), tls.VersionName(cfg.MinVersion))
+			}
+
+			if len(cfg.CipherSuites) != tc.cipherSuites {
+				t.Errorf("expected %d cipher suites, got %d", tc.cipherSuites, len(cfg.CipherSuites))
+			}
+
+			for _, id := range cfg.CipherSuites {
+				if name := tls.CipherSuiteName(id); strings.Contains(name, "CBC") {
+					t.Errorf("unexpected CBC cipher suite %s", name)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTLSConfig_Versions(t *testing.T) {
+	tests := []struct {
+		name       string
+		profile    string
+		minVersion string
+		maxVersion string
+		wantMin    uint16
+		wantMax    uint16
+		err        error
+	}{
+		{
+			name:    "profile bounds",
+			profile: config.HTTPServerTLSProfileIntermediate,
+			wantMin: tls.VersionTLS12,
+		},
+		{
+			name:       "require TLS 1.3",
+			profile:    config.HTTPServerTLSProfileIntermediate,
+			minVersion: "1.3",
+			wantMin:    tls.VersionTLS13,
+		},
+		{
+			name:       "only TLS 1.2",
+			profile:    config.HTTPServerTLSProfileIntermediate,
+			minVersion: "1.2",
+			maxVersion: "1.2",
+			wantMin:    tls.VersionTLS12,
+			wantMax:    tls.VersionTLS12,
+		},
+		{
+			name:       "max below the min of the profile",
+			profile:    config.HTTPServerTLSProfileModern,
+			maxVersion: "1.2",
+			err:        config.ErrHTTPServerInvalidConfigTLSVersion,
+		},
+		{
+			name:       "unknown version",
+			profile:    config.HTTPServerTLSProfileIntermediate,
+			minVersion: "1.1",
+			err:        config.ErrHTTPServerInvalidConfigTLSVersion,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := newTLSConfig(tc.profile, tc.minVersion, tc.maxVersion)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+
+			if tc.err != nil {
+				return
+			}
+
+			if cfg.MinVersion != tc.wantMin {
+				t.Errorf("expected min version %s, got %s", tls.VersionName(tc.wantMin), tls.VersionName(cfg.MinVersion))
+			}
+
+			if cfg.MaxVersion != tc.wantMax {
+				t.Errorf("expected max version %s, got %s", tls.VersionName(tc.wantMax), tls.VersionName(cfg.MaxVersion))
+			}
+		})
+	}
+}
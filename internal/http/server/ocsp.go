@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+var (
+	ErrOCSPMissingIssuer    = errors.New("the certificate file must contain the issuer certificate to request the OCSP staple")
+	ErrOCSPMissingResponder = errors.New("the certificate has no OCSP responder")
+	ErrOCSPExpiredResponse  = errors.New("the OCSP response is past its next update")
+)
+
+const (
+	// ocspRetryInterval is the time to wait before retrying a failed OCSP staple refresh.
+	ocspRetryInterval = 1 * time.Minute
+
+	// ocspDefaultRefreshInterval is the time between the refreshes of the OCSP staple
+	// when the responder does not tell when the next update is available.
+	ocspDefaultRefreshInterval = 1 * time.Hour
+
+	// ocspMaxResponseSize is the maximum size in bytes of a response of the OCSP responder.
+	ocspMaxResponseSize = 1 << 20
+)
+
+// OCSPStapler keeps an OCSP staple of a certificate, refreshed from its OCSP responder,
+// and attaches it to the TLS handshakes. The staple is renewed when the certificate
+// is reloaded. It is safe for concurrent use.
+type OCSPStapler struct {
+	certs  *CertificateReloader
+	client *http.Client
+	renew  chan struct{}
+
+	mu         sync.RWMutex
+	staple     []byte
+	stapleLeaf []byte
+	nextUpdate time.Time
+}
+
+// NewOCSPStapler creates a new OCSPStapler for the certificates of the reloader.
+// The certificate file must contain the issuer certificate after the leaf certificate.
+func NewOCSPStapler(certs *CertificateReloader) (*OCSPStapler, error) {
+	cert := certs.Certificate()
+
+	if _, err := ocspIssuer(cert); err != nil {
+		return nil, err
+	}
+
+	if len(cert.Leaf.OCSPServer) == 0 {
+		return nil, ErrOCSPMissingResponder
+	}
+
+	return &OCSPStapler{
+		certs:  certs,
+		client: &http.Client{Timeout: 10 * time.Second},
+		renew:  make(chan struct{}, 1),
+	}, nil
+}
+
+// ocspIssuer returns the issuer certificate, which follows the leaf certificate.
+func ocspIssuer(cert *tls.Certificate) (*x509.Certificate, error) {
+	if len(cert.Certificate) < 2 {
+		return nil, ErrOCSPMissingIssuer
+	}
+
+	return x509.ParseCertificate(cert.Certificate[1])
+}
+
+// Refresh requests a new OCSP staple of the current certificate to its responder.
+func (s *OCSPStapler) Refresh(ctx context.Context) error {
+	cert := s.certs.Certificate()
+
+	issuer, err := ocspIssuer(cert)
+	if err != nil {
+		return err
+	}
+
+	if len(cert.Leaf.OCSPServer) == 0 {
+		return ErrOCSPMissingResponder
+	}
+
+	req, err := ocsp.CreateRequest(cert.Leaf, issuer, nil)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, cert.Leaf.OCSPServer[0], bytes.NewReader(req))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/ocsp-request")
+	httpReq.Header.Set("Accept", "application/ocsp-response")
+
+	httpResp, err := s.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OCSP responder returned status code %d", httpResp.StatusCode)
+	}
+
+	staple, err := io.ReadAll(io.LimitReader(httpResp.Body, ocspMaxResponseSize))
+	if err != nil {
+		return err
+	}
+
+	resp, err := ocsp.ParseResponseForCert(staple, cert.Leaf, issuer)
+	if err != nil {
+		return err
+	}
+
+	if s.expired(resp.NextUpdate) {
+		return ErrOCSPExpiredResponse
+	}
+
+	if resp.Status != ocsp.Good {
+		slog.Warn("ocsp staple refreshed with a not good status", "status", resp.Status)
+	}
+
+	s.mu.Lock()
+	s.staple = staple
+	s.stapleLeaf = cert.Certificate[0]
+	s.nextUpdate = resp.NextUpdate
+	s.mu.Unlock()
+
+	slog.Debug("ocsp staple refreshed", "next_update", resp.NextUpdate)
+
+	return nil
+}
+
+// Run refreshes the OCSP staple halfway to its next update, and when the certificate
+// is reloaded, until the context is done.
+// The failed refreshes are retried every minute, keeping the previous staple meanwhile
+// until its next update passes.
+func (s *OCSPStapler) Run(ctx context.Context) {
+	for {
+		wait := ocspRetryInterval
+		if err := s.Refresh(ctx); err != nil {
+			slog.Error("ocsp staple refresh error", "error", err)
+			s.dropExpired()
+		} else {
+			wait = s.refreshInterval()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.renew:
+		case <-time.After(wait):
+		}
+	}
+}
+
+// expired returns true when the next update of a staple has passed.
+// A staple without next update never expires.
+func (s *OCSPStapler) expired(nextUpdate time.Time) bool {
+	return !nextUpdate.IsZero() && !time.Now().Before(nextUpdate)
+}
+
+// dropExpired drops the staple once its next update has passed,
+// as the clients reject the expired staples.
+func (s *OCSPStapler) dropExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.staple == nil || !s.expired(s.nextUpdate) {
+		return
+	}
+
+	slog.Warn("ocsp staple expired, serving the certificate without staple", "next_update", s.nextUpdate)
+
+	s.staple = nil
+	s.stapleLeaf = nil
+	s.nextUpdate = time.Time{}
+}
+
+// refreshInterval returns the time to wait before the next refresh.
+func (s *OCSPStapler) refreshInterval() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.nextUpdate.IsZero() {
+		return ocspDefaultRefreshInterval
+	}
+
+	return max(time.Until(s.nextUpdate)/2, ocspRetryInterval)
+}
+
+// GetCertificate returns the current certificate with its OCSP staple,
+// to be used as the GetCertificate function of a [tls.Config].
+// A reloaded certificate is served without staple until its staple is refreshed,
+// and so is the certificate whose staple is past its next update.
+func (s *OCSPStapler) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := *s.certs.Certificate()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case bytes.Equal(s.stapleLeaf, cert.Certificate[0]):
+		if !s.expired(s.nextUpdate) {
+			cert.OCSPStaple = s.staple
+		}
+	case s.stapleLeaf != nil:
+		// the staple is of the previous certificate
+		select {
+		case s.renew <- struct{}{}:
+		default:
+		}
+	}
+
+	return &cert, nil
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// testOCSPResponder counts the requests of the mock OCSP responder and makes it fail.
+type testOCSPResponder struct {
+	requests atomic.Int32
+	fail     atomic.Bool
+}
+
+// newTestOCSPStapler returns a stapler of a certificate issued by a test CA,
+// whose mock responder answers staples valid for the given duration.
+func newTestOCSPStapler(t *testing.T, validity time.Duration) (*OCSPStapler, *x509.Certificate, *testOCSPResponder) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate CA key: %v", err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("could not create CA certificate: %v", err)
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatalf("could not parse CA certificate: %v", err)
+	}
+
+	// the mock responder answers good for any certificate issued by the CA,
+	// or fails while told so
+	responder := &testOCSPResponder{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		responder.requests.Add(1)
+
+		if responder.fail.Load() {
+			http.Error(w, "responder unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		resp, err := ocsp.CreateResponse(caCert, caCert, ocsp.Response{
+			Status:       ocsp.Good,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(validity),
+		}, caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("could not generate key: %v", err)
+	}
+
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		OCSPServer:   []string{server.URL},
+	}, caCert, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("could not create certificate: %v", err)
+	}
+
+	leafKeyDER, err := x509.MarshalECPrivateKey(leafKey)
+	if err != nil {
+		t.Fatalf("could not marshal key: %v", err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	certPEM := append(
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})...,
+	)
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatalf("could not write certificate: %v", err)
+	}
+
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: leafKeyDER}), 0o600); err != nil {
+		t.Fatalf("could not write key: %v", err)
+	}
+
+	certs, err := NewCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("could not load the certificate: %v", err)
+	}
+
+	stapler, err := NewOCSPStapler(certs)
+	if err != nil {
+		t.Fatalf("could not create stapler: %v", err)
+	}
+
+	return stapler, caCert, responder
+}
+
+func TestOCSPStapler_Handshake(t *testing.T) {
+	stapler, caCert, responder := newTestOCSPStapler(t, time.Hour)
+
+	if err := stapler.Refresh(context.Background()); err != nil {
+		t.Fatalf("could not refresh the staple: %v", err)
+	}
+
+	if got := responder.requests.Load(); got != 1 {
+		t.Fatalf("expected 1 request to the responder, got %d", got)
+	}
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		GetCertificate: stapler.GetCertificate,
+	})
+	if err != nil {
+		t.Fatalf("could not listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{
+		RootCAs:    roots,
+		ServerName: "localhost",
+	})
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	defer conn.Close()
+
+	staple := conn.ConnectionState().OCSPResponse
+	if len(staple) == 0 {
+		t.Fatal("expected an OCSP staple in the handshake")
+	}
+
+	resp, err := ocsp.ParseResponseForCert(staple, conn.ConnectionState().PeerCertificates[0], caCert)
+	if err != nil {
+		t.Fatalf("could not parse the staple: %v", err)
+	}
+
+	if resp.Status != ocsp.Good {
+		t.Errorf("expected status good, got %d", resp.Status)
+	}
+}
+
+func TestOCSPStapler_ExpiredStaple(t *testing.T) {
+	// the OCSP times are in seconds
+	stapler, _, responder := newTestOCSPStapler(t, 2*time.Second)
+
+	if err := stapler.Refresh(context.Background()); err != nil {
+		t.Fatalf("could not refresh the staple: %v", err)
+	}
+
+	cert, err := stapler.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("could not get the certificate: %v", err)
+	}
+
+	if len(cert.OCSPStaple) == 0 {
+		t.Fatal("expected an OCSP staple before its next update")
+	}
+
+	responder.fail.Store(true)
+
+	stapler.mu.RLock()
+	nextUpdate := stapler.nextUpdate
+	stapler.mu.RUnlock()
+
+	time.Sleep(time.Until(nextUpdate) + 50*time.Millisecond)
+
+	cert, err = stapler.GetCertificate(nil)
+	if err != nil {
+		t.Fatalf("could not get the certificate: %v", err)
+	}
+
+	if len(cert.OCSPStaple) != 0 {
+		t.Error("expected no OCSP staple after its next update")
+	}
+
+	// the failed refresh of the run drops the expired staple
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		stapler.Run(ctx)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stapler.mu.RLock()
+		dropped := stapler.staple == nil && stapler.stapleLeaf == nil
+		stapler.mu.RUnlock()
+
+		if dropped {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("expected the expired staple to be dropped")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	if got := responder.requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests to the responder, got %d", got)
+	}
+}
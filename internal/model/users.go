@@ -0,0 +1,28 @@
+package model
+
+import "strings"
+
+// Length constraints for the user fields.
+// These are shared by the handler, service and repository layers
+// so every entry point validates the same boundaries.
+const (
+	UserFirstNameMinLength = 2
+	UserFirstNameMaxLength = 25
+	UserLastNameMinLength  = 2
+	UserLastNameMaxLength  = 25
+	UserEmailMinLength     = 6
+	UserEmailMaxLength     = 50
+	UserPasswordMinLength  = 6
+
+	// UserPasswordMaxLength is bounded by bcrypt, which rejects passwords longer than 72 bytes.
+	UserPasswordMaxLength = 72
+
+	// UserBulkDeleteMaxItems is the maximum number of users deleted in a single bulk delete.
+	UserBulkDeleteMaxItems = 100
+)
+
+// NormalizeEmail returns the email without the surrounding spaces and in lower case,
+// the form the emails are stored and looked up in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,76 @@
+package o11y
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Lifecycle logs the phases of a stage of the service, like the startup or
+// the shutdown, with their durations.
+type Lifecycle struct {
+	stage string
+	start time.Time
+
+	mu    sync.Mutex
+	phase string
+}
+
+// NewLifecycle creates a new Lifecycle for the stage, starting now.
+func NewLifecycle(stage string) *Lifecycle {
+	return &Lifecycle{
+		stage: stage,
+		start: time.Now(),
+	}
+}
+
+// Phase starts the phase name and returns the function to call when it ends,
+// logging its duration.
+func (ref *Lifecycle) Phase(name string) func() {
+	start := time.Now()
+
+	ref.mu.Lock()
+	ref.phase = name
+	ref.mu.Unlock()
+
+	return func() {
+		slog.Info(ref.stage+" phase completed", "phase", name, "duration", time.Since(start))
+	}
+}
+
+// Done logs the duration of the whole stage, like "ready in 120ms".
+func (ref *Lifecycle) Done(message string) {
+	duration := time.Since(ref.start)
+	slog.Info(fmt.Sprintf("%s in %dms", message, duration.Milliseconds()), "stage", ref.stage, "duration", duration)
+}
+
+// Deadline returns a context canceled when the stage runs longer than the timeout,
+// and calls onExpired, after logging the running phase, if it isn't canceled before.
+// onExpired is meant to exit, so the phases that don't honor the context can't hang the stage.
+// A timeout less than or equal to zero disables the deadline.
+func (ref *Lifecycle) Deadline(ctx context.Context, timeout time.Duration, onExpired func()) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+
+	go func() {
+		<-ctx.Done()
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return
+		}
+
+		ref.mu.Lock()
+		phase := ref.phase
+		ref.mu.Unlock()
+
+		slog.Error(ref.stage+" timed out", "phase", phase, "timeout", timeout)
+		onExpired()
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,131 @@
+package o11y
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLifecycle(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	startup := NewLifecycle("startup")
+	for _, phase := range []string{"config load", "database connect", "database migrations", "http listener"} {
+		done := startup.Phase(phase)
+		done()
+	}
+	startup.Done("ready")
+
+	type record struct {
+		Msg      string `json:"msg"`
+		Phase    string `json:"phase"`
+		Stage    string `json:"stage"`
+		Duration *int64 `json:"duration"`
+	}
+
+	var records []record
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r record
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("could not decode log line %q: %v", line, err)
+		}
+		records = append(records, r)
+	}
+
+	want := []record{
+		{Msg: "startup phase completed", Phase: "config load"},
+		{Msg: "startup phase completed", Phase: "database connect"},
+		{Msg: "startup phase completed", Phase: "database migrations"},
+		{Msg: "startup phase completed", Phase: "http listener"},
+		{Msg: "ready in ", Stage: "startup"},
+	}
+
+	if len(records) != len(want) {
+		t.Fatalf("expected %d log lines, got %d: %s", len(want), len(records), buf.String())
+	}
+
+	for i, w := range want {
+		got := records[i]
+		if !strings.HasPrefix(got.Msg, w.Msg) || got.Phase != w.Phase || got.Stage != w.Stage {
+			t.Errorf("line %d: expected %q phase %q stage %q, got %q phase %q stage %q", i, w.Msg, w.Phase, w.Stage, got.Msg, got.Phase, got.Stage)
+		}
+
+		if got.Duration == nil {
+			t.Errorf("line %d: expected a duration", i)
+		}
+	}
+
+	if !strings.HasSuffix(records[len(records)-1].Msg, "ms") {
+		t.Errorf("expected the ready line to end with ms, got %q", records[len(records)-1].Msg)
+	}
+}
+
+func TestLifecycle_Deadline(t *testing.T) {
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(io.Discard, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	// the store blocks on ping without honoring the context
+	unblock := make(chan struct{})
+	defer close(unblock)
+	ping := func(context.Context) error {
+		<-unblock
+		return nil
+	}
+
+	t.Run("aborts a stuck startup after the deadline", func(t *testing.T) {
+		startup := NewLifecycle("startup")
+		expired := make(chan struct{})
+
+		start := time.Now()
+		ctx, cancel := startup.Deadline(context.Background(), 50*time.Millisecond, func() {
+			close(expired)
+		})
+		defer cancel()
+
+		done := startup.Phase("database connect")
+		go func() {
+			ping(ctx)
+			done()
+		}()
+
+		select {
+		case <-expired:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the startup to abort after the deadline")
+		}
+
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("expected the startup to abort after 50ms, aborted after %s", elapsed)
+		}
+
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			t.Errorf("expected the context to exceed its deadline, got %v", ctx.Err())
+		}
+	})
+
+	t.Run("completed startup is not aborted", func(t *testing.T) {
+		startup := NewLifecycle("startup")
+		expired := make(chan struct{})
+
+		_, cancel := startup.Deadline(context.Background(), 50*time.Millisecond, func() {
+			close(expired)
+		})
+		cancel()
+
+		select {
+		case <-expired:
+			t.Fatal("expected the completed startup not to abort")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
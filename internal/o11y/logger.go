@@ -0,0 +1,51 @@
+package o11y
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewLogger creates a new logger attaching the service name and the environment
+// to every log line, as the service and env attributes, so the lines of the
+// different services can be told apart once aggregated.
+// An empty environment is left out.
+// The lines logged with the context of a span have its trace_id and span_id.
+func NewLogger(handler slog.Handler, serviceName, environment string) *slog.Logger {
+	logger := slog.New(&traceHandler{Handler: handler}).With("service", serviceName)
+
+	if environment != "" {
+		logger = logger.With("env", environment)
+	}
+
+	return logger
+}
+
+// traceHandler adds the trace and span IDs of the span of the context to the log lines,
+// so the logs can be correlated with the traces. Without span they are left out.
+type traceHandler struct {
+	slog.Handler
+}
+
+// Handle adds the trace and span IDs to the record.
+func (h *traceHandler) Handle(ctx context.Context, record slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+// WithAttrs returns a traceHandler whose handler has the attributes.
+func (h *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &traceHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a traceHandler whose handler has the group.
+func (h *traceHandler) WithGroup(name string) slog.Handler {
+	return &traceHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,116 @@
+package o11y
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		name        string
+		serviceName string
+		environment string
+		want        map[string]string
+	}{
+		{
+			name:        "service and environment",
+			serviceName: "users",
+			environment: "production",
+			want:        map[string]string{"service": "users", "env": "production"},
+		},
+		{
+			name:        "empty environment is left out",
+			serviceName: "users",
+			want:        map[string]string{"service": "users"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			var buf bytes.Buffer
+			logger := NewLogger(slog.NewJSONHandler(&buf, nil), tc.serviceName, tc.environment)
+
+			// When
+			logger.Info("user created", "id", "1")
+
+			// Then
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("could not decode log line %q: %v", buf.String(), err)
+			}
+
+			for key, value := range tc.want {
+				if line[key] != value {
+					t.Errorf("expected %s %q, got %v", key, value, line[key])
+				}
+			}
+
+			if _, ok := line["env"]; ok && tc.environment == "" {
+				t.Errorf("expected no env attribute, got %v", line["env"])
+			}
+		})
+	}
+}
+
+func TestNewLogger_TraceIDs(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+
+	spanCtx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		traceID string
+		spanID  string
+	}{
+		{
+			name:    "within a span",
+			ctx:     spanCtx,
+			traceID: traceID.String(),
+			spanID:  spanID.String(),
+		},
+		{
+			name: "without span they are left out",
+			ctx:  context.Background(),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			var buf bytes.Buffer
+			logger := NewLogger(slog.NewJSONHandler(&buf, nil), "users", "")
+
+			// When
+			logger.InfoContext(tc.ctx, "request")
+
+			// Then
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("could not decode log line %q: %v", buf.String(), err)
+			}
+
+			for key, want := range map[string]string{"trace_id": tc.traceID, "span_id": tc.spanID} {
+				got, ok := line[key]
+				if want == "" && ok {
+					t.Errorf("expected no %s, got %v", key, got)
+				}
+
+				if want != "" && got != want {
+					t.Errorf("expected %s %q, got %v", key, want, got)
+				}
+			}
+		})
+	}
+}
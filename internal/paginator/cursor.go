@@ -14,6 +14,10 @@ const (
 	DefaultLimit int = 10
 	MinLimit     int = 2
 	MaxLimit     int = 100
+
+	// DefaultMaxTokenLength is the default maximum length of a cursor token.
+	// The tokens encode an UUID and a serial, which take less than 80 characters.
+	DefaultMaxTokenLength int = 128
 )
 
 // DataSeparator is the separator used to separate the data in the cursor token.
@@ -21,6 +25,7 @@ var DataSeparator string = ";"
 
 var (
 	ErrInvalidCursor      = errors.New("invalid cursor token")
+	ErrCursorTooLong      = errors.New("cursor token too long")
 	ErrMustBeOneOrGreater = errors.New("limit must be one or greater")
 )
 
@@ -100,7 +105,18 @@ func EncodeToken(id uuid.UUID, serial int64) string {
 }
 
 // DecodeToken decodes the string into a date and id.
+// Tokens longer than DefaultMaxTokenLength are rejected before decoding them.
 func DecodeToken(s string) (id uuid.UUID, serial int64, err error) {
+	return DecodeTokenMaxLength(s, DefaultMaxTokenLength)
+}
+
+// DecodeTokenMaxLength decodes the string into a date and id.
+// Tokens longer than maxLength are rejected with ErrCursorTooLong before decoding them.
+func DecodeTokenMaxLength(s string, maxLength int) (id uuid.UUID, serial int64, err error) {
+	if len(s) > maxLength {
+		return uuid.Nil, 0, ErrCursorTooLong
+	}
+
 	data, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
 		return uuid.Nil, 0, err
@@ -2,7 +2,10 @@ package paginator
 
 import (
 	"encoding/base64"
+	"errors"
+	"math"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/google/uuid"
@@ -71,6 +74,15 @@ func TestDecodeToken(t *testing.T) {
 			wantId:     uuid.Max,
 			wantErr:    false,
 		},
+		{
+			name: "success with the longest serial",
+			args: args{
+				s: base64.StdEncoding.EncodeToString([]byte("ffffffff-ffff-ffff-ffff-ffffffffffff;-9223372036854775808")),
+			},
+			wantSerial: math.MinInt64,
+			wantId:     uuid.Max,
+			wantErr:    false,
+		},
 		{
 			name: "invalid token",
 			args: args{
@@ -80,6 +92,15 @@ func TestDecodeToken(t *testing.T) {
 			wantId:     uuid.Nil,
 			wantErr:    true,
 		},
+		{
+			name: "oversized token",
+			args: args{
+				s: strings.Repeat("A", DefaultMaxTokenLength+4),
+			},
+			wantSerial: 0,
+			wantId:     uuid.Nil,
+			wantErr:    true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -105,3 +126,20 @@ func TestDecodeToken(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeToken_TooLong(t *testing.T) {
+	token := strings.Repeat("A", 1<<20)
+
+	_, _, err := DecodeToken(token)
+	if !errors.Is(err, ErrCursorTooLong) {
+		t.Fatalf("expected error %v, got %v", ErrCursorTooLong, err)
+	}
+
+	// the token is rejected without decoding it
+	allocs := testing.AllocsPerRun(10, func() {
+		_, _, _ = DecodeToken(token)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
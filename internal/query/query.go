@@ -9,6 +9,14 @@ import (
 
 var sortOperators = []string{"ASC", "DESC"}
 
+// sortNulls are the valid placements of the null values in a sort.
+var sortNulls = []string{"FIRST", "LAST"}
+
+// likeComparator matches the LIKE comparator, in any case, surrounded by spaces.
+const likeComparator = `\s+(?i:LIKE)\s+`
+
+var likeComparatorRegexp = regexp.MustCompile(likeComparator)
+
 // GetFields returns a list of fields for partial response after trimming spaces
 // and making these unique.
 func GetFields(fields string) []string {
@@ -70,14 +78,15 @@ func tokenizeFields(fields string) []string {
 
 // IsValidSort checks if a sort string is valid SQL syntax.
 // The columns parameter is a list of valid column names.
-// The sort parameter is a string with the sort to validate.
+// The sort parameter is a string with the sort to validate, where each column
+// has a direction and optionally the placement of its null values.
 // The function returns true if the sort is valid, false otherwise.
 //
 // Example:
 // IsValidSort(
 //
 //	[]string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
-//	"id ASC, first_name DESC"
+//	"id ASC, updated_at DESC NULLS LAST"
 //
 // )
 func IsValidSort(columns []string, sort string) bool {
@@ -124,9 +133,105 @@ func IsValidSort(columns []string, sort string) bool {
 		}
 	}
 
+	// check if the null placements are valid
+	for _, token := range tokens {
+		column := strings.Fields(token)
+		if len(column) == 4 && !isValidNullsSort(column[2], column[3]) {
+			return false
+		}
+	}
+
 	return true
 }
 
+// NormalizeSort returns the sort string with the extra spaces removed
+// and the direction and nulls keywords in upper case.
+// The sort parameter must be validated with IsValidSort first.
+//
+// Example:
+// NormalizeSort("id  asc,first_name desc nulls last") returns "id ASC, first_name DESC NULLS LAST"
+func NormalizeSort(sort string) string {
+	if sort == "" {
+		return ""
+	}
+
+	tokens := tokenizeSort(sort)
+	normalized := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		column := strings.Fields(token)
+		for i := 1; i < len(column); i++ {
+			column[i] = strings.ToUpper(column[i])
+		}
+
+		normalized = append(normalized, strings.Join(column, " "))
+	}
+
+	return strings.Join(normalized, ", ")
+}
+
+// NormalizeFilter returns the filter with a single space around the comparators
+// and the operators, and the operators and the LIKE comparator in upper case.
+// The filter parameter must be validated with IsValidFilter first.
+//
+// Example:
+// NormalizeFilter("first_name='Alice'  and id>=1") returns "first_name = 'Alice' AND id >= 1"
+func NormalizeFilter(filter string) string {
+	if filter == "" {
+		return ""
+	}
+
+	operators := getOperatorsFilter(filter)
+	pairs := getPairsFilter(filter)
+	columns := getColumnsFilter(pairs)
+	comparators := getComparatorsFilter(pairs)
+	values := getValuesFilter(pairs)
+
+	if len(columns) != len(pairs) || len(comparators) != len(pairs) || len(values) != len(pairs) {
+		return filter
+	}
+
+	var sb strings.Builder
+	for i := range pairs {
+		if i > 0 && i-1 < len(operators) {
+			fmt.Fprintf(&sb, " %s ", strings.ToUpper(operators[i-1]))
+		}
+
+		fmt.Fprintf(&sb, "%s %s %s", columns[i], strings.ToUpper(comparators[i]), values[i])
+	}
+
+	return sb.String()
+}
+
+// ApplySortDefaults completes the columns of the sort string without a direction
+// with the given direction, and the columns without a placement of the null values
+// with the given nulls, FIRST or LAST. Empty defaults leave the columns untouched.
+// The result must be validated with IsValidSort.
+//
+// Example:
+// ApplySortDefaults("id, first_name DESC", "ASC", "LAST") returns "id ASC NULLS LAST, first_name DESC NULLS LAST"
+func ApplySortDefaults(sort string, direction string, nulls string) string {
+	if sort == "" || (direction == "" && nulls == "") {
+		return sort
+	}
+
+	tokens := tokenizeSort(sort)
+	completed := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		column := strings.Fields(token)
+		if len(column) == 1 && direction != "" {
+			column = append(column, direction)
+		}
+
+		if len(column) == 2 && nulls != "" {
+			column = append(column, "NULLS", nulls)
+		}
+
+		completed = append(completed, strings.Join(column, " "))
+	}
+
+	return strings.Join(completed, ", ")
+}
+
 // IsValidFilter checks if a filter string is valid SQL syntax.
 // The columns parameter is a list of valid column names.
 // The filter parameter is a string with the filter to validate.
@@ -244,6 +349,22 @@ func isValidOperatorSort(value string) bool {
 	return false
 }
 
+// isValidNullsSort checks if the keywords are a valid placement of the null values,
+// like NULLS FIRST or NULLS LAST.
+func isValidNullsSort(keyword string, value string) bool {
+	if strings.ToUpper(keyword) != "NULLS" {
+		return false
+	}
+
+	for _, nulls := range sortNulls {
+		if strings.ToUpper(value) == nulls {
+			return true
+		}
+	}
+
+	return false
+}
+
 // isValue checks if a token is a valid value.
 // Valid values can be single-quoted strings and numbers.
 func isValue(value any) bool {
@@ -283,8 +404,9 @@ func getOperatorsSort(tokens []string) []string {
 	for _, token := range tokens {
 		t := strings.TrimSpace(token)
 
-		column := strings.Split(t, " ")
-		if len(column) == 2 {
+		// the direction may be followed by NULLS FIRST or NULLS LAST
+		column := strings.Fields(t)
+		if len(column) == 2 || len(column) == 4 {
 			operators = append(operators, column[1])
 		}
 	}
@@ -292,6 +414,42 @@ func getOperatorsSort(tokens []string) []string {
 	return operators
 }
 
+// HasLeadingWildcard checks if any LIKE value in the filter starts with a wildcard,
+// % or _, which prevents the database from using an index on the column.
+// Wildcards escaped with a backslash, like '\%', match the literal character
+// and are not considered wildcards.
+//
+// Example:
+// HasLeadingWildcard("first_name LIKE '%a%'") returns true
+// HasLeadingWildcard("first_name LIKE 'a%'") returns false
+func HasLeadingWildcard(filter string) bool {
+	for _, pair := range getPairsFilter(filter) {
+		matches := likeComparatorRegexp.Split(pair, 2)
+		if len(matches) != 2 {
+			continue
+		}
+
+		value := strings.Trim(strings.TrimSpace(matches[1]), "'")
+		if strings.HasPrefix(value, "%") || strings.HasPrefix(value, "_") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CountFilterPredicates returns the number of column-value predicates in the filter.
+//
+// Example:
+// CountFilterPredicates("id=1 AND first_name='Alice'") returns 2
+func CountFilterPredicates(filter string) int {
+	if filter == "" {
+		return 0
+	}
+
+	return len(getPairsFilter(filter))
+}
+
 // getOperatorsFilter returns the list of valid operators in the tokenized filter.
 func getOperatorsFilter(filter string) []string {
 	// https://regex101.com/r/6HPVL2/1
@@ -310,7 +468,7 @@ func getOperatorsFilter(filter string) []string {
 // getPairs returns the list of column-value pairs in the tokenized filter.
 func getPairsFilter(filter string) []string {
 	// https://regex101.com/r/3aqJcV/4
-	re := regexp.MustCompile(`(\w+\s*(=|!=)\s*('.*?'|".*?"))|(\w+\s{0,}(>=|<=|<|>|=)\s{0,}(\d{1,15}(\.\d{1,15}){0,1})\s{0,}?)`)
+	re := regexp.MustCompile(`(\w+` + likeComparator + `'.*?')|(\w+\s*(=|!=)\s*('.*?'|".*?"))|(\w+\s{0,}(>=|<=|<|>|=)\s{0,}(\d{1,15}(\.\d{1,15}){0,1})\s{0,}?)`)
 
 	matches := re.FindAllString(filter, -1)
 	tokens := make([]string, 0, len(matches))
@@ -324,14 +482,14 @@ func getPairsFilter(filter string) []string {
 
 // getComparatorsFilter returns the list of valid comparators in the pairs values.
 func getComparatorsFilter(pairs []string) []string {
-	re := regexp.MustCompile(`(\s*(=|!=)\s*)|(\s{0,}(>=|<=|<|>|=)\s{0,})`)
+	re := regexp.MustCompile(`(` + likeComparator + `)|(\s*(=|!=)\s*)|(\s{0,}(>=|<=|<|>|=)\s{0,})`)
 
 	comparators := make([]string, 0)
 	for _, pair := range pairs {
 		matches := re.FindAllString(pair, -1)
 
 		for _, match := range matches {
-			if match != "" && len(match) <= 2 {
+			if match != "" && (len(match) <= 2 || likeComparatorRegexp.MatchString(match)) {
 				comparators = append(comparators, strings.TrimSpace(match))
 			}
 		}
@@ -344,8 +502,13 @@ func getComparatorsFilter(pairs []string) []string {
 func getColumnsSort(pairs []string) []string {
 	columns := make([]string, 0)
 	for _, pair := range pairs {
-		p := strings.TrimSpace(pair)
-		column := strings.Split(p, " ")
+		column := strings.Fields(pair)
+		if len(column) == 0 {
+			// keep the empty column so the sort is rejected
+			columns = append(columns, "")
+			continue
+		}
+
 		columns = append(columns, column[0])
 	}
 
@@ -354,7 +517,7 @@ func getColumnsSort(pairs []string) []string {
 
 // getColumnsFilter returns the list of columns in the pairs values.
 func getColumnsFilter(pairs []string) []string {
-	re := regexp.MustCompile(`(\s*(=|!=)\s*)|(\s{0,}(>=|<=|<|>|=)\s{0,})`)
+	re := regexp.MustCompile(`(` + likeComparator + `)|(\s*(=|!=)\s*)|(\s{0,}(>=|<=|<|>|=)\s{0,})`)
 
 	columns := make([]string, 0)
 	for _, pair := range pairs {
@@ -377,7 +540,7 @@ func getColumnsFilter(pairs []string) []string {
 
 // getValuesFilter returns the list of values in the pairs values.
 func getValuesFilter(pairs []string) []string {
-	re := regexp.MustCompile(`(\s*(=|!=)\s*)|(\s{0,}(>=|<=|<|>|=)\s{0,})`)
+	re := regexp.MustCompile(`(` + likeComparator + `)|(\s*(=|!=)\s*)|(\s{0,}(>=|<=|<|>|=)\s{0,})`)
 
 	values := make([]string, 0)
 	for _, pair := range pairs {
@@ -446,3 +609,73 @@ func PrefixFilterFields(filter string, prefix string) (string, error) {
 
 	return sb.String(), nil
 }
+
+// ParameterizeFilter returns the filter with the fields prefixed with a given prefix
+// and each value replaced by a bind parameter, numbered from first on,
+// together with the values of the parameters, in the same order.
+// The quotes of the string values are removed and the numbers are converted
+// to int64 or float64, so no value is written into the SQL.
+// The filter parameter must be validated with IsValidFilter first.
+//
+// Example:
+// ParameterizeFilter("first_name='Alice' AND id>=1", "usrs.", 1) returns
+// "usrs.first_name = $1 AND usrs.id >= $2" and []any{"Alice", int64(1)}
+func ParameterizeFilter(filter string, prefix string, first int) (string, []any, error) {
+	if filter == "" {
+		return "", nil, nil
+	}
+
+	prefix = strings.TrimSpace(prefix)
+
+	operators := getOperatorsFilter(filter)
+	pairs := getPairsFilter(filter)
+
+	// pairs cannot be zero
+	if len(pairs) == 0 {
+		return "", nil, fmt.Errorf("filter is invalid")
+	}
+
+	// if pairs are greater than 1, then operators should be equal to pairs - 1
+	if len(pairs) > 1 && len(operators) != len(pairs)-1 {
+		return "", nil, fmt.Errorf("invalid number of operators in filter")
+	}
+
+	columns := getColumnsFilter(pairs)
+	comparators := getComparatorsFilter(pairs)
+	values := getValuesFilter(pairs)
+
+	if len(columns) != len(pairs) || len(comparators) != len(pairs) || len(values) != len(pairs) {
+		return "", nil, fmt.Errorf("filter is invalid")
+	}
+
+	var sb strings.Builder
+	args := make([]any, 0, len(pairs))
+	for i := range pairs {
+		if i > 0 {
+			fmt.Fprintf(&sb, " %s ", strings.ToUpper(operators[i-1]))
+		}
+
+		args = append(args, parameterValue(values[i]))
+		fmt.Fprintf(&sb, "%s%s %s $%d", prefix, columns[i], strings.ToUpper(comparators[i]), first+len(args)-1)
+	}
+
+	return sb.String(), args, nil
+}
+
+// parameterValue returns the value of a filter as a bind parameter,
+// the content of a quoted string or the number.
+func parameterValue(value string) any {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f
+	}
+
+	return value
+}
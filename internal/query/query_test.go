@@ -1,6 +1,9 @@
 package query
 
 import (
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 )
 
@@ -142,6 +145,38 @@ func TestIsValidFilter(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "valid filter with like",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				filter:  "first_name LIKE 'Ali%' AND last_name like 'Sm_th'",
+			},
+			want: true,
+		},
+		{
+			name: "valid filter with like and escaped literal percent",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				filter:  "first_name LIKE '100\\%' AND id=1",
+			},
+			want: true,
+		},
+		{
+			name: "invalid filter with like and unquoted value",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				filter:  "first_name LIKE Alice",
+			},
+			want: false,
+		},
+		{
+			name: "invalid filter with like on invalid column",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				filter:  "password LIKE 'a%'",
+			},
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -153,6 +188,53 @@ func TestIsValidFilter(t *testing.T) {
 	}
 }
 
+func TestHasLeadingWildcard(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{
+			name:   "empty filter",
+			filter: "",
+			want:   false,
+		},
+		{
+			name:   "filter without like",
+			filter: "first_name='%Alice'",
+			want:   false,
+		},
+		{
+			name:   "like with trailing wildcard",
+			filter: "first_name LIKE 'Ali%'",
+			want:   false,
+		},
+		{
+			name:   "like with leading percent",
+			filter: "id=1 AND first_name LIKE '%a%'",
+			want:   true,
+		},
+		{
+			name:   "like with leading underscore",
+			filter: "first_name like '_lice'",
+			want:   true,
+		},
+		{
+			name:   "like with escaped literal percent",
+			filter: "first_name LIKE '\\%off%'",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasLeadingWildcard(tt.filter); got != tt.want {
+				t.Errorf("HasLeadingWildcard() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestIsValidSort(t *testing.T) {
 	type args struct {
 		columns []string
@@ -227,6 +309,70 @@ func TestIsValidSort(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "valid sort with lower case operator",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				sort:    "first_name desc",
+			},
+			want: true,
+		},
+		{
+			name: "valid sort with extra spaces",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				sort:    "  first_name  DESC ,id\tAsc ",
+			},
+			want: true,
+		},
+		{
+			name: "invalid sort with long operator name",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				sort:    "first_name DESCENDING",
+			},
+			want: false,
+		},
+		{
+			name: "valid sort with nulls placement",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "updated_at"},
+				sort:    "updated_at DESC NULLS LAST, id asc nulls first",
+			},
+			want: true,
+		},
+		{
+			name: "invalid sort with unknown nulls placement",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "updated_at"},
+				sort:    "updated_at DESC NULLS MIDDLE",
+			},
+			want: false,
+		},
+		{
+			name: "invalid sort with nulls placement missing",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "updated_at"},
+				sort:    "updated_at DESC NULLS",
+			},
+			want: false,
+		},
+		{
+			name: "invalid sort with nulls placement without direction",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "updated_at"},
+				sort:    "updated_at NULLS LAST",
+			},
+			want: false,
+		},
+		{
+			name: "invalid sort with empty column",
+			args: args{
+				columns: []string{"id", "first_name", "last_name", "email", "created_at", "updated_at"},
+				sort:    "first_name DESC, ",
+			},
+			want: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -298,6 +444,155 @@ func TestIsValidFields(t *testing.T) {
 	}
 }
 
+func TestNormalizeSort(t *testing.T) {
+	tests := []struct {
+		name string
+		sort string
+		want string
+	}{
+		{
+			name: "empty sort",
+			sort: "",
+			want: "",
+		},
+		{
+			name: "already normalized",
+			sort: "id ASC, first_name DESC",
+			want: "id ASC, first_name DESC",
+		},
+		{
+			name: "lower and mixed case operators",
+			sort: "first_name desc,id Asc",
+			want: "first_name DESC, id ASC",
+		},
+		{
+			name: "extra spaces",
+			sort: "  first_name  DESC ,  id\tASC ",
+			want: "first_name DESC, id ASC",
+		},
+		{
+			name: "nulls placement",
+			sort: "updated_at desc nulls last",
+			want: "updated_at DESC NULLS LAST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeSort(tt.sort); got != tt.want {
+				t.Errorf("NormalizeSort() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{
+			name:   "empty filter",
+			filter: "",
+			want:   "",
+		},
+		{
+			name:   "already normalized",
+			filter: "first_name = 'Alice' AND id >= 1",
+			want:   "first_name = 'Alice' AND id >= 1",
+		},
+		{
+			name:   "missing and extra spaces",
+			filter: "first_name='Alice'  and id>=1",
+			want:   "first_name = 'Alice' AND id >= 1",
+		},
+		{
+			name:   "like comparator",
+			filter: "email like '%@doe.com' or last_name!='Doe'",
+			want:   "email LIKE '%@doe.com' OR last_name != 'Doe'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeFilter(tt.filter); got != tt.want {
+				t.Errorf("NormalizeFilter() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountFilterPredicates(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   int
+	}{
+		{name: "empty filter", filter: "", want: 0},
+		{name: "single predicate", filter: "id=1", want: 1},
+		{name: "several predicates", filter: "id=1 AND first_name='Alice' OR email LIKE '%@doe.com'", want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CountFilterPredicates(tt.filter); got != tt.want {
+				t.Errorf("CountFilterPredicates() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplySortDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		sort      string
+		direction string
+		nulls     string
+		want      string
+	}{
+		{
+			name:      "empty sort",
+			sort:      "",
+			direction: "ASC",
+			nulls:     "LAST",
+			want:      "",
+		},
+		{
+			name: "no defaults",
+			sort: "id, first_name DESC",
+			want: "id, first_name DESC",
+		},
+		{
+			name:      "default direction",
+			sort:      "id, first_name DESC",
+			direction: "ASC",
+			want:      "id ASC, first_name DESC",
+		},
+		{
+			name:  "default nulls",
+			sort:  "updated_at DESC, id ASC NULLS FIRST",
+			nulls: "LAST",
+			want:  "updated_at DESC NULLS LAST, id ASC NULLS FIRST",
+		},
+		{
+			name:      "default direction and nulls",
+			sort:      "updated_at",
+			direction: "DESC",
+			nulls:     "FIRST",
+			want:      "updated_at DESC NULLS FIRST",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ApplySortDefaults(tt.sort, tt.direction, tt.nulls); got != tt.want {
+				t.Errorf("ApplySortDefaults() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestPrefixFilterFields(t *testing.T) {
 	type args struct {
 		filter string
@@ -336,6 +631,15 @@ func TestPrefixFilterFields(t *testing.T) {
 			want:    "users.id=1 AND users.first_name='Alice' or users.last_name='Smith'",
 			wantErr: false,
 		},
+		{
+			name: "prefix filter with like",
+			args: args{
+				filter: "first_name LIKE 'Ali%' AND id=1",
+				prefix: "users.",
+			},
+			want:    "users.first_name LIKE 'Ali%' AND users.id=1",
+			wantErr: false,
+		},
 		{
 			name: "prefix filter with spaces and no prefix",
 			args: args{
@@ -359,3 +663,135 @@ func TestPrefixFilterFields(t *testing.T) {
 		})
 	}
 }
+
+func TestParameterizeFilter(t *testing.T) {
+	type args struct {
+		filter string
+		prefix string
+		first  int
+	}
+	tests := []struct {
+		name     string
+		args     args
+		want     string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name: "no filter",
+			args: args{filter: "", prefix: "users.", first: 1},
+			want: "",
+		},
+		{
+			name:     "strings and numbers",
+			args:     args{filter: "id=1 AND first_name='Alice' or last_name=\"Smith\"", prefix: "users.", first: 1},
+			want:     "users.id = $1 AND users.first_name = $2 OR users.last_name = $3",
+			wantArgs: []any{int64(1), "Alice", "Smith"},
+		},
+		{
+			name:     "like and comparators numbered from first",
+			args:     args{filter: "first_name like 'Ali%' AND age>=2.5", prefix: "u.", first: 4},
+			want:     "u.first_name LIKE $4 AND u.age >= $5",
+			wantArgs: []any{"Ali%", 2.5},
+		},
+		{
+			name:    "invalid filter",
+			args:    args{filter: "not a filter", prefix: "users.", first: 1},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotArgs, err := ParameterizeFilter(tt.args.filter, tt.args.prefix, tt.args.first)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParameterizeFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParameterizeFilter() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(gotArgs, tt.wantArgs) {
+				t.Errorf("ParameterizeFilter() args = %#v, want %#v", gotArgs, tt.wantArgs)
+			}
+		})
+	}
+}
+
+// filterSQLRegexp matches the SQL allowed outside the string literals of a prefixed filter:
+// the prefixed columns, the comparators, the numbers and the logical operators.
+var filterSQLRegexp = regexp.MustCompile(`^(\s+|u\.\w+|!=|>=|<=|=|<|>|\d+(\.\d+)?|(?i:LIKE|AND|OR))*$`)
+
+// stripLiterals removes the single-quoted string literals of a SQL expression,
+// reporting false when a literal is not terminated.
+func stripLiterals(sql string) (string, bool) {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(sql, '\'')
+		if start < 0 {
+			sb.WriteString(sql)
+			return sb.String(), true
+		}
+
+		end := strings.IndexByte(sql[start+1:], '\'')
+		if end < 0 {
+			return "", false
+		}
+
+		sb.WriteString(sql[:start])
+		sb.WriteString(" ")
+		sql = sql[start+end+2:]
+	}
+}
+
+// FuzzParseFilter checks that the filter parser never panics and that an accepted filter
+// is rendered only with the allowed columns, comparators and operators around its literals,
+// so no input can inject SQL in the query. Run it with:
+//
+//	go test -run=^$ -fuzz=FuzzParseFilter ./internal/query/
+func FuzzParseFilter(f *testing.F) {
+	columns := []string{"id", "first_name", "last_name", "email", "age"}
+
+	for _, seed := range []string{
+		"",
+		"id=1",
+		"age>=20 AND age<=30.5",
+		"first_name='Alice Julie' OR last_name!='Smith'",
+		"first_name LIKE 'Ali%' and last_name like 'Sm_th'",
+		"first_name LIKE '100\\%' AND id=1",
+		"email='alice@mail.com'",
+		// adversarial
+		"id=1; DROP TABLE users",
+		"id=1 OR 1=1",
+		"first_name='a' OR 1=1 --'",
+		"first_name='a'' OR ''1''=''1'",
+		"first_name='a' UNION SELECT password FROM users",
+		"first_name='a' /* comment */ OR id=1",
+		"first_name=\"a\" OR id=1",
+		"password LIKE 'a%'",
+		"id=1 AND (SELECT 1)=1",
+		"'",
+		"id='",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, filter string) {
+		if !IsValidFilter(columns, filter) {
+			return
+		}
+
+		prefixed, err := PrefixFilterFields(filter, "u.")
+		if err != nil {
+			t.Fatalf("valid filter %q could not be prefixed: %v", filter, err)
+		}
+
+		sql, ok := stripLiterals(prefixed)
+		if !ok {
+			t.Fatalf("valid filter %q has an unterminated literal: %q", filter, prefixed)
+		}
+
+		if !filterSQLRegexp.MatchString(sql) {
+			t.Fatalf("valid filter %q renders unexpected SQL: %q", filter, prefixed)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/query"
+)
+
+// listQuery describes a paginated list over a table with id and serial_id columns,
+// shared by the repositories to build their select queries.
+// Columns, Filter and Sort must be validated against the allowed columns of the table.
+type listQuery struct {
+	// Table is the name of the table.
+	Table string
+
+	// Alias is the alias of the table used in the query.
+	Alias string
+
+	// Columns are the selected columns, all of them when empty.
+	// Only these columns are queried, so the rows are not fetched whole
+	// to return a partial response. id and serial_id are always selected
+	// because they are used for pagination.
+	Columns []string
+
+	// Filter is the filter of the rows. Its values are passed as bind parameters.
+	Filter string
+
+	// Sort is the order of the returned rows, like "first_name ASC, updated_at DESC NULLS LAST",
+	// newest first when empty.
+	Sort string
+
+	// Paginator has the limit and the cursor tokens. When both tokens
+	// are provided the next token is used.
+	Paginator paginator.Paginator
+}
+
+// build returns the query and its arguments.
+func (ref listQuery) build() (string, []any, error) {
+	prefix := ref.Alias + "."
+
+	var args []any
+	where := make([]string, 0, 2)
+	if ref.Filter != "" {
+		filter, filterArgs, err := query.ParameterizeFilter(ref.Filter, prefix, 1)
+		if err != nil {
+			return "", nil, err
+		}
+
+		where = append(where, fmt.Sprintf("(%s)", filter))
+		args = append(args, filterArgs...)
+	}
+
+	if ref.Paginator.NextToken != "" && ref.Paginator.PrevToken != "" {
+		slog.Warn("repository.listQuery.build",
+			"message",
+			"both next and prev tokens are provided, going to use next token")
+
+		ref.Paginator.PrevToken = ""
+	}
+
+	// from newest to oldest by default, and from the cursor on when a token is provided
+	internalSort := fmt.Sprintf("%sserial_id DESC, %sid DESC", prefix, prefix)
+
+	token, comparator := ref.Paginator.NextToken, "<"
+	if ref.Paginator.PrevToken != "" {
+		token, comparator = ref.Paginator.PrevToken, ">"
+		internalSort = fmt.Sprintf("%sserial_id ASC, %sid ASC", prefix, prefix)
+	}
+
+	if token != "" {
+		id, serial, err := paginator.DecodeToken(token)
+		if err != nil {
+			return "", nil, err
+		}
+
+		n := len(args)
+		where = append(where, fmt.Sprintf("(%sserial_id %s $%d) AND (%sid %s $%d OR %sserial_id %s $%d)",
+			prefix, comparator, n+1, prefix, comparator, n+2, prefix, comparator, n+3))
+		args = append(args, serial, id.String(), serial)
+	}
+
+	externalSort := ref.orderBy()
+	if externalSort == "" {
+		externalSort = fmt.Sprintf("%sserial_id DESC, %sid DESC", prefix, prefix)
+	}
+
+	selected, returned := prefix+"*", "*"
+	if columns := ref.columns(); columns != nil {
+		returned = strings.Join(columns, ", ")
+
+		// the outer query sorts the rows, so the sort columns are selected
+		// even when they are not returned
+		for _, column := range ref.sortColumns() {
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+
+		selected = strings.Join(columns, ", ")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "WITH %s AS (SELECT %s FROM %s AS %s", ref.Alias, selected, ref.Table, ref.Alias)
+
+	if len(where) > 0 {
+		fmt.Fprintf(&sb, " WHERE %s", strings.Join(where, " AND "))
+	}
+
+	fmt.Fprintf(&sb, " ORDER BY %s LIMIT %d) SELECT %s FROM %s ORDER BY %s",
+		internalSort,
+		ref.Paginator.Limit,
+		returned,
+		ref.Alias,
+		externalSort,
+	)
+
+	return sb.String(), args, nil
+}
+
+// count returns the query counting the rows matching the filter and its arguments.
+// The columns, the sort and the paginator are ignored.
+func (ref listQuery) count() (string, []any, error) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT COUNT(*) FROM %s AS %s", ref.Table, ref.Alias)
+
+	filter, args, err := query.ParameterizeFilter(ref.Filter, ref.Alias+".", 1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if filter != "" {
+		fmt.Fprintf(&sb, " WHERE (%s)", filter)
+	}
+
+	return sb.String(), args, nil
+}
+
+// columns returns the selected columns prefixed with the table alias,
+// or nil when all the columns are selected.
+func (ref listQuery) columns() []string {
+	prefix := ref.Alias + "."
+
+	if len(ref.Columns) == 0 || ref.Columns[0] == "" {
+		return nil
+	}
+
+	columns := make([]string, 0, len(ref.Columns)+2)
+	var idFound bool
+	for _, column := range ref.Columns {
+		columns = append(columns, prefix+column)
+		if column == "id" {
+			idFound = true
+		}
+	}
+
+	if !idFound {
+		columns = append(columns, prefix+"id")
+	}
+
+	columns = append(columns, prefix+"serial_id")
+
+	return columns
+}
+
+// sortColumns returns the columns in the sort prefixed with the table alias.
+func (ref listQuery) sortColumns() []string {
+	prefix := ref.Alias + "."
+
+	var columns []string
+	for _, token := range strings.Split(ref.Sort, ",") {
+		if fields := strings.Fields(token); len(fields) > 0 {
+			columns = append(columns, prefix+fields[0])
+		}
+	}
+
+	return columns
+}
+
+// orderBy returns the sort rendered for the query, like
+// "first_name ASC, updated_at DESC NULLS LAST", or empty when there is no sort.
+func (ref listQuery) orderBy() string {
+	var terms []string
+	for _, token := range strings.Split(ref.Sort, ",") {
+		fields := strings.Fields(token)
+		if len(fields) < 2 {
+			continue
+		}
+
+		term := fmt.Sprintf("%s %s", fields[0], strings.ToUpper(fields[1]))
+		if len(fields) == 4 {
+			term += " NULLS " + strings.ToUpper(fields[3])
+		}
+
+		terms = append(terms, term)
+	}
+
+	return strings.Join(terms, ", ")
+}
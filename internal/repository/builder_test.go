@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
+)
+
+func TestListQuery_Build(t *testing.T) {
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+	token := paginator.EncodeToken(id, 42)
+
+	tests := []struct {
+		name     string
+		query    listQuery
+		wantSQL  string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:  "all columns, newest first",
+			query: listQuery{Table: "users", Alias: "usrs", Paginator: paginator.Paginator{Limit: 10}},
+			wantSQL: "WITH usrs AS (SELECT usrs.* FROM users AS usrs ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 10) " +
+				"SELECT * FROM usrs ORDER BY usrs.serial_id DESC, usrs.id DESC",
+		},
+		{
+			name: "columns always include id and serial_id",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Columns:   []string{"first_name", "email"},
+				Paginator: paginator.Paginator{Limit: 10},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.first_name, usrs.email, usrs.id, usrs.serial_id FROM users AS usrs ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 10) " +
+				"SELECT usrs.first_name, usrs.email, usrs.id, usrs.serial_id FROM usrs ORDER BY usrs.serial_id DESC, usrs.id DESC",
+		},
+		{
+			name: "sort columns are selected but not returned",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Columns:   []string{"email"},
+				Sort:      "last_name ASC, email DESC",
+				Paginator: paginator.Paginator{Limit: 10},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.email, usrs.id, usrs.serial_id, usrs.last_name FROM users AS usrs ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 10) " +
+				"SELECT usrs.email, usrs.id, usrs.serial_id FROM usrs ORDER BY last_name ASC, email DESC",
+		},
+		{
+			name: "filter and sort",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Filter:    "first_name='Alice' AND disabled=0",
+				Sort:      "first_name ASC",
+				Paginator: paginator.Paginator{Limit: 5},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.* FROM users AS usrs WHERE (usrs.first_name = $1 AND usrs.disabled = $2) ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 5) " +
+				"SELECT * FROM usrs ORDER BY first_name ASC",
+			wantArgs: []any{"Alice", int64(0)},
+		},
+		{
+			name: "filter, sort and next token",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Filter:    "first_name='Alice'",
+				Sort:      "first_name DESC",
+				Paginator: paginator.Paginator{Limit: 5, NextToken: token},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.* FROM users AS usrs WHERE (usrs.first_name = $1) AND (usrs.serial_id < $2) AND (usrs.id < $3 OR usrs.serial_id < $4) ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 5) " +
+				"SELECT * FROM usrs ORDER BY first_name DESC",
+			wantArgs: []any{"Alice", int64(42), id.String(), int64(42)},
+		},
+		{
+			name: "prev token reverses the internal order",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Paginator: paginator.Paginator{Limit: 5, PrevToken: token},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.* FROM users AS usrs WHERE (usrs.serial_id > $1) AND (usrs.id > $2 OR usrs.serial_id > $3) ORDER BY usrs.serial_id ASC, usrs.id ASC LIMIT 5) " +
+				"SELECT * FROM usrs ORDER BY usrs.serial_id DESC, usrs.id DESC",
+			wantArgs: []any{int64(42), id.String(), int64(42)},
+		},
+		{
+			name: "next token wins over prev token",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Paginator: paginator.Paginator{Limit: 5, NextToken: token, PrevToken: token},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.* FROM users AS usrs WHERE (usrs.serial_id < $1) AND (usrs.id < $2 OR usrs.serial_id < $3) ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 5) " +
+				"SELECT * FROM usrs ORDER BY usrs.serial_id DESC, usrs.id DESC",
+			wantArgs: []any{int64(42), id.String(), int64(42)},
+		},
+		{
+			name: "sort with nulls placement",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Sort:      "updated_at desc nulls last, id ASC",
+				Paginator: paginator.Paginator{Limit: 5},
+			},
+			wantSQL: "WITH usrs AS (SELECT usrs.* FROM users AS usrs ORDER BY usrs.serial_id DESC, usrs.id DESC LIMIT 5) " +
+				"SELECT * FROM usrs ORDER BY updated_at DESC NULLS LAST, id ASC",
+		},
+		{
+			name: "invalid token",
+			query: listQuery{
+				Table: "users", Alias: "usrs",
+				Paginator: paginator.Paginator{Limit: 5, NextToken: "not-a-token"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotSQL, gotArgs, err := tc.query.build()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("build() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if gotSQL != tc.wantSQL {
+				t.Errorf("build() query:\n got %s\nwant %s", gotSQL, tc.wantSQL)
+			}
+
+			if diff := cmp.Diff(tc.wantArgs, gotArgs); diff != "" {
+				t.Errorf("build() args (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListQuery_Count(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    listQuery
+		wantSQL  string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:    "all rows",
+			query:   listQuery{Table: "users", Alias: "usrs", Sort: "first_name ASC", Paginator: paginator.Paginator{Limit: 10}},
+			wantSQL: "SELECT COUNT(*) FROM users AS usrs",
+		},
+		{
+			name:     "filtered rows",
+			query:    listQuery{Table: "users", Alias: "usrs", Filter: "first_name='Alice' AND disabled=0"},
+			wantSQL:  "SELECT COUNT(*) FROM users AS usrs WHERE (usrs.first_name = $1 AND usrs.disabled = $2)",
+			wantArgs: []any{"Alice", int64(0)},
+		},
+		{
+			name:    "invalid filter",
+			query:   listQuery{Table: "users", Alias: "usrs", Filter: "not a filter"},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotSQL, gotArgs, err := tc.query.count()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("count() error = %v, wantErr %v", err, tc.wantErr)
+			}
+
+			if gotSQL != tc.wantSQL {
+				t.Errorf("count() query:\n got %s\nwant %s", gotSQL, tc.wantSQL)
+			}
+
+			if diff := cmp.Diff(tc.wantArgs, gotArgs); diff != "" {
+				t.Errorf("count() args (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListQuery_NoLiteralValues(t *testing.T) {
+	values := []string{"Alice", "Ha%", "42", "3.14"}
+	q := listQuery{
+		Table: "users", Alias: "usrs",
+		Filter:    "first_name='Alice' OR last_name LIKE 'Ha%' OR serial_id>=42 AND serial_id<3.14",
+		Paginator: paginator.Paginator{Limit: 10},
+	}
+
+	buildSQL, buildArgs, err := q.build()
+	if err != nil {
+		t.Fatalf("build() error = %v", err)
+	}
+
+	countSQL, countArgs, err := q.count()
+	if err != nil {
+		t.Fatalf("count() error = %v", err)
+	}
+
+	for _, statement := range []string{buildSQL, countSQL} {
+		if strings.Contains(statement, "'") {
+			t.Errorf("expected no quoted literal in %s", statement)
+		}
+
+		for _, value := range values {
+			if strings.Contains(statement, value) {
+				t.Errorf("expected no literal %q in %s", value, statement)
+			}
+		}
+	}
+
+	if len(buildArgs) != 4 || len(countArgs) != 4 {
+		t.Errorf("expected the 4 values as arguments, got %v and %v", buildArgs, countArgs)
+	}
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func init() {
+	// the repository reports the name of the registered driver, so one is
+	// registered once, while the tests open their own with openFakeDB
+	sql.Register("fake", &fakeDriver{})
+}
+
+// openFakeDB returns a database on d, closed at the end of the test.
+// It connects through a connector, so d doesn't need to be registered.
+func openFakeDB(tb testing.TB, d *fakeDriver) *sql.DB {
+	tb.Helper()
+
+	db := sql.OpenDB(fakeConnector{driver: d})
+	tb.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+type fakeConnector struct {
+	driver *fakeDriver
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open("")
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// fakeDriver is the database driver of the tests.
+// It records the statements executed on each connection, prefixed with the
+// connection number, and fails them with the errors of execErrors, in order,
+// nil succeeding. The queries are answered by query, and fail when it is nil.
+type fakeDriver struct {
+	mu         sync.Mutex
+	conns      int
+	statements []string
+	execErrors []error
+	query      func(ctx context.Context, query string) (driver.Rows, error)
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.conns++
+	return &fakeConn{driver: d, id: d.conns}, nil
+}
+
+// reset forgets the recorded statements and sets the errors of the next executed ones.
+func (d *fakeDriver) reset(execErrors ...error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.statements = nil
+	d.execErrors = execErrors
+}
+
+func (d *fakeDriver) record(statement string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.statements = append(d.statements, statement)
+}
+
+func (d *fakeDriver) nextExecError() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.execErrors) == 0 {
+		return nil
+	}
+
+	err := d.execErrors[0]
+	d.execErrors = d.execErrors[1:]
+
+	return err
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+	id     int
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	statement := fmt.Sprintf("%d: BEGIN", c.id)
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		statement += " ISOLATION LEVEL " + strings.ToUpper(level.String())
+	}
+
+	c.driver.record(statement)
+
+	return &fakeTx{conn: c}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	statement := fmt.Sprintf("%d: %s", c.id, query)
+	for _, arg := range args {
+		statement += fmt.Sprintf(" [%v]", arg.Value)
+	}
+
+	c.driver.record(statement)
+
+	if err := c.driver.nextExecError(); err != nil {
+		return nil, err
+	}
+
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if c.driver.query == nil {
+		return nil, errors.New("queries not supported")
+	}
+
+	return c.driver.query(ctx, query)
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.driver.record(fmt.Sprintf("%d: COMMIT", tx.conn.id))
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.driver.record(fmt.Sprintf("%d: ROLLBACK", tx.conn.id))
+	return nil
+}
@@ -8,6 +8,7 @@ var (
 	ErrDBInvalidMaxQueryTimeout     = errors.New("invalid max query timeout. It must be greater than 10 millisecond")
 	ErrOTInvalidConfiguration       = errors.New("invalid OpenTelemetry configuration. It is nil")
 	ErrAtLeastOneFieldMustBeUpdated = errors.New("at least one field must be updated")
+	ErrTxAlreadyActive              = errors.New("a transaction is already active in the context")
 
 	ErrInputIsNil       = errors.New("input is nil")
 	ErrInvalidFilter    = errors.New("invalid filter field")
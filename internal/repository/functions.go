@@ -1,8 +1,16 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // prettyPrint removes comments, newlines, and extra spaces from a query string.
@@ -17,3 +25,217 @@ func prettyPrint(query string) string {
 
 	return out
 }
+
+// isUniqueViolation reports whether err is a violation of the unique
+// constraint on the given column.
+func isUniqueViolation(err error, column string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return false
+	}
+
+	// the constraint names follow the table_column convention,
+	// with table_pkey for the primary key
+	constraint := pgErr.ConstraintName
+	if constraint == "" {
+		constraint = pgErr.Message
+	}
+
+	if column == "id" {
+		return strings.Contains(constraint, "_pkey")
+	}
+
+	return strings.Contains(constraint, "_"+column)
+}
+
+// isRetryable reports whether err is a serialization failure (40001)
+// or a deadlock (40P01), which abort the transaction but are safe to retry.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// querier is implemented by *sql.DB and *sql.Conn, so the queries
+// can run on the pool or on a dedicated connection.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// ApplicationNameFunc returns the application name used for the queries of the context,
+// for example the request ID. An empty name leaves the application name untouched.
+type ApplicationNameFunc func(ctx context.Context) string
+
+// withApplicationName returns the querier to run the queries of the context and
+// the function to release it once done.
+// When fn returns a name, a dedicated connection with
+// the application name set is returned, so the database activity views show which
+// request a query belongs to. Otherwise, or if the name cannot be set, the pool is returned.
+func withApplicationName(ctx context.Context, db *sql.DB, fn ApplicationNameFunc) (querier, func()) {
+	if fn == nil {
+		return db, func() {}
+	}
+
+	name := fn(ctx)
+	if name == "" {
+		return db, func() {}
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		slog.Warn("repository.withApplicationName", "error", err)
+		return db, func() {}
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT set_config('application_name', $1, false)", name); err != nil {
+		slog.Warn("repository.withApplicationName", "error", err)
+		conn.Close()
+		return db, func() {}
+	}
+
+	return conn, func() {
+		// the connection goes back to the pool, so restore the default name
+		// without the request context, which could be already canceled
+		if _, err := conn.ExecContext(context.Background(), "RESET application_name"); err != nil {
+			slog.Warn("repository.withApplicationName", "error", err)
+		}
+
+		conn.Close()
+	}
+}
+
+// TxOpts represents the options of a transaction run by withTx.
+type TxOpts struct {
+	// Isolation is the isolation level of the transaction. The default,
+	// sql.LevelDefault, uses the level of the database, read committed on PostgreSQL.
+	// The operations reading rows and writing based on them without locking
+	// them, like checking a value is unused before inserting it, should use
+	// sql.LevelSerializable together with MaxRetries, as the database aborts
+	// the conflicting transactions. The Upsert of the users takes whether it created
+	// the user from the INSERT ... ON CONFLICT itself, and DeleteByIDs locks the
+	// existing rows it reads with FOR UPDATE, so they are safe with the default level.
+	// Savepoints run at the level of the transaction they are nested in.
+	Isolation sql.IsolationLevel
+
+	// Savepoint runs fn inside a savepoint of the transaction already active in
+	// the context, if any, so fn failing only rolls back its own changes.
+	// Without an active transaction, a new one is begun as usual.
+	Savepoint bool
+
+	// MaxRetries is the number of times the whole transaction is run again
+	// when it fails with an error reported as retryable by IsRetryable.
+	// Savepoints are never retried on their own, as these errors abort the transaction.
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubled after each retry.
+	RetryBackoff time.Duration
+
+	// IsRetryable reports whether the error of the transaction is safe to retry,
+	// usually isRetryable. If nil, nothing is retried.
+	IsRetryable func(err error) bool
+}
+
+// txKey is the context key of the active transaction.
+type txKey struct{}
+
+// activeTx is the transaction active in a context and its savepoint nesting depth.
+type activeTx struct {
+	tx    *sql.Tx
+	depth int
+}
+
+// txFromContext returns the transaction active in the context, if any.
+func txFromContext(ctx context.Context) *activeTx {
+	active, _ := ctx.Value(txKey{}).(*activeTx)
+	return active
+}
+
+// withTx runs fn inside a database transaction.
+// The transaction is committed when fn returns nil and rolled back otherwise.
+// The context given to fn marks the transaction as active, so beginning another
+// one with it fails with ErrTxAlreadyActive instead of holding a second connection
+// that could wait forever on the locks of the first one, unless opts.Savepoint
+// is set to nest fn in a savepoint of the active transaction.
+// A transaction failing with a retryable error is run again, with fn called from
+// scratch, up to opts.MaxRetries times.
+func withTx(ctx context.Context, db querier, opts TxOpts, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	if active := txFromContext(ctx); active != nil {
+		if !opts.Savepoint {
+			return ErrTxAlreadyActive
+		}
+
+		return withSavepoint(ctx, active, fn)
+	}
+
+	backoff := opts.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, db, opts.Isolation, fn)
+		if err == nil || opts.IsRetryable == nil || !opts.IsRetryable(err) || attempt > opts.MaxRetries {
+			return err
+		}
+
+		slog.Warn("repository.withTx", "message", "transaction failed, retrying",
+			"retry", attempt,
+			"max_retries", opts.MaxRetries,
+			"backoff", backoff,
+			"error", err,
+		)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// runTx runs fn inside a new transaction with the given isolation level,
+// committed when fn returns nil and rolled back otherwise.
+func runTx(ctx context.Context, db querier, isolation sql.IsolationLevel, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: isolation})
+	if err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, &activeTx{tx: tx}), tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// withSavepoint runs fn inside a savepoint of the active transaction.
+// The savepoint is released when fn returns nil and rolled back otherwise,
+// leaving the changes made before it in the transaction untouched.
+func withSavepoint(ctx context.Context, active *activeTx, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	nested := &activeTx{tx: active.tx, depth: active.depth + 1}
+	name := fmt.Sprintf("sp_%d", nested.depth)
+
+	if _, err := active.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return err
+	}
+
+	if err := fn(context.WithValue(ctx, txKey{}, nested), active.tx); err != nil {
+		if _, rbErr := active.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+
+		return err
+	}
+
+	_, err := active.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
@@ -0,0 +1,319 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestWithApplicationName(t *testing.T) {
+	recorder := &fakeDriver{}
+	db := openFakeDB(t, recorder)
+
+	requestID := func(context.Context) string { return "request-1" }
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		fn   ApplicationNameFunc
+		want []string
+	}{
+		{
+			name: "application name set for the query",
+			fn:   requestID,
+			want: []string{
+				"1: SELECT set_config('application_name', $1, false) [request-1]",
+				"1: DELETE FROM users",
+				"1: RESET application_name",
+			},
+		},
+		{
+			name: "application name not configured",
+			want: []string{"1: DELETE FROM users"},
+		},
+		{
+			name: "empty application name",
+			fn:   func(context.Context) string { return "" },
+			want: []string{"1: DELETE FROM users"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder.reset()
+
+			q, release := withApplicationName(ctx, db, tc.fn)
+			if _, err := q.ExecContext(ctx, "DELETE FROM users"); err != nil {
+				t.Fatalf("could not execute query: %v", err)
+			}
+			release()
+
+			if !slices.Equal(recorder.statements, tc.want) {
+				t.Errorf("expected statements %q, got %q", tc.want, recorder.statements)
+			}
+		})
+	}
+}
+
+func TestWithTx_Nested(t *testing.T) {
+	recorder := &fakeDriver{}
+	db := openFakeDB(t, recorder)
+
+	err := withTx(context.Background(), db, TxOpts{}, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM users"); err != nil {
+			return err
+		}
+
+		nested := withTx(ctx, db, TxOpts{}, func(ctx context.Context, tx *sql.Tx) error {
+			t.Error("the nested transaction must not run")
+			return nil
+		})
+		if !errors.Is(nested, ErrTxAlreadyActive) {
+			t.Errorf("expected error %v, got %v", ErrTxAlreadyActive, nested)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{"1: BEGIN", "1: DELETE FROM users", "1: COMMIT"}
+	if !slices.Equal(recorder.statements, want) {
+		t.Errorf("expected statements %q, got %q", want, recorder.statements)
+	}
+}
+
+func TestWithTx_Savepoint(t *testing.T) {
+	recorder := &fakeDriver{}
+	db := openFakeDB(t, recorder)
+
+	errInner := errors.New("inner step failed")
+
+	err := withTx(context.Background(), db, TxOpts{}, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO users VALUES (1)"); err != nil {
+			return err
+		}
+
+		inner := withTx(ctx, db, TxOpts{Savepoint: true}, func(ctx context.Context, tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO users VALUES (2)"); err != nil {
+				return err
+			}
+
+			return errInner
+		})
+		if !errors.Is(inner, errInner) {
+			t.Errorf("expected error %v, got %v", errInner, inner)
+		}
+
+		released := withTx(ctx, db, TxOpts{Savepoint: true}, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO users VALUES (3)")
+			return err
+		})
+		if released != nil {
+			t.Errorf("expected no error, got %v", released)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	want := []string{
+		"1: BEGIN",
+		"1: INSERT INTO users VALUES (1)",
+		"1: SAVEPOINT sp_1",
+		"1: INSERT INTO users VALUES (2)",
+		"1: ROLLBACK TO SAVEPOINT sp_1",
+		"1: SAVEPOINT sp_1",
+		"1: INSERT INTO users VALUES (3)",
+		"1: RELEASE SAVEPOINT sp_1",
+		"1: COMMIT",
+	}
+	if !slices.Equal(recorder.statements, want) {
+		t.Errorf("expected statements %q, got %q", want, recorder.statements)
+	}
+}
+
+func TestWithTx_Retry(t *testing.T) {
+	recorder := &fakeDriver{}
+	db := openFakeDB(t, recorder)
+
+	serializationFailure := &pgconn.PgError{Code: "40001"}
+	opts := TxOpts{
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+		IsRetryable:  isRetryable,
+	}
+
+	tests := []struct {
+		name       string
+		execErrors []error
+		opts       TxOpts
+		wantErr    error
+		wantRuns   int
+	}{
+		{
+			name:       "serialization failure on the first attempt",
+			execErrors: []error{serializationFailure},
+			opts:       opts,
+			wantRuns:   2,
+		},
+		{
+			name:       "serialization failure on every attempt",
+			execErrors: []error{serializationFailure, serializationFailure, serializationFailure},
+			opts:       opts,
+			wantErr:    serializationFailure,
+			wantRuns:   3,
+		},
+		{
+			name:       "error not retryable",
+			execErrors: []error{&pgconn.PgError{Code: "23505"}},
+			opts:       opts,
+			wantErr:    &pgconn.PgError{Code: "23505"},
+			wantRuns:   1,
+		},
+		{
+			name:       "retries not configured",
+			execErrors: []error{serializationFailure},
+			wantErr:    serializationFailure,
+			wantRuns:   1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder.reset(tc.execErrors...)
+
+			runs := 0
+			err := withTx(context.Background(), db, tc.opts, func(ctx context.Context, tx *sql.Tx) error {
+				runs++
+				_, err := tx.ExecContext(ctx, "UPDATE users SET disabled = true")
+				return err
+			})
+
+			if tc.wantErr == nil && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+
+			if tc.wantErr != nil && (err == nil || err.Error() != tc.wantErr.Error()) {
+				t.Errorf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			if runs != tc.wantRuns {
+				t.Errorf("expected %d runs, got %d", tc.wantRuns, runs)
+			}
+		})
+	}
+
+	t.Run("failed attempt rolled back before the retry", func(t *testing.T) {
+		recorder.reset(serializationFailure)
+
+		err := withTx(context.Background(), db, opts, func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE users SET disabled = true")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		want := []string{
+			"1: BEGIN",
+			"1: UPDATE users SET disabled = true",
+			"1: ROLLBACK",
+			"1: BEGIN",
+			"1: UPDATE users SET disabled = true",
+			"1: COMMIT",
+		}
+		if !slices.Equal(recorder.statements, want) {
+			t.Errorf("expected statements %q, got %q", want, recorder.statements)
+		}
+	})
+}
+
+func TestWithTx_Isolation(t *testing.T) {
+	recorder := &fakeDriver{}
+	db := openFakeDB(t, recorder)
+
+	tests := []struct {
+		name      string
+		isolation sql.IsolationLevel
+		want      string
+	}{
+		{"database default", sql.LevelDefault, "1: BEGIN"},
+		{"read committed", sql.LevelReadCommitted, "1: BEGIN ISOLATION LEVEL READ COMMITTED"},
+		{"repeatable read", sql.LevelRepeatableRead, "1: BEGIN ISOLATION LEVEL REPEATABLE READ"},
+		{"serializable", sql.LevelSerializable, "1: BEGIN ISOLATION LEVEL SERIALIZABLE"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder.reset()
+
+			err := withTx(context.Background(), db, TxOpts{Isolation: tc.isolation}, func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+
+			want := []string{tc.want, "1: COMMIT"}
+			if !slices.Equal(recorder.statements, want) {
+				t.Errorf("expected statements %q, got %q", want, recorder.statements)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		column string
+		want   bool
+	}{
+		{"primary key", &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey"}, "id", true},
+		{"email", &pgconn.PgError{Code: "23505", ConstraintName: "users_email"}, "email", true},
+		{"email is not id", &pgconn.PgError{Code: "23505", ConstraintName: "users_email"}, "id", false},
+		{"wrapped", fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey"}), "id", true},
+		{"other error", &pgconn.PgError{Code: "23503", ConstraintName: "users_pkey"}, "id", false},
+		{"plain error", errors.New("boom"), "id", false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isUniqueViolation(tc.err, tc.column); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", &pgconn.PgError{Code: "40001"}, true},
+		{"deadlock", &pgconn.PgError{Code: "40P01"}, true},
+		{"wrapped", fmt.Errorf("update: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"unique violation", &pgconn.PgError{Code: "23505"}, false},
+		{"plain error", errors.New("boom"), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isRetryable(tc.err); got != tc.want {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
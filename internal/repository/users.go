@@ -1,21 +1,17 @@
 package repository
 
 import (
-	"bytes"
 	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"html/template"
 	"log/slog"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
-	"github.com/p2p-b2b/go-rest-api-service-template/internal/query"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -28,6 +24,22 @@ type UsersRepositoryConfig struct {
 	MaxQueryTimeout time.Duration
 	OT              *o11y.OpenTelemetry
 	MetricsPrefix   string
+
+	// CountTimeout is the time allowed to count the rows exactly before
+	// estimating them from the table statistics. Zero always counts them exactly.
+	CountTimeout time.Duration
+
+	// ApplicationName, when set, returns the application name of the
+	// connection running the queries of a context, like the request ID.
+	ApplicationName ApplicationNameFunc
+
+	// TxMaxRetries is the number of times a transaction failing with a
+	// serialization failure or a deadlock is retried.
+	TxMaxRetries int
+
+	// TxRetryBackoff is the wait before the first retry of a transaction,
+	// doubled after each retry.
+	TxRetryBackoff time.Duration
 }
 
 type usersRepositoryMetrics struct {
@@ -38,9 +50,13 @@ type UsersRepository struct {
 	db              *sql.DB
 	maxPingTimeout  time.Duration
 	maxQueryTimeout time.Duration
+	countTimeout    time.Duration
 	ot              *o11y.OpenTelemetry
 	metricsPrefix   string
 	metrics         usersRepositoryMetrics
+	applicationName ApplicationNameFunc
+	txMaxRetries    int
+	txRetryBackoff  time.Duration
 }
 
 func NewUsersRepository(conf UsersRepositoryConfig) (*UsersRepository, error) {
@@ -64,7 +80,11 @@ func NewUsersRepository(conf UsersRepositoryConfig) (*UsersRepository, error) {
 		db:              conf.DB,
 		maxPingTimeout:  conf.MaxPingTimeout,
 		maxQueryTimeout: conf.MaxQueryTimeout,
+		countTimeout:    conf.CountTimeout,
 		ot:              conf.OT,
+		applicationName: conf.ApplicationName,
+		txMaxRetries:    conf.TxMaxRetries,
+		txRetryBackoff:  conf.TxRetryBackoff,
 	}
 	if conf.MetricsPrefix != "" {
 		repo.metricsPrefix = strings.ReplaceAll(conf.MetricsPrefix, "-", "_")
@@ -85,6 +105,15 @@ func NewUsersRepository(conf UsersRepositoryConfig) (*UsersRepository, error) {
 	return repo, nil
 }
 
+// txOpts returns the options of the transactions of the repository.
+func (ref *UsersRepository) txOpts() TxOpts {
+	return TxOpts{
+		MaxRetries:   ref.txMaxRetries,
+		RetryBackoff: ref.txRetryBackoff,
+		IsRetryable:  isRetryable,
+	}
+}
+
 func (ref *UsersRepository) DriverName() string {
 	return sql.Drivers()[0]
 }
@@ -157,7 +186,10 @@ func (ref *UsersRepository) Insert(ctx context.Context, input *InsertUserInput)
         VALUES ($1, $2, $3, $4, $5, $6);
     `
 
-	_, err := ref.db.ExecContext(ctx, query,
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	_, err := db.ExecContext(ctx, query,
 		input.ID,
 		input.FirstName,
 		input.LastName,
@@ -175,19 +207,12 @@ func (ref *UsersRepository) Insert(ctx context.Context, input *InsertUserInput)
 			),
 		)
 
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			if pgErr.Code == "23505" {
-				if strings.Contains(pgErr.Message, "_pkey") {
-					return ErrUserIDAlreadyExists
-				}
-
-				if strings.Contains(pgErr.Message, "_email") {
-					return ErrUserEmailAlreadyExists
-				}
+		if isUniqueViolation(err, "id") {
+			return ErrUserIDAlreadyExists
+		}
 
-				return err
-			}
+		if isUniqueViolation(err, "email") {
+			return ErrUserEmailAlreadyExists
 		}
 
 		return err
@@ -205,6 +230,115 @@ func (ref *UsersRepository) Insert(ctx context.Context, input *InsertUserInput)
 	return nil
 }
 
+// Upsert inserts the user or, when a user with the same ID already exists,
+// replaces its data. It reports whether the user was created.
+func (ref *UsersRepository) Upsert(ctx context.Context, input *UpsertUserInput) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, ref.maxQueryTimeout)
+	defer cancel()
+
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "repository.Users.Upsert")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("driver", ref.DriverName()),
+		attribute.String("component", "repository.Users.Upsert"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("driver", ref.DriverName()),
+		attribute.String("component", "repository.Users.Upsert"),
+	}
+
+	if input == nil {
+		span.SetStatus(codes.Error, ErrInputIsNil.Error())
+		span.RecordError(ErrInputIsNil)
+		slog.Error("repository.Users.Upsert", "error", ErrInputIsNil.Error())
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return false, ErrInputIsNil
+	}
+
+	span.SetAttributes(attribute.String("user.id", input.ID.String()))
+
+	if err := input.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("repository.Users.Upsert", "error", err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return false, err
+	}
+
+	// the upsert itself reports whether it inserted the row, as reading the row first
+	// can't lock a row that doesn't exist yet: two concurrent upserts of a new user
+	// would both see no row, and both report it as created.
+	// xmax is zero for the row versions written by an insert and holds the id of the
+	// transaction for the ones written by the ON CONFLICT DO UPDATE.
+	upsertQuery := `
+        INSERT INTO users (id, first_name, last_name, email, password_hash, disabled, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (id) DO UPDATE SET
+            first_name = EXCLUDED.first_name,
+            last_name = EXCLUDED.last_name,
+            email = EXCLUDED.email,
+            password_hash = EXCLUDED.password_hash,
+            disabled = EXCLUDED.disabled,
+            updated_at = EXCLUDED.updated_at
+        RETURNING (xmax = 0);
+    `
+
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	var created bool
+	err := withTx(ctx, db, ref.txOpts(), func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, upsertQuery,
+			input.ID,
+			input.FirstName,
+			input.LastName,
+			input.Email,
+			input.PasswordHash,
+			input.Disabled,
+			time.Now().UTC(),
+		).Scan(&created)
+	})
+	if err != nil {
+		slog.Error("repository.Users.Upsert", "error", err)
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		if isUniqueViolation(err, "email") {
+			return false, ErrUserEmailAlreadyExists
+		}
+
+		return false, err
+	}
+
+	slog.Debug("repository.Users.Upsert", "user.id", input.ID, "created", created)
+	span.SetStatus(codes.Ok, "user upserted successfully")
+	span.SetAttributes(attribute.Bool("user.created", created))
+	ref.metrics.repositoryCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	return created, nil
+}
+
 func (ref *UsersRepository) Update(ctx context.Context, input *UpdateUserInput) error {
 	ctx, cancel := context.WithTimeout(ctx, ref.maxQueryTimeout)
 	defer cancel()
@@ -250,7 +384,7 @@ func (ref *UsersRepository) Update(ctx context.Context, input *UpdateUserInput)
 		return err
 	}
 
-	args := []interface{}{input.ID}
+	var args []interface{}
 
 	if input.FirstName != nil && *input.FirstName != "" {
 		args = append(args, *input.FirstName)
@@ -283,20 +417,23 @@ func (ref *UsersRepository) Update(ctx context.Context, input *UpdateUserInput)
 	}
 
 	updatedAt, _ := time.Now().In(time.FixedZone("UTC", 0)).MarshalText()
-	args = append(args, updatedAt)
+	args = append(args, updatedAt, input.ID)
 
 	query := `
         UPDATE users SET
-            first_name = COALESCE($2, first_name),
-            last_name = COALESCE($3,  last_name),
-            email = COALESCE($4, email),
-            password_hash = COALESCE($5, password_hash),
-            disabled = COALESCE($6, disabled),
-            updated_at = COALESCE($7 , updated_at)
-        WHERE id = $1;
+            first_name = COALESCE($1, first_name),
+            last_name = COALESCE($2,  last_name),
+            email = COALESCE($3, email),
+            password_hash = COALESCE($4, password_hash),
+            disabled = COALESCE($5, disabled),
+            updated_at = COALESCE($6 , updated_at)
+        WHERE id = $7;
     `
 
-	stmt, err := ref.db.PrepareContext(ctx, query)
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	stmt, err := db.PrepareContext(ctx, query)
 	if err != nil {
 		slog.Error("repository.Users.Update", "error", err)
 		span.SetStatus(codes.Error, "query failed")
@@ -322,6 +459,10 @@ func (ref *UsersRepository) Update(ctx context.Context, input *UpdateUserInput)
 			),
 		)
 
+		if isUniqueViolation(err, "email") {
+			return ErrUserEmailAlreadyExists
+		}
+
 		return err
 	}
 
@@ -413,7 +554,10 @@ func (ref *UsersRepository) Delete(ctx context.Context, input *DeleteUserInput)
         WHERE id = $1
     `
 
-	result, err := ref.db.ExecContext(ctx, query, input.ID)
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	result, err := db.ExecContext(ctx, query, input.ID)
 	if err != nil {
 		span.SetStatus(codes.Error, "query failed")
 		span.RecordError(err)
@@ -465,6 +609,139 @@ func (ref *UsersRepository) Delete(ctx context.Context, input *DeleteUserInput)
 	return nil
 }
 
+func (ref *UsersRepository) DeleteByIDs(ctx context.Context, input *DeleteUsersByIDsInput) (*DeleteUsersByIDsOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, ref.maxQueryTimeout)
+	defer cancel()
+
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "repository.Users.DeleteByIDs")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("driver", ref.DriverName()),
+		attribute.String("component", "repository.Users.DeleteByIDs"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("driver", ref.DriverName()),
+		attribute.String("component", "repository.Users.DeleteByIDs"),
+	}
+
+	if input == nil {
+		slog.Error("repository.Users.DeleteByIDs", "error", "input is nil")
+		span.SetStatus(codes.Error, ErrInputIsNil.Error())
+		span.RecordError(ErrInputIsNil)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, ErrInputIsNil
+	}
+
+	span.SetAttributes(
+		attribute.Int("users.count", len(input.IDs)),
+		attribute.Bool("dry_run", input.DryRun),
+	)
+
+	if err := input.Validate(); err != nil {
+		slog.Error("repository.Users.DeleteByIDs", "error", err)
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	args := make([]interface{}, len(input.IDs))
+	placeholders := make([]string, len(input.IDs))
+	for i, id := range input.IDs {
+		args[i] = id
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+
+	inList := strings.Join(placeholders, ", ")
+
+	// lock the rows first, so the reported users are the ones deleted
+	selectQuery := fmt.Sprintf(`
+        SELECT id
+        FROM users
+        WHERE id IN (%s)
+        FOR UPDATE;
+    `, inList)
+
+	deleteQuery := fmt.Sprintf(`
+        DELETE FROM users
+        WHERE id IN (%s);
+    `, inList)
+
+	slog.Debug("repository.Users.DeleteByIDs", "query", prettyPrint(selectQuery))
+
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	var deleted []uuid.UUID
+	err := withTx(ctx, db, ref.txOpts(), func(ctx context.Context, tx *sql.Tx) error {
+		// start over when the transaction is retried
+		deleted = nil
+
+		rows, err := tx.QueryContext(ctx, selectQuery, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id uuid.UUID
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+
+			deleted = append(deleted, id)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		// in dry run mode only report the users that would be deleted
+		if input.DryRun || len(deleted) == 0 {
+			return nil
+		}
+
+		slog.Debug("repository.Users.DeleteByIDs", "query", prettyPrint(deleteQuery))
+
+		_, err = tx.ExecContext(ctx, deleteQuery, args...)
+		return err
+	})
+	if err != nil {
+		slog.Error("repository.Users.DeleteByIDs", "error", err)
+		span.SetStatus(codes.Error, "query failed")
+		span.RecordError(err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "users deleted successfully")
+	span.SetAttributes(attribute.Int("users.deleted", len(deleted)))
+	ref.metrics.repositoryCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	return &DeleteUsersByIDsOutput{Deleted: deleted}, nil
+}
+
 func (ref *UsersRepository) SelectByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	ctx, cancel := context.WithTimeout(ctx, ref.maxQueryTimeout)
 	defer cancel()
@@ -512,7 +789,10 @@ func (ref *UsersRepository) SelectByID(ctx context.Context, id uuid.UUID) (*User
 
 	slog.Debug("repository.Users.SelectByID", "query", prettyPrint(query))
 
-	row := ref.db.QueryRowContext(ctx, query, id)
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	row := db.QueryRowContext(ctx, query, id)
 
 	var item User
 	if err := row.Scan(
@@ -589,12 +869,15 @@ func (ref *UsersRepository) SelectByEmail(ctx context.Context, email string) (*U
             created_at,
             updated_at
         FROM users
-        WHERE email = $1;
+        WHERE lower(email) = lower($1);
     `
 
 	slog.Debug("repository.Users.SelectByEmail", "query", prettyPrint(query))
 
-	row := ref.db.QueryRowContext(ctx, query, email)
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	row := db.QueryRowContext(ctx, query, email)
 
 	var item User
 	if err := row.Scan(
@@ -676,163 +959,17 @@ func (ref *UsersRepository) Select(ctx context.Context, input *SelectUsersInput)
 		return nil, err
 	}
 
-	// if no fields are provided, select all fields
-	sqlFieldsPrefix := "usrs."
-	fieldsStr := sqlFieldsPrefix + "*"
-	if input.Fields[0] != "" {
-		fields := make([]string, 0)
-		var isIsPresent bool
-		for _, field := range input.Fields {
-			fields = append(fields, sqlFieldsPrefix+field)
-			if field == "id" {
-				isIsPresent = true
-			}
-		}
-
-		// id and serial_id are always selected because they are used for pagination
-		if !isIsPresent {
-			fields = append(fields, sqlFieldsPrefix+"id")
-		}
-
-		fields = append(fields, sqlFieldsPrefix+"serial_id")
-		fieldsStr = strings.Join(fields, ", ")
-	}
-
-	var filterQuery string
-	if input.Filter != "" {
-		filter, err := query.PrefixFilterFields(input.Filter, sqlFieldsPrefix)
-		if err != nil {
-			span.SetStatus(codes.Error, err.Error())
-			span.RecordError(err)
-			slog.Error("repository.Users.Select", "error", err)
-			ref.metrics.repositoryCalls.Add(ctx, 1,
-				metric.WithAttributes(
-					append(metricCommonAttributes, attribute.String("successful", "false"))...,
-				),
-			)
-
-			return nil, err
-		}
-
-		filterQuery = fmt.Sprintf("WHERE (%s)", filter)
-	}
-
-	var sortQuery string
-	if input.Sort == "" {
-		sortQuery = "usrs.serial_id DESC, usrs.id DESC"
-	} else {
-		sortQuery = input.Sort
-	}
-
-	var queryTemplate string = `
-        WITH usrs AS (
-            SELECT
-                {{.QueryColumns}}
-            FROM users AS usrs
-            {{ .QueryWhere }}
-            ORDER BY {{.QueryInternalSort}}
-            LIMIT {{.QueryLimit}}
-        ) SELECT * FROM usrs ORDER BY {{.QueryExternalSort}}
-    `
-
-	var queryValues struct {
-		QueryColumns      string
-		QueryWhere        template.HTML
-		QueryLimit        int
-		QueryInternalSort string
-		QueryExternalSort string
-	}
-
-	queryValues.QueryColumns = fieldsStr
-	queryValues.QueryWhere = template.HTML(filterQuery)
-	queryValues.QueryLimit = input.Paginator.Limit
-	queryValues.QueryInternalSort = "usrs.serial_id DESC, usrs.id DESC"
-	queryValues.QueryExternalSort = sortQuery
-
-	filterQueryJoiner := "WHERE"
-	if filterQuery != "" {
-		filterQueryJoiner = "AND"
-	}
-
-	// if both next and prev tokens are provided, use next token
-	if input.Paginator.NextToken != "" && input.Paginator.PrevToken != "" {
-		slog.Warn("repository.Users.Select",
-			"message",
-			"both next and prev tokens are provided, going to use next token")
-
-		// clean the prev token
-		input.Paginator.PrevToken = ""
-	}
-
-	// if next token is provided
-	if input.Paginator.NextToken != "" {
-		// decode the token
-		id, serial, err := paginator.DecodeToken(input.Paginator.NextToken)
-		if err != nil {
-			slog.Error("repository.Users.Select", "error", err)
-			span.SetStatus(codes.Error, "invalid token")
-			span.RecordError(err)
-			ref.metrics.repositoryCalls.Add(ctx, 1,
-				metric.WithAttributes(
-					append(metricCommonAttributes, attribute.String("successful", "false"))...,
-				),
-			)
-
-			return nil, err
-		}
-
-		// from newest to oldest
-		queryValues.QueryInternalSort = "usrs.serial_id DESC, usrs.id DESC"
-		queryValues.QueryWhere = template.HTML(fmt.Sprintf(`
-                %s
-                    %s (usrs.serial_id < '%d')
-                    AND (usrs.id < '%s' OR usrs.serial_id < '%d')`,
-			filterQuery,
-			filterQueryJoiner,
-			serial,
-			id.String(),
-			serial,
-		))
-
-	}
-
-	// if prev token is provided
-	if input.Paginator.PrevToken != "" {
-		// decode the token
-		id, serial, err := paginator.DecodeToken(input.Paginator.PrevToken)
-		if err != nil {
-			slog.Error("repository.Users.Select", "error", err)
-			span.SetStatus(codes.Error, "invalid token")
-			span.RecordError(err)
-			ref.metrics.repositoryCalls.Add(ctx, 1,
-				metric.WithAttributes(
-					append(metricCommonAttributes, attribute.String("successful", "false"))...,
-				),
-			)
-			return nil, err
-		}
-
-		// from newest to oldest
-		queryValues.QueryInternalSort = "usrs.serial_id ASC, usrs.id ASC"
-		queryValues.QueryWhere = template.HTML(fmt.Sprintf(`
-                %s
-                    %s (usrs.serial_id > '%d')
-                    AND (usrs.id > '%s' OR usrs.serial_id > '%d')`,
-			filterQuery,
-			filterQueryJoiner,
-			serial,
-			id.String(),
-			serial,
-		))
-	}
-
-	// render the template on query variable
-	var tpl bytes.Buffer
-	t := template.Must(template.New("query").Parse(queryTemplate))
-	err := t.Execute(&tpl, queryValues)
+	query, args, err := listQuery{
+		Table:     "users",
+		Alias:     "usrs",
+		Columns:   input.Fields,
+		Filter:    input.Filter,
+		Sort:      input.Sort,
+		Paginator: input.Paginator,
+	}.build()
 	if err != nil {
 		slog.Error("repository.Users.Select", "error", err)
-		span.SetStatus(codes.Error, "failed to render query template")
+		span.SetStatus(codes.Error, "failed to build query")
 		span.RecordError(err)
 		ref.metrics.repositoryCalls.Add(ctx, 1,
 			metric.WithAttributes(
@@ -840,14 +977,16 @@ func (ref *UsersRepository) Select(ctx context.Context, input *SelectUsersInput)
 			),
 		)
 
-		return nil, fmt.Errorf("failed to parse query: %w", err)
+		return nil, err
 	}
 
-	query := tpl.String()
 	slog.Debug("repository.Users.Select", "query", prettyPrint(query))
 
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
 	// execute the query
-	rows, err := ref.db.QueryContext(ctx, query)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		slog.Error("repository.Users.Select", "error", err)
 		span.SetStatus(codes.Error, "failed to select all users")
@@ -866,7 +1005,7 @@ func (ref *UsersRepository) Select(ctx context.Context, input *SelectUsersInput)
 	for rows.Next() {
 		var item User
 
-		scanFields := make([]interface{}, 0)
+		var scanFields []interface{}
 
 		if input.Fields[0] == "" {
 			scanFields = []interface{}{
@@ -881,8 +1020,10 @@ func (ref *UsersRepository) Select(ctx context.Context, input *SelectUsersInput)
 				&item.SerialID,
 			}
 		} else {
-			var idFound bool
+			// the requested fields, id and serial_id
+			scanFields = make([]interface{}, 0, len(input.Fields)+2)
 
+			var idFound bool
 			for _, field := range input.Fields {
 				switch field {
 				case "id":
@@ -973,3 +1114,132 @@ func (ref *UsersRepository) Select(ctx context.Context, input *SelectUsersInput)
 
 	return ret, nil
 }
+
+// Count returns the number of users matching the filter.
+// If the count takes longer than the count timeout, the number of users is estimated instead.
+func (ref *UsersRepository) Count(ctx context.Context, input *CountUsersInput) (*CountUsersOutput, error) {
+	ctx, cancel := context.WithTimeout(ctx, ref.maxQueryTimeout)
+	defer cancel()
+
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "repository.Users.Count")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("driver", ref.DriverName()),
+		attribute.String("component", "repository.Users.Count"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("driver", ref.DriverName()),
+		attribute.String("component", "repository.Users.Count"),
+	}
+
+	if input == nil {
+		span.SetStatus(codes.Error, ErrInputIsNil.Error())
+		span.RecordError(ErrInputIsNil)
+		slog.Error("repository.Users.Count", "error", ErrInputIsNil)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, ErrInputIsNil
+	}
+
+	if err := input.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("repository.Users.Count", "error", err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	query, args, err := listQuery{
+		Table:  "users",
+		Alias:  "usrs",
+		Filter: input.Filter,
+	}.count()
+	if err != nil {
+		slog.Error("repository.Users.Count", "error", err)
+		span.SetStatus(codes.Error, "failed to build query")
+		span.RecordError(err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	slog.Debug("repository.Users.Count", "query", prettyPrint(query))
+
+	db, release := withApplicationName(ctx, ref.db, ref.applicationName)
+	defer release()
+
+	out, err := ref.countOrEstimate(ctx, db, query, args)
+	if err != nil {
+		slog.Error("repository.Users.Count", "error", err)
+		span.SetStatus(codes.Error, "failed to count users")
+		span.RecordError(err)
+		ref.metrics.repositoryCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	span.SetStatus(codes.Ok, "users counted successfully")
+	span.SetAttributes(
+		attribute.Int64("users.count", out.Count),
+		attribute.Bool("users.count.estimate", out.Estimate),
+	)
+	ref.metrics.repositoryCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	return out, nil
+}
+
+// countOrEstimate runs the count query within the count timeout, and once it
+// expires, estimates the number of rows of the users table instead.
+func (ref *UsersRepository) countOrEstimate(ctx context.Context, db querier, query string, args []any) (*CountUsersOutput, error) {
+	countCtx, cancel := ctx, context.CancelFunc(func() {})
+	if ref.countTimeout > 0 {
+		countCtx, cancel = context.WithTimeout(ctx, ref.countTimeout)
+	}
+	defer cancel()
+
+	var out CountUsersOutput
+	err := db.QueryRowContext(countCtx, query, args...).Scan(&out.Count)
+	if err == nil {
+		return &out, nil
+	}
+
+	// only the count timeout falls back to the estimate, not the query timeout
+	if ref.countTimeout == 0 || ctx.Err() != nil || countCtx.Err() == nil {
+		return nil, err
+	}
+
+	slog.Warn("repository.Users.Count", "message", "count timed out, estimating it", "timeout", ref.countTimeout)
+
+	// the connection of the canceled count could be closed, so the pool runs the estimate
+	out.Estimate = true
+	// reltuples is -1 for the tables never vacuumed nor analyzed
+	estimateQuery := "SELECT GREATEST(reltuples, 0)::bigint FROM pg_class WHERE oid = 'users'::regclass"
+	if err := ref.db.QueryRowContext(ctx, estimateQuery).Scan(&out.Count); err != nil {
+		return nil, err
+	}
+
+	return &out, nil
+}
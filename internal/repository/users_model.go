@@ -8,30 +8,21 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/model"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/query"
 )
 
-const (
-	UserFirstNameMinLength = 2
-	UserFirstNameMaxLength = 25
-	UserLastNameMinLength  = 2
-	UserLastNameMaxLength  = 25
-	UserEmailMinLength     = 6
-	UserEmailMaxLength     = 50
-	UserPasswordMinLength  = 6
-	UserPasswordMaxLength  = 255
-)
-
 var (
 	ErrUserInvalidID          = errors.New("invalid user ID. Must be a valid UUID")
-	ErrUserInvalidFirstName   = errors.New("invalid first name. Must be between " + fmt.Sprintf("%d and %d", UserFirstNameMinLength, UserFirstNameMaxLength) + " characters long")
-	ErrUserInvalidLastName    = errors.New("invalid last name. Must be between " + fmt.Sprintf("%d and %d", UserLastNameMinLength, UserLastNameMaxLength) + " characters long")
-	ErrUserInvalidEmail       = errors.New("invalid email. Must be between " + fmt.Sprintf("%d and %d", UserEmailMinLength, UserEmailMaxLength) + " characters long")
-	ErrUserInvalidPassword    = errors.New("invalid password. Must be between " + fmt.Sprintf("%d and %d", UserPasswordMinLength, UserPasswordMaxLength) + " characters long")
+	ErrUserInvalidFirstName   = errors.New("invalid first name. Must be between " + fmt.Sprintf("%d and %d", model.UserFirstNameMinLength, model.UserFirstNameMaxLength) + " characters long")
+	ErrUserInvalidLastName    = errors.New("invalid last name. Must be between " + fmt.Sprintf("%d and %d", model.UserLastNameMinLength, model.UserLastNameMaxLength) + " characters long")
+	ErrUserInvalidEmail       = errors.New("invalid email. Must be between " + fmt.Sprintf("%d and %d", model.UserEmailMinLength, model.UserEmailMaxLength) + " characters long")
+	ErrUserInvalidPassword    = errors.New("invalid password. Must be between " + fmt.Sprintf("%d and %d", model.UserPasswordMinLength, model.UserPasswordMaxLength) + " characters long")
 	ErrUserNotFound           = errors.New("user not found")
 	ErrUserIDAlreadyExists    = errors.New("user ID already exists")
 	ErrUserEmailAlreadyExists = errors.New("user email already exists")
+	ErrUserInvalidIDs         = errors.New("invalid user IDs. Must be between 1 and " + fmt.Sprintf("%d", model.UserBulkDeleteMaxItems) + " valid UUIDs")
 )
 
 var (
@@ -71,15 +62,15 @@ func (ref *InsertUserInput) Validate() error {
 		return ErrUserInvalidID
 	}
 
-	if len(ref.FirstName) < UserFirstNameMinLength || len(ref.FirstName) > UserFirstNameMaxLength {
+	if len(ref.FirstName) < model.UserFirstNameMinLength || len(ref.FirstName) > model.UserFirstNameMaxLength {
 		return ErrUserInvalidFirstName
 	}
 
-	if len(ref.LastName) < UserLastNameMinLength || len(ref.LastName) > UserLastNameMaxLength {
+	if len(ref.LastName) < model.UserLastNameMinLength || len(ref.LastName) > model.UserLastNameMaxLength {
 		return ErrUserInvalidLastName
 	}
 
-	if len(ref.Email) < UserEmailMinLength || len(ref.Email) > UserEmailMaxLength {
+	if len(ref.Email) < model.UserEmailMinLength || len(ref.Email) > model.UserEmailMaxLength {
 		return ErrUserInvalidEmail
 	}
 
@@ -88,7 +79,45 @@ func (ref *InsertUserInput) Validate() error {
 		return ErrUserInvalidEmail
 	}
 
-	if len(ref.PasswordHash) < UserPasswordMinLength || len(ref.PasswordHash) > UserPasswordMaxLength {
+	if len(ref.PasswordHash) < model.UserPasswordMinLength || len(ref.PasswordHash) > model.UserPasswordMaxLength {
+		return ErrUserInvalidPassword
+	}
+
+	return nil
+}
+
+type UpsertUserInput struct {
+	ID           uuid.UUID
+	FirstName    string
+	LastName     string
+	Email        string
+	PasswordHash string
+	Disabled     bool
+}
+
+func (ref *UpsertUserInput) Validate() error {
+	if ref.ID == uuid.Nil {
+		return ErrUserInvalidID
+	}
+
+	if len(ref.FirstName) < model.UserFirstNameMinLength || len(ref.FirstName) > model.UserFirstNameMaxLength {
+		return ErrUserInvalidFirstName
+	}
+
+	if len(ref.LastName) < model.UserLastNameMinLength || len(ref.LastName) > model.UserLastNameMaxLength {
+		return ErrUserInvalidLastName
+	}
+
+	if len(ref.Email) < model.UserEmailMinLength || len(ref.Email) > model.UserEmailMaxLength {
+		return ErrUserInvalidEmail
+	}
+
+	_, err := mail.ParseAddress(ref.Email)
+	if err != nil {
+		return ErrUserInvalidEmail
+	}
+
+	if len(ref.PasswordHash) < model.UserPasswordMinLength || len(ref.PasswordHash) > model.UserPasswordMaxLength {
 		return ErrUserInvalidPassword
 	}
 
@@ -115,40 +144,32 @@ func (ref *UpdateUserInput) Validate() error {
 	}
 
 	if ref.FirstName != nil {
-		if len(*ref.FirstName) < UserFirstNameMinLength || len(*ref.FirstName) > UserFirstNameMaxLength {
+		if len(*ref.FirstName) < model.UserFirstNameMinLength || len(*ref.FirstName) > model.UserFirstNameMaxLength {
 			return ErrUserInvalidFirstName
 		}
 	}
 
 	if ref.LastName != nil {
-		if len(*ref.LastName) < UserLastNameMinLength || len(*ref.LastName) > UserLastNameMaxLength {
+		if len(*ref.LastName) < model.UserLastNameMinLength || len(*ref.LastName) > model.UserLastNameMaxLength {
 			return ErrUserInvalidLastName
 		}
 	}
 
 	if ref.Email != nil {
-		if len(*ref.Email) < UserEmailMinLength || len(*ref.Email) > UserEmailMaxLength {
+		if len(*ref.Email) < model.UserEmailMinLength || len(*ref.Email) > model.UserEmailMaxLength {
 			return ErrUserInvalidEmail
 		}
 	}
 
 	if ref.Email != nil {
-		if len(*ref.Email) < UserEmailMinLength || len(*ref.Email) > UserEmailMaxLength {
+		_, err := mail.ParseAddress(*ref.Email)
+		if err != nil {
 			return ErrUserInvalidEmail
 		}
 	}
 
 	if ref.PasswordHash != nil {
-		if len(*ref.PasswordHash) >= UserPasswordMinLength || len(*ref.PasswordHash) <= UserPasswordMaxLength {
-			_, err := mail.ParseAddress(*ref.Email)
-			if err != nil {
-				return ErrUserInvalidEmail
-			}
-		}
-	}
-
-	if ref.PasswordHash != nil {
-		if len(*ref.PasswordHash) < UserPasswordMinLength || len(*ref.PasswordHash) > UserPasswordMaxLength {
+		if len(*ref.PasswordHash) < model.UserPasswordMinLength || len(*ref.PasswordHash) > model.UserPasswordMaxLength {
 			return ErrUserInvalidPassword
 		}
 	}
@@ -168,6 +189,31 @@ func (ref *DeleteUserInput) Validate() error {
 	return nil
 }
 
+type DeleteUsersByIDsInput struct {
+	IDs    []uuid.UUID
+	DryRun bool
+}
+
+func (ref *DeleteUsersByIDsInput) Validate() error {
+	if len(ref.IDs) == 0 || len(ref.IDs) > model.UserBulkDeleteMaxItems {
+		return ErrUserInvalidIDs
+	}
+
+	for _, id := range ref.IDs {
+		if id == uuid.Nil {
+			return ErrUserInvalidIDs
+		}
+	}
+
+	return nil
+}
+
+type DeleteUsersByIDsOutput struct {
+	// Deleted contains the IDs that existed and were deleted,
+	// or that would be deleted when DryRun is set.
+	Deleted []uuid.UUID
+}
+
 type SelectUsersInput struct {
 	Sort      string
 	Filter    string
@@ -197,6 +243,26 @@ func (ref *SelectUsersInput) Validate() error {
 	return nil
 }
 
+type CountUsersInput struct {
+	Filter string
+}
+
+func (ref *CountUsersInput) Validate() error {
+	if ref.Filter != "" && !query.IsValidFilter(UserFilterFields, ref.Filter) {
+		return ErrInvalidFilter
+	}
+
+	return nil
+}
+
+// CountUsersOutput is the number of users matching the filter.
+// When Estimate is true, the exact count took too long and Count is the
+// number of rows of the table estimated from the database statistics.
+type CountUsersOutput struct {
+	Count    int64
+	Estimate bool
+}
+
 type SelectUsersOutput struct {
 	Items     []*User
 	Paginator paginator.Paginator
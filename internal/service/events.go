@@ -0,0 +1,89 @@
+package service
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types published on the changes of the resources.
+const (
+	EventUserCreated = "user.created"
+	EventUserUpdated = "user.updated"
+	EventUserDeleted = "user.deleted"
+)
+
+// DefaultEventBufferSize is the default number of events buffered for each subscriber.
+// Events for a subscriber with a full buffer are dropped.
+const DefaultEventBufferSize = 64
+
+// Event represents a change of a resource.
+type Event struct {
+	Type       string    `json:"type"`
+	ResourceID uuid.UUID `json:"resource_id"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// EventBroker fans out the published events to all its subscribers.
+// It is safe for concurrent use.
+type EventBroker struct {
+	mu          sync.RWMutex
+	subscribers map[chan Event]struct{}
+	bufferSize  int
+}
+
+// NewEventBroker creates a new EventBroker buffering bufferSize events for each subscriber.
+// A bufferSize less than 1 uses DefaultEventBufferSize.
+func NewEventBroker(bufferSize int) *EventBroker {
+	if bufferSize < 1 {
+		bufferSize = DefaultEventBufferSize
+	}
+
+	return &EventBroker{
+		subscribers: make(map[chan Event]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// and a function to cancel the subscription and close the channel.
+func (ref *EventBroker) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, ref.bufferSize)
+
+	ref.mu.Lock()
+	ref.subscribers[ch] = struct{}{}
+	ref.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			ref.mu.Lock()
+			delete(ref.subscribers, ch)
+			ref.mu.Unlock()
+			close(ch)
+		})
+	}
+
+	return ch, cancel
+}
+
+// Publish sends the event to every subscriber without blocking,
+// the event is dropped for the subscribers not keeping up.
+func (ref *EventBroker) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	ref.mu.RLock()
+	defer ref.mu.RUnlock()
+
+	for ch := range ref.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Warn("service.EventBroker.Publish", "message", "subscriber is not keeping up, event dropped", "event.type", event.Type)
+		}
+	}
+}
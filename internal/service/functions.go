@@ -1,6 +1,10 @@
 package service
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -14,6 +18,34 @@ func hashAndSaltPassword(password string) (string, error) {
 	return string(hash), nil
 }
 
+// randomPassword returns a random password nobody knows,
+// used to lock out anonymized users.
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// uniqueIDs returns the IDs without the repeated ones, in the order of their first occurrence.
+func uniqueIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
+
 // comparePasswords compares the hashed password and the plain password.
 func comparePasswords(hashedPwd string, plainPwd string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hashedPwd), []byte(plainPwd))
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultReadinessTimeout is the default time allowed to each dependency probe.
+const DefaultReadinessTimeout = 2 * time.Second
+
+// Dependency is a downstream dependency of the service probed by the readiness checks.
+// The service is not ready while a Required dependency is down.
+type Dependency struct {
+	Name     string
+	Required bool
+	Probe    func(ctx context.Context) error
+}
+
+// DependencyStatus is the result of the probe of a dependency.
+type DependencyStatus struct {
+	Status   Status `json:"status"`
+	Required bool   `json:"required"`
+}
+
+// Readiness is the readiness of the service and the status of each dependency, by name.
+type Readiness struct {
+	Status       Status                      `json:"status"`
+	Dependencies map[string]DependencyStatus `json:"dependencies"`
+}
+
+// ReadinessChecker probes the dependencies of the service concurrently.
+type ReadinessChecker struct {
+	dependencies []Dependency
+	timeout      time.Duration
+}
+
+// NewReadinessChecker creates a new ReadinessChecker probing the dependencies with the timeout.
+// A timeout less than or equal to zero uses DefaultReadinessTimeout.
+func NewReadinessChecker(timeout time.Duration, dependencies ...Dependency) *ReadinessChecker {
+	if timeout <= 0 {
+		timeout = DefaultReadinessTimeout
+	}
+
+	return &ReadinessChecker{
+		dependencies: dependencies,
+		timeout:      timeout,
+	}
+}
+
+// Check probes every dependency and returns the readiness of the service,
+// which is up when all the required dependencies are up.
+func (ref *ReadinessChecker) Check(ctx context.Context) Readiness {
+	statuses := make([]DependencyStatus, len(ref.dependencies))
+
+	var wg sync.WaitGroup
+	for i, dependency := range ref.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = ref.probe(ctx, dependency)
+		}()
+	}
+	wg.Wait()
+
+	readiness := Readiness{
+		Status:       StatusUp,
+		Dependencies: make(map[string]DependencyStatus, len(ref.dependencies)),
+	}
+
+	for i, dependency := range ref.dependencies {
+		readiness.Dependencies[dependency.Name] = statuses[i]
+
+		if statuses[i].Required && statuses[i].Status == StatusDown {
+			readiness.Status = StatusDown
+		}
+	}
+
+	return readiness
+}
+
+// probe probes the dependency within the timeout.
+// The error is only logged, it could reveal details of the infrastructure.
+func (ref *ReadinessChecker) probe(ctx context.Context, dependency Dependency) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, ref.timeout)
+	defer cancel()
+
+	status := DependencyStatus{
+		Status:   StatusUp,
+		Required: dependency.Required,
+	}
+
+	if err := dependency.Probe(ctx); err != nil {
+		slog.Warn("service.ReadinessChecker.Check", "dependency", dependency.Name, "required", dependency.Required, "error", err)
+		status.Status = StatusDown
+	}
+
+	return status
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/model"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/repository"
 	"go.opentelemetry.io/otel/attribute"
@@ -28,16 +29,23 @@ type UsersRepository interface {
 	Conn(ctx context.Context) (*sql.Conn, error)
 	Insert(ctx context.Context, input *repository.InsertUserInput) error
 	Update(ctx context.Context, input *repository.UpdateUserInput) error
+	Upsert(ctx context.Context, input *repository.UpsertUserInput) (bool, error)
 	Delete(ctx context.Context, input *repository.DeleteUserInput) error
+	DeleteByIDs(ctx context.Context, input *repository.DeleteUsersByIDsInput) (*repository.DeleteUsersByIDsOutput, error)
 	SelectByID(ctx context.Context, id uuid.UUID) (*repository.User, error)
 	SelectByEmail(ctx context.Context, email string) (*repository.User, error)
 	Select(ctx context.Context, input *repository.SelectUsersInput) (*repository.SelectUsersOutput, error)
+	Count(ctx context.Context, input *repository.CountUsersInput) (*repository.CountUsersOutput, error)
 }
 
 type UsersServiceConf struct {
 	Repository    UsersRepository
 	OT            *o11y.OpenTelemetry
 	MetricsPrefix string
+
+	// Events receives the user.created, user.updated and user.deleted events.
+	// If nil, no events are published.
+	Events *EventBroker
 }
 
 type usersServiceMetrics struct {
@@ -49,6 +57,7 @@ type UsersService struct {
 	ot            *o11y.OpenTelemetry
 	metricsPrefix string
 	metrics       usersServiceMetrics
+	events        *EventBroker
 }
 
 // NewUsersService creates a new UsersService.
@@ -64,6 +73,7 @@ func NewUsersService(conf UsersServiceConf) (*UsersService, error) {
 	u := &UsersService{
 		repository: conf.Repository,
 		ot:         conf.OT,
+		events:     conf.Events,
 	}
 	if conf.MetricsPrefix != "" {
 		u.metricsPrefix = strings.ReplaceAll(conf.MetricsPrefix, "-", "_")
@@ -83,6 +93,15 @@ func NewUsersService(conf UsersServiceConf) (*UsersService, error) {
 	return u, nil
 }
 
+// publish publishes the event of the user with the given ID, if there is an event broker.
+func (ref *UsersService) publish(eventType string, id uuid.UUID) {
+	if ref.events == nil {
+		return
+	}
+
+	ref.events.Publish(Event{Type: eventType, ResourceID: id})
+}
+
 // HealthCheck verifies a connection to the repository is still alive.
 func (ref *UsersService) HealthCheck(ctx context.Context) (Health, error) {
 	// database
@@ -212,6 +231,7 @@ func (ref *UsersService) GetByEmail(ctx context.Context, email string) (*User, e
 		attribute.String("component", "service.Users.GetByEmail"),
 	}
 
+	email = model.NormalizeEmail(email)
 	if email == "" {
 		slog.Error("service.Users.GetByEmail", "error", ErrUserInvalidEmail)
 		span.SetStatus(codes.Error, ErrUserInvalidEmail.Error())
@@ -299,6 +319,8 @@ func (ref *UsersService) Create(ctx context.Context, input *CreateUserInput) err
 		input.ID = uuid.New()
 	}
 
+	input.Email = model.NormalizeEmail(input.Email)
+
 	// validate the user input
 	if err := input.Validate(); err != nil {
 		span.SetStatus(codes.Error, err.Error())
@@ -366,6 +388,9 @@ func (ref *UsersService) Create(ctx context.Context, input *CreateUserInput) err
 			append(metricCommonAttributes, attribute.String("successful", "true"))...,
 		),
 	)
+
+	ref.publish(EventUserCreated, input.ID)
+
 	return nil
 }
 
@@ -398,6 +423,11 @@ func (ref *UsersService) Update(ctx context.Context, input *UpdateUserInput) err
 		attribute.String("user.id", input.ID.String()),
 	)
 
+	if input.Email != nil {
+		email := model.NormalizeEmail(*input.Email)
+		input.Email = &email
+	}
+
 	if err := input.Validate(); err != nil {
 		span.SetStatus(codes.Error, err.Error())
 		span.RecordError(err)
@@ -420,8 +450,7 @@ func (ref *UsersService) Update(ctx context.Context, input *UpdateUserInput) err
 	}
 
 	// update the password if it is provided
-	if input.Password != nil && len(*input.Password) < UserPasswordMinLength {
-
+	if input.Password != nil {
 		hashPwd, err := hashAndSaltPassword(*input.Password)
 		if err != nil {
 			slog.Error("handler.Users.createUser", "error", err.Error())
@@ -470,6 +499,199 @@ func (ref *UsersService) Update(ctx context.Context, input *UpdateUserInput) err
 		),
 	)
 
+	ref.publish(EventUserUpdated, input.ID)
+
+	return nil
+}
+
+// Upsert creates the user with the specified ID, or replaces its data when it already exists.
+// It reports whether the user was created.
+func (ref *UsersService) Upsert(ctx context.Context, input *UpsertUserInput) (bool, error) {
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "service.Users.Upsert")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "service.Users.Upsert"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "service.Users.Upsert"),
+	}
+
+	if input == nil {
+		span.SetStatus(codes.Error, ErrInputIsNil.Error())
+		span.RecordError(ErrInputIsNil)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return false, ErrInputIsNil
+	}
+
+	span.SetAttributes(
+		attribute.String("user.id", input.ID.String()),
+	)
+
+	input.Email = model.NormalizeEmail(input.Email)
+
+	if err := input.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.Upsert", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return false, err
+	}
+
+	hashPwd, err := hashAndSaltPassword(input.Password)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.Upsert", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return false, err
+	}
+
+	rParams := &repository.UpsertUserInput{
+		ID:           input.ID,
+		FirstName:    input.FirstName,
+		LastName:     input.LastName,
+		Email:        input.Email,
+		Disabled:     input.Disabled,
+		PasswordHash: hashPwd,
+	}
+
+	created, err := ref.repository.Upsert(ctx, rParams)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.Upsert", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		if errors.Is(err, repository.ErrUserEmailAlreadyExists) {
+			return false, ErrUserEmailAlreadyExists
+		}
+
+		return false, err
+	}
+
+	slog.Debug("service.Users.Upsert", "user.id", input.ID, "created", created)
+	span.SetStatus(codes.Ok, "User upserted")
+	span.SetAttributes(attribute.Bool("user.created", created))
+	ref.metrics.serviceCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	if created {
+		ref.publish(EventUserCreated, input.ID)
+	} else {
+		ref.publish(EventUserUpdated, input.ID)
+	}
+
+	return created, nil
+}
+
+// Anonymize irreversibly replaces the personal data of the user with the specified ID
+// with tombstone values and locks the account, keeping the user row in place.
+func (ref *UsersService) Anonymize(ctx context.Context, id uuid.UUID) error {
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "service.Users.Anonymize")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "service.Users.Anonymize"),
+		attribute.String("user.id", id.String()),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "service.Users.Anonymize"),
+	}
+
+	if id == uuid.Nil {
+		span.SetStatus(codes.Error, ErrUserInvalidID.Error())
+		span.RecordError(ErrUserInvalidID)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return ErrUserInvalidID
+	}
+
+	pwd, err := randomPassword()
+	if err == nil {
+		pwd, err = hashAndSaltPassword(pwd)
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.Anonymize", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return err
+	}
+
+	firstName := UserAnonymizedFirstName
+	lastName := UserAnonymizedLastName
+	email := fmt.Sprintf("%s@%s", strings.ReplaceAll(id.String(), "-", ""), UserAnonymizedEmailDomain)
+	disabled := true
+
+	rParams := &repository.UpdateUserInput{
+		ID:           id,
+		FirstName:    &firstName,
+		LastName:     &lastName,
+		Email:        &email,
+		PasswordHash: &pwd,
+		Disabled:     &disabled,
+	}
+
+	if err := ref.repository.Update(ctx, rParams); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.Anonymize", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return ErrUserNotFound
+		}
+
+		return err
+	}
+
+	span.SetStatus(codes.Ok, "User anonymized")
+	ref.metrics.serviceCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	ref.publish(EventUserUpdated, id)
+
 	return nil
 }
 
@@ -530,9 +752,116 @@ func (ref *UsersService) Delete(ctx context.Context, input *DeleteUserInput) err
 		),
 	)
 
+	ref.publish(EventUserDeleted, input.ID)
+
 	return nil
 }
 
+// BulkDelete deletes the users with the given IDs in a single transaction
+// and reports, for every requested ID, whether it was deleted.
+// A repeated ID is deleted and reported once.
+func (ref *UsersService) BulkDelete(ctx context.Context, input *BulkDeleteUsersInput) (*BulkDeleteUsersOutput, error) {
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "service.Users.BulkDelete")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "service.Users.BulkDelete"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "service.Users.BulkDelete"),
+	}
+
+	if input == nil {
+		span.SetStatus(codes.Error, ErrInputIsNil.Error())
+		span.RecordError(ErrInputIsNil)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, ErrInputIsNil
+	}
+
+	span.SetAttributes(
+		attribute.Int("users.count", len(input.IDs)),
+		attribute.Bool("dry_run", input.DryRun),
+	)
+
+	if err := input.Validate(); err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.BulkDelete", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	ids := uniqueIDs(input.IDs)
+
+	rParams := &repository.DeleteUsersByIDsInput{
+		IDs:    ids,
+		DryRun: input.DryRun,
+	}
+
+	slog.Debug("service.Users.BulkDelete", "qParams", rParams)
+
+	out, err := ref.repository.DeleteByIDs(ctx, rParams)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.BulkDelete", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		if errors.Is(err, repository.ErrUserInvalidIDs) {
+			return nil, ErrUserInvalidIDs
+		}
+
+		return nil, err
+	}
+
+	deleted := make(map[uuid.UUID]bool, len(out.Deleted))
+	for _, id := range out.Deleted {
+		deleted[id] = true
+	}
+
+	items := make([]BulkDeleteUserResult, len(ids))
+	for i, id := range ids {
+		items[i] = BulkDeleteUserResult{
+			ID:      id,
+			Deleted: deleted[id],
+		}
+	}
+
+	span.SetStatus(codes.Ok, "Users deleted")
+	span.SetAttributes(attribute.Int("users.deleted", len(out.Deleted)))
+	ref.metrics.serviceCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	if !input.DryRun {
+		for _, id := range out.Deleted {
+			ref.publish(EventUserDeleted, id)
+		}
+	}
+
+	return &BulkDeleteUsersOutput{
+		DryRun: input.DryRun,
+		Items:  items,
+	}, nil
+}
+
 // List returns a list of users.
 func (ref *UsersService) List(ctx context.Context, input *ListUsersInput) (*ListUsersOutput, error) {
 	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "service.Users.List")
@@ -599,3 +928,58 @@ func (ref *UsersService) List(ctx context.Context, input *ListUsersInput) (*List
 		Paginator: repOut.Paginator,
 	}, nil
 }
+
+// Count returns the number of users matching the filter.
+func (ref *UsersService) Count(ctx context.Context, input *CountUsersInput) (*CountUsersOutput, error) {
+	ctx, span := ref.ot.Traces.Tracer.Start(ctx, "service.Users.Count")
+	defer span.End()
+
+	span.SetAttributes(
+		attribute.String("component", "service.Users.Count"),
+	)
+
+	metricCommonAttributes := []attribute.KeyValue{
+		attribute.String("component", "service.Users.Count"),
+	}
+
+	if input == nil {
+		span.SetStatus(codes.Error, ErrInputIsNil.Error())
+		span.RecordError(ErrInputIsNil)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, ErrInputIsNil
+	}
+
+	span.SetAttributes(attribute.String("filter", input.Filter))
+
+	repOut, err := ref.repository.Count(ctx, &repository.CountUsersInput{Filter: input.Filter})
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		span.RecordError(err)
+		slog.Error("service.Users.Count", "error", err)
+		ref.metrics.serviceCalls.Add(ctx, 1,
+			metric.WithAttributes(
+				append(metricCommonAttributes, attribute.String("successful", "false"))...,
+			),
+		)
+
+		return nil, err
+	}
+
+	slog.Debug("service.Users.Count", "users.count", repOut.Count, "users.count.estimate", repOut.Estimate)
+	span.SetStatus(codes.Ok, "Users counted")
+	ref.metrics.serviceCalls.Add(ctx, 1,
+		metric.WithAttributes(
+			append(metricCommonAttributes, attribute.String("successful", "true"))...,
+		),
+	)
+
+	return &CountUsersOutput{
+		Count:    repOut.Count,
+		Estimate: repOut.Estimate,
+	}, nil
+}
@@ -8,29 +8,27 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/model"
 	"github.com/p2p-b2b/go-rest-api-service-template/internal/paginator"
 )
 
-const (
-	UserFirstNameMinLength = 2
-	UserFirstNameMaxLength = 25
-	UserLastNameMinLength  = 2
-	UserLastNameMaxLength  = 25
-	UserEmailMinLength     = 6
-	UserEmailMaxLength     = 50
-	UserPasswordMinLength  = 6
-	UserPasswordMaxLength  = 255
-)
-
 var (
 	ErrUserInvalidID          = errors.New("invalid user ID. Must be a valid UUID")
-	ErrUserInvalidFirstName   = errors.New("invalid first name. Must be between " + fmt.Sprintf("%d and %d", UserFirstNameMinLength, UserFirstNameMaxLength) + " characters long")
-	ErrUserInvalidLastName    = errors.New("invalid last name. Must be between " + fmt.Sprintf("%d and %d", UserLastNameMinLength, UserLastNameMaxLength) + " characters long")
-	ErrUserInvalidEmail       = errors.New("invalid email. Must be between " + fmt.Sprintf("%d and %d", UserEmailMinLength, UserEmailMaxLength) + " characters long")
-	ErrUserInvalidPassword    = errors.New("invalid password. Must be between " + fmt.Sprintf("%d and %d", UserPasswordMinLength, UserPasswordMaxLength) + " characters long")
+	ErrUserInvalidFirstName   = errors.New("invalid first name. Must be between " + fmt.Sprintf("%d and %d", model.UserFirstNameMinLength, model.UserFirstNameMaxLength) + " characters long")
+	ErrUserInvalidLastName    = errors.New("invalid last name. Must be between " + fmt.Sprintf("%d and %d", model.UserLastNameMinLength, model.UserLastNameMaxLength) + " characters long")
+	ErrUserInvalidEmail       = errors.New("invalid email. Must be between " + fmt.Sprintf("%d and %d", model.UserEmailMinLength, model.UserEmailMaxLength) + " characters long")
+	ErrUserInvalidPassword    = errors.New("invalid password. Must be between " + fmt.Sprintf("%d and %d", model.UserPasswordMinLength, model.UserPasswordMaxLength) + " characters long")
 	ErrUserNotFound           = errors.New("user not found")
 	ErrUserIDAlreadyExists    = errors.New("user ID already exists")
 	ErrUserEmailAlreadyExists = errors.New("user email already exists")
+	ErrUserInvalidIDs         = errors.New("invalid user IDs. Must be between 1 and " + fmt.Sprintf("%d", model.UserBulkDeleteMaxItems) + " valid UUIDs")
+)
+
+// Tombstone values written over the personal data of an anonymized user.
+const (
+	UserAnonymizedFirstName   = "Anonymized"
+	UserAnonymizedLastName    = "User"
+	UserAnonymizedEmailDomain = "erased.invalid"
 )
 
 type User struct {
@@ -58,16 +56,16 @@ func (ref *CreateUserInput) Validate() error {
 		return ErrUserInvalidID
 	}
 
-	if len(ref.FirstName) < UserFirstNameMinLength || len(ref.FirstName) > UserFirstNameMaxLength {
+	if len(ref.FirstName) < model.UserFirstNameMinLength || len(ref.FirstName) > model.UserFirstNameMaxLength {
 		return ErrUserInvalidFirstName
 	}
 
-	if len(ref.LastName) < UserLastNameMinLength || len(ref.LastName) > UserLastNameMaxLength {
+	if len(ref.LastName) < model.UserLastNameMinLength || len(ref.LastName) > model.UserLastNameMaxLength {
 		return ErrUserInvalidLastName
 	}
 
 	// minimal email validation
-	if len(ref.Email) < UserEmailMinLength || len(ref.Email) > UserEmailMaxLength {
+	if len(ref.Email) < model.UserEmailMinLength || len(ref.Email) > model.UserEmailMaxLength {
 		return ErrUserInvalidEmail
 	}
 
@@ -76,7 +74,46 @@ func (ref *CreateUserInput) Validate() error {
 		return ErrUserInvalidEmail
 	}
 
-	if len(ref.Password) < UserPasswordMinLength {
+	if len(ref.Password) < model.UserPasswordMinLength || len(ref.Password) > model.UserPasswordMaxLength {
+		return ErrUserInvalidPassword
+	}
+
+	return nil
+}
+
+type UpsertUserInput struct {
+	ID        uuid.UUID
+	FirstName string
+	LastName  string
+	Email     string
+	Password  string
+	Disabled  bool
+}
+
+func (ref *UpsertUserInput) Validate() error {
+	if ref.ID == uuid.Nil {
+		return ErrUserInvalidID
+	}
+
+	if len(ref.FirstName) < model.UserFirstNameMinLength || len(ref.FirstName) > model.UserFirstNameMaxLength {
+		return ErrUserInvalidFirstName
+	}
+
+	if len(ref.LastName) < model.UserLastNameMinLength || len(ref.LastName) > model.UserLastNameMaxLength {
+		return ErrUserInvalidLastName
+	}
+
+	// minimal email validation
+	if len(ref.Email) < model.UserEmailMinLength || len(ref.Email) > model.UserEmailMaxLength {
+		return ErrUserInvalidEmail
+	}
+
+	_, err := mail.ParseAddress(ref.Email)
+	if err != nil {
+		return ErrUserInvalidEmail
+	}
+
+	if len(ref.Password) < model.UserPasswordMinLength || len(ref.Password) > model.UserPasswordMaxLength {
 		return ErrUserInvalidPassword
 	}
 
@@ -101,25 +138,25 @@ func (ref *UpdateUserInput) Validate() error {
 		return ErrUserInvalidID
 	}
 	if ref.FirstName != nil {
-		if len(*ref.FirstName) < UserFirstNameMinLength || len(*ref.FirstName) > UserFirstNameMaxLength {
+		if len(*ref.FirstName) < model.UserFirstNameMinLength || len(*ref.FirstName) > model.UserFirstNameMaxLength {
 			return ErrUserInvalidFirstName
 		}
 	}
 
 	if ref.LastName != nil {
-		if len(*ref.LastName) < UserLastNameMinLength || len(*ref.LastName) > UserLastNameMaxLength {
+		if len(*ref.LastName) < model.UserLastNameMinLength || len(*ref.LastName) > model.UserLastNameMaxLength {
 			return ErrUserInvalidLastName
 		}
 	}
 
 	if ref.Email != nil {
-		if len(*ref.Email) < UserEmailMinLength || len(*ref.Email) > UserEmailMaxLength {
+		if len(*ref.Email) < model.UserEmailMinLength || len(*ref.Email) > model.UserEmailMaxLength {
 			return ErrUserInvalidEmail
 		}
 	}
 
 	if ref.Email != nil {
-		if len(*ref.Email) >= UserEmailMinLength && len(*ref.Email) <= UserEmailMaxLength {
+		if len(*ref.Email) >= model.UserEmailMinLength && len(*ref.Email) <= model.UserEmailMaxLength {
 			_, err := mail.ParseAddress(*ref.Email)
 			if err != nil {
 				return ErrUserInvalidEmail
@@ -127,8 +164,10 @@ func (ref *UpdateUserInput) Validate() error {
 		}
 	}
 
-	if ref.Password != nil && len(*ref.Password) < UserPasswordMinLength {
-		return ErrUserInvalidPassword
+	if ref.Password != nil {
+		if len(*ref.Password) < model.UserPasswordMinLength || len(*ref.Password) > model.UserPasswordMaxLength {
+			return ErrUserInvalidPassword
+		}
 	}
 
 	return nil
@@ -145,6 +184,35 @@ func (ref *DeleteUserInput) Validate() error {
 	return nil
 }
 
+type BulkDeleteUsersInput struct {
+	IDs    []uuid.UUID
+	DryRun bool
+}
+
+func (ref *BulkDeleteUsersInput) Validate() error {
+	if len(ref.IDs) == 0 || len(ref.IDs) > model.UserBulkDeleteMaxItems {
+		return ErrUserInvalidIDs
+	}
+
+	for _, id := range ref.IDs {
+		if id == uuid.Nil {
+			return ErrUserInvalidIDs
+		}
+	}
+
+	return nil
+}
+
+type BulkDeleteUserResult struct {
+	ID      uuid.UUID
+	Deleted bool
+}
+
+type BulkDeleteUsersOutput struct {
+	DryRun bool
+	Items  []BulkDeleteUserResult
+}
+
 type ListUsersInput struct {
 	Sort      string
 	Filter    string
@@ -152,6 +220,17 @@ type ListUsersInput struct {
 	Paginator paginator.Paginator
 }
 
+type CountUsersInput struct {
+	Filter string
+}
+
+// CountUsersOutput is the number of users matching the filter,
+// estimated from the database statistics when Estimate is true.
+type CountUsersOutput struct {
+	Count    int64
+	Estimate bool
+}
+
 type ListUsersOutput struct {
 	Items     []*User
 	Paginator paginator.Paginator
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/config"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/o11y"
+	"github.com/p2p-b2b/go-rest-api-service-template/internal/repository"
+	mocksRepository "github.com/p2p-b2b/go-rest-api-service-template/mocks/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+func newTestTelemetry(t *testing.T) *o11y.OpenTelemetry {
+	t.Helper()
+
+	otConfig := config.NewOpenTelemetryConfig("test", "1.0.0")
+	otConfig.TraceExporter.Value = "console"
+	otConfig.MetricExporter.Value = "console"
+
+	telemetry, err := o11y.New(context.TODO(), otConfig)
+	if err != nil {
+		t.Fatalf("could not create telemetry: %v", err)
+	}
+
+	if err := telemetry.Start(); err != nil {
+		t.Fatalf("could not start telemetry: %v", err)
+	}
+
+	return telemetry
+}
+
+func TestUsersService_Anonymize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := mocksRepository.NewMockUsersRepository(ctrl)
+
+	svc, err := NewUsersService(UsersServiceConf{
+		Repository: mockRepository,
+		OT:         newTestTelemetry(t),
+	})
+	if err != nil {
+		t.Fatalf("could not create user service: %v", err)
+	}
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+	oldPassword := "ThisIs4Passw0rd"
+
+	t.Run("personal data is replaced and the user is locked out", func(t *testing.T) {
+		var got *repository.UpdateUserInput
+		mockRepository.
+			EXPECT().
+			Update(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, input *repository.UpdateUserInput) error {
+				got = input
+				return input.Validate()
+			}).
+			Times(1)
+
+		if err := svc.Anonymize(context.TODO(), id); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if got.ID != id {
+			t.Errorf("expected id %s, got %s", id, got.ID)
+		}
+
+		if *got.FirstName != UserAnonymizedFirstName || *got.LastName != UserAnonymizedLastName {
+			t.Errorf("expected tombstone names, got %q %q", *got.FirstName, *got.LastName)
+		}
+
+		if *got.Email == "" || !strings.HasSuffix(*got.Email, "@"+UserAnonymizedEmailDomain) {
+			t.Errorf("expected anonymized email, got %q", *got.Email)
+		}
+
+		if got.Disabled == nil || !*got.Disabled {
+			t.Error("expected the user to be disabled")
+		}
+
+		if got.PasswordHash == nil || comparePasswords(*got.PasswordHash, oldPassword) {
+			t.Error("expected the password to be replaced")
+		}
+	})
+
+	t.Run("user not found", func(t *testing.T) {
+		mockRepository.
+			EXPECT().
+			Update(gomock.Any(), gomock.Any()).
+			Return(repository.ErrUserNotFound).
+			Times(1)
+
+		if err := svc.Anonymize(context.TODO(), id); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected error %v, got %v", ErrUserNotFound, err)
+		}
+	})
+
+	t.Run("nil id", func(t *testing.T) {
+		if err := svc.Anonymize(context.TODO(), uuid.Nil); !errors.Is(err, ErrUserInvalidID) {
+			t.Errorf("expected error %v, got %v", ErrUserInvalidID, err)
+		}
+	})
+}
+
+func TestUsersService_GetByEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := mocksRepository.NewMockUsersRepository(ctrl)
+
+	svc, err := NewUsersService(UsersServiceConf{
+		Repository: mockRepository,
+		OT:         newTestTelemetry(t),
+	})
+	if err != nil {
+		t.Fatalf("could not create user service: %v", err)
+	}
+
+	id := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+
+	mockRepository.
+		EXPECT().
+		SelectByEmail(gomock.Any(), "john@doe.com").
+		Return(&repository.User{ID: id, Email: "john@doe.com"}, nil).
+		Times(1)
+
+	mockRepository.
+		EXPECT().
+		SelectByEmail(gomock.Any(), "nobody@doe.com").
+		Return(nil, repository.ErrUserNotFound).
+		Times(1)
+
+	t.Run("the email is normalized before the lookup", func(t *testing.T) {
+		user, err := svc.GetByEmail(context.Background(), "  John@Doe.COM ")
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		if user.ID != id {
+			t.Errorf("expected user %s, got %s", id, user.ID)
+		}
+	})
+
+	t.Run("nonexistent email", func(t *testing.T) {
+		if _, err := svc.GetByEmail(context.Background(), "nobody@doe.com"); !errors.Is(err, ErrUserNotFound) {
+			t.Errorf("expected %v, got %v", ErrUserNotFound, err)
+		}
+	})
+
+	t.Run("empty email", func(t *testing.T) {
+		if _, err := svc.GetByEmail(context.Background(), "   "); !errors.Is(err, ErrUserInvalidEmail) {
+			t.Errorf("expected %v, got %v", ErrUserInvalidEmail, err)
+		}
+	})
+}
+
+func TestUsersService_BulkDelete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepository := mocksRepository.NewMockUsersRepository(ctrl)
+
+	svc, err := NewUsersService(UsersServiceConf{
+		Repository: mockRepository,
+		OT:         newTestTelemetry(t),
+	})
+	if err != nil {
+		t.Fatalf("could not create user service: %v", err)
+	}
+
+	existingID := uuid.Must(uuid.Parse("e1cdf461-87c7-465f-a374-dc6bc7e962b9"))
+	missingID := uuid.Must(uuid.Parse("9a0f2c6e-3b1d-4e8a-8c5f-7d2e1b4a6c90"))
+
+	t.Run("a repeated id is deleted and reported once", func(t *testing.T) {
+		mockRepository.
+			EXPECT().
+			DeleteByIDs(gomock.Any(), &repository.DeleteUsersByIDsInput{IDs: []uuid.UUID{existingID, missingID}}).
+			Return(&repository.DeleteUsersByIDsOutput{Deleted: []uuid.UUID{existingID}}, nil).
+			Times(1)
+
+		out, err := svc.BulkDelete(context.Background(), &BulkDeleteUsersInput{
+			IDs: []uuid.UUID{existingID, missingID, existingID},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		want := []BulkDeleteUserResult{
+			{ID: existingID, Deleted: true},
+			{ID: missingID, Deleted: false},
+		}
+
+		if len(out.Items) != len(want) {
+			t.Fatalf("expected %d items, got %d: %v", len(want), len(out.Items), out.Items)
+		}
+
+		for i := range want {
+			if out.Items[i] != want[i] {
+				t.Errorf("expected item %d to be %v, got %v", i, want[i], out.Items[i])
+			}
+		}
+	})
+}
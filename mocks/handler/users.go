@@ -42,6 +42,50 @@ func (m *MockUsersService) EXPECT() *MockUsersServiceMockRecorder {
 	return m.recorder
 }
 
+// Anonymize mocks base method.
+func (m *MockUsersService) Anonymize(ctx context.Context, id uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Anonymize", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Anonymize indicates an expected call of Anonymize.
+func (mr *MockUsersServiceMockRecorder) Anonymize(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Anonymize", reflect.TypeOf((*MockUsersService)(nil).Anonymize), ctx, id)
+}
+
+// BulkDelete mocks base method.
+func (m *MockUsersService) BulkDelete(ctx context.Context, input *service.BulkDeleteUsersInput) (*service.BulkDeleteUsersOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BulkDelete", ctx, input)
+	ret0, _ := ret[0].(*service.BulkDeleteUsersOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BulkDelete indicates an expected call of BulkDelete.
+func (mr *MockUsersServiceMockRecorder) BulkDelete(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BulkDelete", reflect.TypeOf((*MockUsersService)(nil).BulkDelete), ctx, input)
+}
+
+// Count mocks base method.
+func (m *MockUsersService) Count(ctx context.Context, input *service.CountUsersInput) (*service.CountUsersOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, input)
+	ret0, _ := ret[0].(*service.CountUsersOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockUsersServiceMockRecorder) Count(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockUsersService)(nil).Count), ctx, input)
+}
+
 // Create mocks base method.
 func (m *MockUsersService) Create(ctx context.Context, input *service.CreateUserInput) error {
 	m.ctrl.T.Helper()
@@ -143,3 +187,18 @@ func (mr *MockUsersServiceMockRecorder) Update(ctx, input any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUsersService)(nil).Update), ctx, input)
 }
+
+// Upsert mocks base method.
+func (m *MockUsersService) Upsert(ctx context.Context, input *service.UpsertUserInput) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upsert", ctx, input)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upsert indicates an expected call of Upsert.
+func (mr *MockUsersServiceMockRecorder) Upsert(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockUsersService)(nil).Upsert), ctx, input)
+}
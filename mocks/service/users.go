@@ -72,6 +72,21 @@ func (mr *MockUsersRepositoryMockRecorder) Conn(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Conn", reflect.TypeOf((*MockUsersRepository)(nil).Conn), ctx)
 }
 
+// Count mocks base method.
+func (m *MockUsersRepository) Count(ctx context.Context, input *repository.CountUsersInput) (*repository.CountUsersOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Count", ctx, input)
+	ret0, _ := ret[0].(*repository.CountUsersOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Count indicates an expected call of Count.
+func (mr *MockUsersRepositoryMockRecorder) Count(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Count", reflect.TypeOf((*MockUsersRepository)(nil).Count), ctx, input)
+}
+
 // Delete mocks base method.
 func (m *MockUsersRepository) Delete(ctx context.Context, input *repository.DeleteUserInput) error {
 	m.ctrl.T.Helper()
@@ -86,6 +101,21 @@ func (mr *MockUsersRepositoryMockRecorder) Delete(ctx, input any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockUsersRepository)(nil).Delete), ctx, input)
 }
 
+// DeleteByIDs mocks base method.
+func (m *MockUsersRepository) DeleteByIDs(ctx context.Context, input *repository.DeleteUsersByIDsInput) (*repository.DeleteUsersByIDsOutput, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByIDs", ctx, input)
+	ret0, _ := ret[0].(*repository.DeleteUsersByIDsOutput)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteByIDs indicates an expected call of DeleteByIDs.
+func (mr *MockUsersRepositoryMockRecorder) DeleteByIDs(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByIDs", reflect.TypeOf((*MockUsersRepository)(nil).DeleteByIDs), ctx, input)
+}
+
 // DriverName mocks base method.
 func (m *MockUsersRepository) DriverName() string {
 	m.ctrl.T.Helper()
@@ -186,3 +216,18 @@ func (mr *MockUsersRepositoryMockRecorder) Update(ctx, input any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockUsersRepository)(nil).Update), ctx, input)
 }
+
+// Upsert mocks base method.
+func (m *MockUsersRepository) Upsert(ctx context.Context, input *repository.UpsertUserInput) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upsert", ctx, input)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Upsert indicates an expected call of Upsert.
+func (mr *MockUsersRepositoryMockRecorder) Upsert(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upsert", reflect.TypeOf((*MockUsersRepository)(nil).Upsert), ctx, input)
+}